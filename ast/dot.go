@@ -0,0 +1,75 @@
+package ast
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	dotProgramColor    = "gray90"
+	dotStatementColor  = "lightblue"
+	dotExpressionColor = "lightyellow"
+)
+
+// Dot renders an AST as a Graphviz digraph. Nodes are labelled with their
+// type and operator or value, statements and expressions are filled with
+// different colours and children are laid out in source order.
+func Dot(node Node) string {
+	var out strings.Builder
+
+	out.WriteString("digraph AST {\n")
+	out.WriteString("\tordering=out;\n")
+	out.WriteString("\tnode [shape=box, style=\"rounded,filled\", fontname=\"monospace\"];\n")
+
+	count := 0
+
+	var visit func(node Node) string
+	visit = func(node Node) string {
+		id := fmt.Sprintf("n%d", count)
+		count++
+
+		fmt.Fprintf(&out, "\t%s [label=%q, fillcolor=%q];\n", id, dotLabel(node), dotColor(node))
+
+		for _, child := range node.Children() {
+			fmt.Fprintf(&out, "\t%s -> %s;\n", id, visit(child))
+		}
+
+		return id
+	}
+
+	if node != nil {
+		visit(node)
+	}
+
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+func dotLabel(node Node) string {
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+
+	switch node := node.(type) {
+	case *PrefixOperatorExpression:
+		return kind + "\n" + node.Operator
+	case *InfixOperatorExpression:
+		return kind + "\n" + node.Operator
+	case *PostfixOperatorExpression:
+		return kind + "\n" + node.Operator
+	case *IdentifierLiteral, *BooleanLiteral, *IntegerLiteral, *FloatLiteral, *StringLiteral:
+		return kind + "\n" + node.String()
+	default:
+		return kind
+	}
+}
+
+func dotColor(node Node) string {
+	switch node.(type) {
+	case Statement:
+		return dotStatementColor
+	case Expression:
+		return dotExpressionColor
+	default:
+		return dotProgramColor
+	}
+}
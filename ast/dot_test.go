@@ -0,0 +1,64 @@
+package ast
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDot(t *testing.T) {
+	// let a = -b;
+	program := &Program{Statements: []Statement{
+		&LetStatement{Identifier: ident("a", 1, 5), Expression: &PrefixOperatorExpression{Operator: "-", Right: ident("b", 1, 10)}},
+	}}
+
+	output := Dot(program)
+
+	expected := []string{
+		"digraph AST {",
+		`n0 [label="Program", fillcolor="gray90"];`,
+		`n1 [label="LetStatement", fillcolor="lightblue"];`,
+		`n2 [label="IdentifierLiteral\na", fillcolor="lightyellow"];`,
+		`n3 [label="PrefixOperatorExpression\n-", fillcolor="lightyellow"];`,
+		"n0 -> n1;",
+		"n1 -> n2;",
+		"n1 -> n3;",
+		"n3 -> n4;",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(output, line) {
+			t.Errorf("expected the graph to contain %q, got:\n%s", line, output)
+		}
+	}
+}
+
+func TestVisualizersSupportEveryNode(t *testing.T) {
+	for _, node := range sampleNodes() {
+		if sexpr := SExpr(node); strings.Contains(sexpr, "nil") {
+			t.Errorf("%T - SExpr doesn't support every node, got %s", node, sexpr)
+		}
+
+		if dot := Dot(node); !strings.HasSuffix(dot, "}\n") {
+			t.Errorf("%T - Dot output is incomplete", node)
+		}
+	}
+}
+
+func TestSExpr(t *testing.T) {
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{&InfixOperatorExpression{Operator: "+", Left: ident("a", 1, 1), Right: &InfixOperatorExpression{Operator: "*", Left: ident("b", 1, 5), Right: ident("c", 1, 9)}}, "(+ a (* b c))"},
+		{&PostfixOperatorExpression{Operator: "--", Left: ident("a", 1, 1)}, "(postfix -- a)"},
+		{&FloatLiteral{Value: 2}, "2.0"},
+		{&LetStatement{Identifier: ident("a", 1, 5)}, "(let a nil)"},
+		{&Program{}, "(program)"},
+	}
+
+	for _, test := range tests {
+		if actual := SExpr(test.node); actual != test.expected {
+			t.Errorf("expected %s, got %s", test.expected, actual)
+		}
+	}
+}
@@ -21,6 +21,9 @@ type PrefixOperatorExpression struct {
 
 func (expression *PrefixOperatorExpression) expressionNode()      {}
 func (expression *PrefixOperatorExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *PrefixOperatorExpression) Children() []Node {
+	return collectExpressions(expression.Right)
+}
 func (expression *PrefixOperatorExpression) String() string {
 	if expression == nil {
 		return ""
@@ -51,6 +54,9 @@ type InfixOperatorExpression struct {
 
 func (expression *InfixOperatorExpression) expressionNode()      {}
 func (expression *InfixOperatorExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *InfixOperatorExpression) Children() []Node {
+	return collectExpressions(expression.Left, expression.Right)
+}
 func (expression *InfixOperatorExpression) String() string {
 	if expression == nil {
 		return ""
@@ -85,6 +91,9 @@ type PostfixOperatorExpression struct {
 
 func (expression *PostfixOperatorExpression) expressionNode()      {}
 func (expression *PostfixOperatorExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *PostfixOperatorExpression) Children() []Node {
+	return collectExpressions(expression.Left)
+}
 func (expression *PostfixOperatorExpression) String() string {
 	if expression == nil {
 		return ""
@@ -113,6 +122,19 @@ type IfExpression struct {
 
 func (expression *IfExpression) expressionNode()      {}
 func (expression *IfExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *IfExpression) Children() []Node {
+	children := collectExpressions(expression.Condition)
+
+	if expression.Consequence != nil {
+		children = append(children, expression.Consequence)
+	}
+
+	if expression.Alternative != nil {
+		children = append(children, expression.Alternative)
+	}
+
+	return children
+}
 func (expression *IfExpression) String() string {
 	if expression == nil {
 		return ""
@@ -145,6 +167,15 @@ type WhileExpression struct {
 
 func (expression *WhileExpression) expressionNode()      {}
 func (expression *WhileExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *WhileExpression) Children() []Node {
+	children := collectExpressions(expression.Condition)
+
+	if expression.Body != nil {
+		children = append(children, expression.Body)
+	}
+
+	return children
+}
 func (expression *WhileExpression) String() string {
 	if expression == nil {
 		return ""
@@ -163,3 +194,79 @@ func (expression *WhileExpression) String() string {
 
 	return out.String()
 }
+
+/* --- Call Expression ------------------------------------------------------ */
+
+type CallExpression struct {
+	Token     token.Token // The opening parenthesis
+	Function  Expression
+	Arguments []Expression
+}
+
+func (expression *CallExpression) expressionNode()      {}
+func (expression *CallExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *CallExpression) Children() []Node {
+	return collectExpressions(append([]Expression{expression.Function}, expression.Arguments...)...)
+}
+func (expression *CallExpression) String() string {
+	if expression == nil {
+		return ""
+	}
+
+	var out bytes.Buffer
+
+	if expression.Function != nil {
+		out.WriteString(expression.Function.String())
+	}
+
+	out.WriteString("(")
+
+	for i, argument := range expression.Arguments {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+
+		if argument != nil {
+			out.WriteString(argument.String())
+		}
+	}
+
+	out.WriteString(")")
+
+	return out.String()
+}
+
+/* --- Index Expression ----------------------------------------------------- */
+
+type IndexExpression struct {
+	Token token.Token // The opening bracket
+	Left  Expression
+	Index Expression
+}
+
+func (expression *IndexExpression) expressionNode()      {}
+func (expression *IndexExpression) TokenLiteral() string { return expression.Token.Literal }
+func (expression *IndexExpression) Children() []Node {
+	return collectExpressions(expression.Left, expression.Index)
+}
+func (expression *IndexExpression) String() string {
+	if expression == nil {
+		return ""
+	}
+
+	var out bytes.Buffer
+
+	if expression.Left != nil {
+		out.WriteString(expression.Left.String())
+	}
+
+	out.WriteString("[")
+
+	if expression.Index != nil {
+		out.WriteString(expression.Index.String())
+	}
+
+	out.WriteString("]")
+
+	return out.String()
+}
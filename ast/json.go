@@ -0,0 +1,510 @@
+package ast
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"monkey/token"
+)
+
+// The JSON schema of a node is an object with its "kind", the Go type name
+// of the node, the "span" where it starts in the source, the "token" it was
+// created from and its own fields:
+//
+//	Program                    statements
+//	LetStatement               identifier, expression
+//	ReturnStatement            expression
+//	BlockStatement             statements
+//	ExpressionStatement        expression
+//	IdentifierLiteral          value (string)
+//	BooleanLiteral             value (boolean)
+//	IntegerLiteral             value (decimal string, integers may not fit in a double)
+//	FloatLiteral               value (number)
+//	StringLiteral              value (string)
+//	ArrayLiteral               elements
+//	HashLiteral                keys, values
+//	FunctionLiteral            parameters, body
+//	PrefixOperatorExpression   operator, right
+//	InfixOperatorExpression    operator, left, right
+//	PostfixOperatorExpression  operator, left
+//	IfExpression               condition, consequence, alternative
+//	WhileExpression            condition, body
+//	CallExpression             function, arguments
+//	IndexExpression            left, index
+//
+// Missing sub-nodes and empty lists are left out.
+
+type jsonSpan struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+}
+
+type jsonNode struct {
+	Kind  string     `json:"kind"`
+	Span  *jsonSpan  `json:"span,omitempty"`
+	Token *jsonToken `json:"token,omitempty"`
+
+	Value    interface{} `json:"value,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+
+	Identifier  *jsonNode   `json:"identifier,omitempty"`
+	Parameters  []*jsonNode `json:"parameters,omitempty"`
+	Condition   *jsonNode   `json:"condition,omitempty"`
+	Left        *jsonNode   `json:"left,omitempty"`
+	Right       *jsonNode   `json:"right,omitempty"`
+	Expression  *jsonNode   `json:"expression,omitempty"`
+	Consequence *jsonNode   `json:"consequence,omitempty"`
+	Alternative *jsonNode   `json:"alternative,omitempty"`
+	Body        *jsonNode   `json:"body,omitempty"`
+	Statements  []*jsonNode `json:"statements,omitempty"`
+	Function    *jsonNode   `json:"function,omitempty"`
+	Arguments   []*jsonNode `json:"arguments,omitempty"`
+	Elements    []*jsonNode `json:"elements,omitempty"`
+	Keys        []*jsonNode `json:"keys,omitempty"`
+	Values      []*jsonNode `json:"values,omitempty"`
+	Index       *jsonNode   `json:"index,omitempty"`
+}
+
+// MarshalJSON encodes an AST following the schema described above.
+func MarshalJSON(node Node) ([]byte, error) {
+	encoded, err := toJSONNode(node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(encoded)
+}
+
+// UnmarshalJSON decodes an AST encoded by MarshalJSON.
+func UnmarshalJSON(data []byte) (Node, error) {
+	var decoded jsonNode
+
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+
+	return fromJSONNode(&decoded)
+}
+
+/* --- Encoding ------------------------------------------------------------- */
+
+func newJSONNode(kind string, tok token.Token) *jsonNode {
+	return &jsonNode{
+		Kind:  kind,
+		Span:  &jsonSpan{Line: tok.Line, Column: tok.Column},
+		Token: &jsonToken{Type: tok.Type, Literal: tok.Literal},
+	}
+}
+
+func toJSONNodes(nodes []Node) ([]*jsonNode, error) {
+	encoded := []*jsonNode{}
+
+	for _, node := range nodes {
+		child, err := toJSONNode(node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		encoded = append(encoded, child)
+	}
+
+	return encoded, nil
+}
+
+func toJSONNode(node Node) (*jsonNode, error) {
+	var err error
+	var encoded *jsonNode
+
+	// Sub-nodes are encoded through optional so a nil field stays out of
+	// the output instead of becoming an empty object.
+	optional := func(child Node, isNil bool) *jsonNode {
+		if isNil || err != nil {
+			return nil
+		}
+
+		var result *jsonNode
+		result, err = toJSONNode(child)
+
+		return result
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		encoded = &jsonNode{Kind: "Program"}
+		encoded.Statements, err = toJSONNodes(node.Children())
+
+	case *LetStatement:
+		encoded = newJSONNode("LetStatement", node.Token)
+		encoded.Identifier = optional(node.Identifier, node.Identifier == nil)
+		encoded.Expression = optional(node.Expression, node.Expression == nil)
+
+	case *ReturnStatement:
+		encoded = newJSONNode("ReturnStatement", node.Token)
+		encoded.Expression = optional(node.Expression, node.Expression == nil)
+
+	case *BlockStatement:
+		encoded = newJSONNode("BlockStatement", node.Token)
+		encoded.Statements, err = toJSONNodes(node.Children())
+
+	case *ExpressionStatement:
+		encoded = newJSONNode("ExpressionStatement", node.Token)
+		encoded.Expression = optional(node.Expression, node.Expression == nil)
+
+	case *IdentifierLiteral:
+		encoded = newJSONNode("IdentifierLiteral", node.Token)
+		encoded.Value = node.Value
+
+	case *BooleanLiteral:
+		encoded = newJSONNode("BooleanLiteral", node.Token)
+		encoded.Value = node.Value
+
+	case *IntegerLiteral:
+		encoded = newJSONNode("IntegerLiteral", node.Token)
+		encoded.Value = node.String()
+
+	case *FloatLiteral:
+		encoded = newJSONNode("FloatLiteral", node.Token)
+		encoded.Value = node.Value
+
+	case *StringLiteral:
+		encoded = newJSONNode("StringLiteral", node.Token)
+		encoded.Value = node.Value
+
+	case *ArrayLiteral:
+		encoded = newJSONNode("ArrayLiteral", node.Token)
+
+		for _, element := range node.Elements {
+			encoded.Elements = append(encoded.Elements, optional(element, element == nil))
+		}
+
+	case *HashLiteral:
+		encoded = newJSONNode("HashLiteral", node.Token)
+
+		for i := range node.Keys {
+			encoded.Keys = append(encoded.Keys, optional(node.Keys[i], node.Keys[i] == nil))
+			encoded.Values = append(encoded.Values, optional(node.Values[i], node.Values[i] == nil))
+		}
+
+	case *FunctionLiteral:
+		encoded = newJSONNode("FunctionLiteral", node.Token)
+
+		for _, param := range node.Parameters {
+			encoded.Parameters = append(encoded.Parameters, optional(param, false))
+		}
+
+		encoded.Body = optional(node.Body, node.Body == nil)
+
+	case *PrefixOperatorExpression:
+		encoded = newJSONNode("PrefixOperatorExpression", node.Token)
+		encoded.Operator = node.Operator
+		encoded.Right = optional(node.Right, node.Right == nil)
+
+	case *InfixOperatorExpression:
+		encoded = newJSONNode("InfixOperatorExpression", node.Token)
+		encoded.Operator = node.Operator
+		encoded.Left = optional(node.Left, node.Left == nil)
+		encoded.Right = optional(node.Right, node.Right == nil)
+
+	case *PostfixOperatorExpression:
+		encoded = newJSONNode("PostfixOperatorExpression", node.Token)
+		encoded.Operator = node.Operator
+		encoded.Left = optional(node.Left, node.Left == nil)
+
+	case *IfExpression:
+		encoded = newJSONNode("IfExpression", node.Token)
+		encoded.Condition = optional(node.Condition, node.Condition == nil)
+		encoded.Consequence = optional(node.Consequence, node.Consequence == nil)
+		encoded.Alternative = optional(node.Alternative, node.Alternative == nil)
+
+	case *WhileExpression:
+		encoded = newJSONNode("WhileExpression", node.Token)
+		encoded.Condition = optional(node.Condition, node.Condition == nil)
+		encoded.Body = optional(node.Body, node.Body == nil)
+
+	case *CallExpression:
+		encoded = newJSONNode("CallExpression", node.Token)
+		encoded.Function = optional(node.Function, node.Function == nil)
+
+		for _, argument := range node.Arguments {
+			encoded.Arguments = append(encoded.Arguments, optional(argument, argument == nil))
+		}
+
+	case *IndexExpression:
+		encoded = newJSONNode("IndexExpression", node.Token)
+		encoded.Left = optional(node.Left, node.Left == nil)
+		encoded.Index = optional(node.Index, node.Index == nil)
+
+	default:
+		return nil, fmt.Errorf("ast.MarshalJSON: unexpected node type %T", node)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return encoded, nil
+}
+
+/* --- Decoding ------------------------------------------------------------- */
+
+func (encoded *jsonNode) token() token.Token {
+	tok := token.Token{}
+
+	if encoded.Span != nil {
+		tok.Line = encoded.Span.Line
+		tok.Column = encoded.Span.Column
+	}
+
+	if encoded.Token != nil {
+		tok.Type = encoded.Token.Type
+		tok.Literal = encoded.Token.Literal
+	}
+
+	return tok
+}
+
+// jsonDecoder decodes sub-nodes and remembers the first error, so the
+// decoding of a node reads like a list of its fields.
+type jsonDecoder struct {
+	err error
+}
+
+func (decoder *jsonDecoder) node(encoded *jsonNode) Node {
+	if encoded == nil || decoder.err != nil {
+		return nil
+	}
+
+	node, err := fromJSONNode(encoded)
+	decoder.err = err
+
+	return node
+}
+
+func (decoder *jsonDecoder) expression(encoded *jsonNode) Expression {
+	node := decoder.node(encoded)
+
+	if node == nil {
+		return nil
+	}
+
+	expression, ok := node.(Expression)
+
+	if !ok && decoder.err == nil {
+		decoder.err = fmt.Errorf("ast.UnmarshalJSON: expected an expression, got %s", encoded.Kind)
+	}
+
+	return expression
+}
+
+func (decoder *jsonDecoder) statements(encoded []*jsonNode) []Statement {
+	statements := []Statement{}
+
+	for _, child := range encoded {
+		node := decoder.node(child)
+
+		if node == nil {
+			continue
+		}
+
+		statement, ok := node.(Statement)
+
+		if !ok && decoder.err == nil {
+			decoder.err = fmt.Errorf("ast.UnmarshalJSON: expected a statement, got %s", child.Kind)
+		}
+
+		statements = append(statements, statement)
+	}
+
+	return statements
+}
+
+func (decoder *jsonDecoder) identifier(encoded *jsonNode) *IdentifierLiteral {
+	node := decoder.node(encoded)
+
+	if node == nil {
+		return nil
+	}
+
+	identifier, ok := node.(*IdentifierLiteral)
+
+	if !ok && decoder.err == nil {
+		decoder.err = fmt.Errorf("ast.UnmarshalJSON: expected an IdentifierLiteral, got %s", encoded.Kind)
+	}
+
+	return identifier
+}
+
+func (decoder *jsonDecoder) block(encoded *jsonNode) *BlockStatement {
+	node := decoder.node(encoded)
+
+	if node == nil {
+		return nil
+	}
+
+	block, ok := node.(*BlockStatement)
+
+	if !ok && decoder.err == nil {
+		decoder.err = fmt.Errorf("ast.UnmarshalJSON: expected a BlockStatement, got %s", encoded.Kind)
+	}
+
+	return block
+}
+
+func fromJSONNode(encoded *jsonNode) (Node, error) {
+	decoder := &jsonDecoder{}
+
+	var node Node
+
+	switch encoded.Kind {
+	case "Program":
+		node = &Program{Statements: decoder.statements(encoded.Statements)}
+
+	case "LetStatement":
+		node = &LetStatement{Token: encoded.token(), Identifier: decoder.identifier(encoded.Identifier), Expression: decoder.expression(encoded.Expression)}
+
+	case "ReturnStatement":
+		node = &ReturnStatement{Token: encoded.token(), Expression: decoder.expression(encoded.Expression)}
+
+	case "BlockStatement":
+		node = &BlockStatement{Token: encoded.token(), Statements: decoder.statements(encoded.Statements)}
+
+	case "ExpressionStatement":
+		node = &ExpressionStatement{Token: encoded.token(), Expression: decoder.expression(encoded.Expression)}
+
+	case "IdentifierLiteral":
+		value, ok := encoded.Value.(string)
+
+		if !ok {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: IdentifierLiteral value must be a string")
+		}
+
+		node = &IdentifierLiteral{Token: encoded.token(), Value: value}
+
+	case "BooleanLiteral":
+		value, ok := encoded.Value.(bool)
+
+		if !ok {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: BooleanLiteral value must be a boolean")
+		}
+
+		node = &BooleanLiteral{Token: encoded.token(), Value: value}
+
+	case "IntegerLiteral":
+		text, ok := encoded.Value.(string)
+		value, valid := new(big.Int).SetString(text, 10)
+
+		if !ok || !valid {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: IntegerLiteral value must be a decimal string")
+		}
+
+		literal := &IntegerLiteral{Token: encoded.token()}
+
+		if value.IsInt64() {
+			literal.Value = value.Int64()
+		} else {
+			literal.Big = value
+		}
+
+		node = literal
+
+	case "FloatLiteral":
+		value, ok := encoded.Value.(float64)
+
+		if !ok {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: FloatLiteral value must be a number")
+		}
+
+		node = &FloatLiteral{Token: encoded.token(), Value: value}
+
+	case "StringLiteral":
+		value, ok := encoded.Value.(string)
+
+		if !ok && encoded.Value != nil {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: StringLiteral value must be a string")
+		}
+
+		node = &StringLiteral{Token: encoded.token(), Value: value}
+
+	case "ArrayLiteral":
+		array := &ArrayLiteral{Token: encoded.token(), Elements: []Expression{}}
+
+		for _, element := range encoded.Elements {
+			if expression := decoder.expression(element); expression != nil {
+				array.Elements = append(array.Elements, expression)
+			}
+		}
+
+		node = array
+
+	case "HashLiteral":
+		if len(encoded.Keys) != len(encoded.Values) {
+			return nil, fmt.Errorf("ast.UnmarshalJSON: HashLiteral has %d keys and %d values", len(encoded.Keys), len(encoded.Values))
+		}
+
+		hash := &HashLiteral{Token: encoded.token(), Keys: []Expression{}, Values: []Expression{}}
+
+		for i := range encoded.Keys {
+			hash.Keys = append(hash.Keys, decoder.expression(encoded.Keys[i]))
+			hash.Values = append(hash.Values, decoder.expression(encoded.Values[i]))
+		}
+
+		node = hash
+
+	case "FunctionLiteral":
+		function := &FunctionLiteral{Token: encoded.token(), Parameters: []*IdentifierLiteral{}}
+
+		for _, param := range encoded.Parameters {
+			if identifier := decoder.identifier(param); identifier != nil {
+				function.Parameters = append(function.Parameters, identifier)
+			}
+		}
+
+		function.Body = decoder.block(encoded.Body)
+		node = function
+
+	case "PrefixOperatorExpression":
+		node = &PrefixOperatorExpression{Token: encoded.token(), Operator: encoded.Operator, Right: decoder.expression(encoded.Right)}
+
+	case "InfixOperatorExpression":
+		node = &InfixOperatorExpression{Token: encoded.token(), Operator: encoded.Operator, Left: decoder.expression(encoded.Left), Right: decoder.expression(encoded.Right)}
+
+	case "PostfixOperatorExpression":
+		node = &PostfixOperatorExpression{Token: encoded.token(), Operator: encoded.Operator, Left: decoder.expression(encoded.Left)}
+
+	case "IfExpression":
+		node = &IfExpression{Token: encoded.token(), Condition: decoder.expression(encoded.Condition), Consequence: decoder.block(encoded.Consequence), Alternative: decoder.block(encoded.Alternative)}
+
+	case "WhileExpression":
+		node = &WhileExpression{Token: encoded.token(), Condition: decoder.expression(encoded.Condition), Body: decoder.block(encoded.Body)}
+
+	case "CallExpression":
+		call := &CallExpression{Token: encoded.token(), Function: decoder.expression(encoded.Function), Arguments: []Expression{}}
+
+		for _, argument := range encoded.Arguments {
+			if expression := decoder.expression(argument); expression != nil {
+				call.Arguments = append(call.Arguments, expression)
+			}
+		}
+
+		node = call
+
+	case "IndexExpression":
+		node = &IndexExpression{Token: encoded.token(), Left: decoder.expression(encoded.Left), Index: decoder.expression(encoded.Index)}
+
+	default:
+		return nil, fmt.Errorf("ast.UnmarshalJSON: unknown node kind %q", encoded.Kind)
+	}
+
+	if decoder.err != nil {
+		return nil, decoder.err
+	}
+
+	return node, nil
+}
@@ -0,0 +1,81 @@
+package ast
+
+import (
+	"math/big"
+	"monkey/token"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	nodes := append(sampleNodes(), &IntegerLiteral{Big: new(big.Int).Lsh(big.NewInt(1), 80)})
+
+	for _, node := range nodes {
+		encoded, err := MarshalJSON(node)
+
+		if err != nil {
+			t.Errorf("%T - MarshalJSON failled: %s", node, err)
+			continue
+		}
+
+		decoded, err := UnmarshalJSON(encoded)
+
+		if err != nil {
+			t.Errorf("%T - UnmarshalJSON failled: %s\n%s", node, err, encoded)
+			continue
+		}
+
+		if decoded.String() != node.String() {
+			t.Errorf("%T - expected '%s' got '%s'", node, node.String(), decoded.String())
+		}
+
+		reencoded, _ := MarshalJSON(decoded)
+
+		if string(reencoded) != string(encoded) {
+			t.Errorf("%T - round trip isn't stable:\n%s\n%s", node, encoded, reencoded)
+		}
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	// x + 1;
+	node := &ExpressionStatement{
+		Token: token.Token{Type: token.Identifier, Literal: "x", Line: 1, Column: 1},
+		Expression: &InfixOperatorExpression{
+			Token:    token.Token{Type: token.Plus, Literal: "+", Line: 1, Column: 3},
+			Operator: "+",
+			Left:     ident("x", 1, 1),
+			Right:    &IntegerLiteral{Token: token.Token{Type: token.Integer, Literal: "1", Line: 1, Column: 5}, Value: 1},
+		},
+	}
+
+	expected := `{"kind":"ExpressionStatement","span":{"line":1,"column":1},"token":{"type":"Identifier","literal":"x"},` +
+		`"expression":{"kind":"InfixOperatorExpression","span":{"line":1,"column":3},"token":{"type":"Plus","literal":"+"},"operator":"+",` +
+		`"left":{"kind":"IdentifierLiteral","span":{"line":1,"column":1},"token":{"type":"Identifier","literal":"x"},"value":"x"},` +
+		`"right":{"kind":"IntegerLiteral","span":{"line":1,"column":5},"token":{"type":"Integer","literal":"1"},"value":"1"}}}`
+
+	encoded, err := MarshalJSON(node)
+
+	if err != nil {
+		t.Fatalf("MarshalJSON failled: %s", err)
+	}
+
+	if string(encoded) != expected {
+		t.Errorf("unexpected JSON, expected:\n%s\ngot:\n%s", expected, encoded)
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	inputs := []string{
+		`{"kind":"Nope"}`,
+		`{"kind":"LetStatement","expression":{"kind":"BlockStatement"}}`,
+		`{"kind":"Program","statements":[{"kind":"IdentifierLiteral","value":"x"}]}`,
+		`{"kind":"IntegerLiteral","value":12}`,
+		`not json`,
+	}
+
+	for _, input := range inputs {
+		if _, err := UnmarshalJSON([]byte(input)); err == nil {
+			t.Errorf("expected %s to be rejected", input)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package ast
 import (
 	"bytes"
 	"fmt"
+	"math/big"
 	"monkey/token"
+	"strconv"
 )
 
 /* --- Identifier Literal --------------------------------------------------- */
@@ -15,6 +17,7 @@ type IdentifierLiteral struct {
 
 func (expression *IdentifierLiteral) expressionNode()      {}
 func (expression *IdentifierLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *IdentifierLiteral) Children() []Node     { return nil }
 func (expression *IdentifierLiteral) String() string {
 	if expression == nil {
 		return ""
@@ -32,6 +35,7 @@ type BooleanLiteral struct {
 
 func (expression *BooleanLiteral) expressionNode()      {}
 func (expression *BooleanLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *BooleanLiteral) Children() []Node     { return nil }
 func (expression *BooleanLiteral) String() string {
 	if expression == nil {
 		return ""
@@ -49,18 +53,144 @@ func (expression *BooleanLiteral) String() string {
 type IntegerLiteral struct {
 	Token token.Token
 	Value int64
+
+	// Big is set instead of Value when the literal doesn't fit in an int64.
+	Big *big.Int
 }
 
 func (expression *IntegerLiteral) expressionNode()      {}
 func (expression *IntegerLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *IntegerLiteral) Children() []Node     { return nil }
 func (expression *IntegerLiteral) String() string {
 	if expression == nil {
 		return ""
 	}
 
+	if expression.Big != nil {
+		return expression.Big.String()
+	}
+
 	return fmt.Sprintf("%d", expression.Value)
 }
 
+/* --- Float Literal -------------------------------------------------------- */
+
+type FloatLiteral struct {
+	Token token.Token
+	Value float64
+}
+
+func (expression *FloatLiteral) expressionNode()      {}
+func (expression *FloatLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *FloatLiteral) Children() []Node     { return nil }
+func (expression *FloatLiteral) String() string {
+	if expression == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(expression.Value, 'g', -1, 64)
+}
+
+/* --- String Literal ------------------------------------------------------- */
+
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (expression *StringLiteral) expressionNode()      {}
+func (expression *StringLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *StringLiteral) Children() []Node     { return nil }
+func (expression *StringLiteral) String() string {
+	if expression == nil {
+		return ""
+	}
+
+	return strconv.Quote(expression.Value)
+}
+
+/* --- Array Literal -------------------------------------------------------- */
+
+type ArrayLiteral struct {
+	Token    token.Token // The opening bracket
+	Elements []Expression
+}
+
+func (expression *ArrayLiteral) expressionNode()      {}
+func (expression *ArrayLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *ArrayLiteral) Children() []Node     { return collectExpressions(expression.Elements...) }
+func (expression *ArrayLiteral) String() string {
+	if expression == nil {
+		return ""
+	}
+
+	var out bytes.Buffer
+	out.WriteString("[")
+
+	for i, element := range expression.Elements {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+
+		if element != nil {
+			out.WriteString(element.String())
+		}
+	}
+
+	out.WriteString("]")
+
+	return out.String()
+}
+
+/* --- Hash Literal --------------------------------------------------------- */
+
+// HashLiteral keeps its pairs in source order, Keys[i] maps to Values[i].
+type HashLiteral struct {
+	Token  token.Token // The opening brace
+	Keys   []Expression
+	Values []Expression
+}
+
+func (expression *HashLiteral) expressionNode()      {}
+func (expression *HashLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *HashLiteral) Children() []Node {
+	nodes := []Node{}
+
+	for i := range expression.Keys {
+		nodes = append(nodes, collectExpressions(expression.Keys[i], expression.Values[i])...)
+	}
+
+	return nodes
+}
+func (expression *HashLiteral) String() string {
+	if expression == nil {
+		return ""
+	}
+
+	var out bytes.Buffer
+	out.WriteString("{")
+
+	for i := range expression.Keys {
+		if i > 0 {
+			out.WriteString(", ")
+		}
+
+		if expression.Keys[i] != nil {
+			out.WriteString(expression.Keys[i].String())
+		}
+
+		out.WriteString(": ")
+
+		if expression.Values[i] != nil {
+			out.WriteString(expression.Values[i].String())
+		}
+	}
+
+	out.WriteString("}")
+
+	return out.String()
+}
+
 /* --- Function Literal ----------------------------------------------------- */
 
 type FunctionLiteral struct {
@@ -71,6 +201,19 @@ type FunctionLiteral struct {
 
 func (expression *FunctionLiteral) expressionNode()      {}
 func (expression *FunctionLiteral) TokenLiteral() string { return expression.Token.Literal }
+func (expression *FunctionLiteral) Children() []Node {
+	children := []Node{}
+
+	for _, param := range expression.Parameters {
+		children = append(children, param)
+	}
+
+	if expression.Body != nil {
+		children = append(children, expression.Body)
+	}
+
+	return children
+}
 func (expression *FunctionLiteral) String() string {
 	if expression == nil {
 		return ""
@@ -3,4 +3,32 @@ package ast
 type Node interface {
 	TokenLiteral() string
 	String() string
+
+	// Children returns the direct sub-nodes in source order, missing
+	// (nil) sub-nodes are left out.
+	Children() []Node
+}
+
+func collectExpressions(expressions ...Expression) []Node {
+	children := []Node{}
+
+	for _, expression := range expressions {
+		if expression != nil {
+			children = append(children, expression)
+		}
+	}
+
+	return children
+}
+
+func collectStatements(statements []Statement) []Node {
+	children := []Node{}
+
+	for _, statement := range statements {
+		if statement != nil {
+			children = append(children, statement)
+		}
+	}
+
+	return children
 }
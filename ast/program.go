@@ -14,6 +14,10 @@ func (p *Program) TokenLiteral() string {
 	return ""
 }
 
+func (p *Program) Children() []Node {
+	return collectStatements(p.Statements)
+}
+
 func (p *Program) String() string {
 	if p == nil {
 		return ""
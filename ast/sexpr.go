@@ -0,0 +1,154 @@
+package ast
+
+import (
+	"strconv"
+	"strings"
+)
+
+// SExpr renders an AST as a fully parenthesised S-expression. Operators come
+// first, `(+ a (* b c))`, and each statement of a program is on its own line,
+// which makes it a stable format for golden files.
+func SExpr(node Node) string {
+	var out strings.Builder
+
+	if program, ok := node.(*Program); ok {
+		out.WriteString("(program")
+
+		for _, statement := range program.Children() {
+			out.WriteString("\n  ")
+			writeSExpr(&out, statement)
+		}
+
+		out.WriteString(")")
+
+		return out.String()
+	}
+
+	writeSExpr(&out, node)
+
+	return out.String()
+}
+
+func writeSExprList(out *strings.Builder, head string, nodes ...Node) {
+	out.WriteString("(" + head)
+
+	for _, node := range nodes {
+		out.WriteString(" ")
+		writeSExpr(out, node)
+	}
+
+	out.WriteString(")")
+}
+
+func writeSExpr(out *strings.Builder, node Node) {
+	switch node := node.(type) {
+	case *Program:
+		writeSExprList(out, "program", node.Children()...)
+
+	case *LetStatement:
+		writeSExprList(out, "let", optionalNode(node.Identifier, node.Identifier == nil), optionalNode(node.Expression, node.Expression == nil))
+
+	case *ReturnStatement:
+		writeSExprList(out, "return", optionalNode(node.Expression, node.Expression == nil))
+
+	case *BlockStatement:
+		writeSExprList(out, "block", node.Children()...)
+
+	case *ExpressionStatement:
+		writeSExpr(out, optionalNode(node.Expression, node.Expression == nil))
+
+	case *IdentifierLiteral:
+		out.WriteString(node.Value)
+
+	case *BooleanLiteral, *IntegerLiteral, *StringLiteral:
+		out.WriteString(node.String())
+
+	case *ArrayLiteral:
+		nodes := []Node{}
+
+		for _, element := range node.Elements {
+			nodes = append(nodes, optionalNode(element, element == nil))
+		}
+
+		writeSExprList(out, "array", nodes...)
+
+	case *HashLiteral:
+		nodes := []Node{}
+
+		for i := range node.Keys {
+			nodes = append(nodes, optionalNode(node.Keys[i], node.Keys[i] == nil), optionalNode(node.Values[i], node.Values[i] == nil))
+		}
+
+		writeSExprList(out, "hash", nodes...)
+
+	case *FloatLiteral:
+		// Always keep a fraction or an exponent so floats can't be mistaken
+		// for integers.
+		str := strconv.FormatFloat(node.Value, 'g', -1, 64)
+
+		if !strings.ContainsAny(str, ".eIN") {
+			str += ".0"
+		}
+
+		out.WriteString(str)
+
+	case *FunctionLiteral:
+		out.WriteString("(function (")
+
+		for i, param := range node.Parameters {
+			if i > 0 {
+				out.WriteString(" ")
+			}
+
+			writeSExpr(out, param)
+		}
+
+		out.WriteString(") ")
+		writeSExpr(out, optionalNode(node.Body, node.Body == nil))
+		out.WriteString(")")
+
+	case *PrefixOperatorExpression:
+		writeSExprList(out, node.Operator, optionalNode(node.Right, node.Right == nil))
+
+	case *InfixOperatorExpression:
+		writeSExprList(out, node.Operator, optionalNode(node.Left, node.Left == nil), optionalNode(node.Right, node.Right == nil))
+
+	case *PostfixOperatorExpression:
+		writeSExprList(out, "postfix "+node.Operator, optionalNode(node.Left, node.Left == nil))
+
+	case *IfExpression:
+		if node.Alternative != nil {
+			writeSExprList(out, "if", optionalNode(node.Condition, node.Condition == nil), optionalNode(node.Consequence, node.Consequence == nil), node.Alternative)
+		} else {
+			writeSExprList(out, "if", optionalNode(node.Condition, node.Condition == nil), optionalNode(node.Consequence, node.Consequence == nil))
+		}
+
+	case *WhileExpression:
+		writeSExprList(out, "while", optionalNode(node.Condition, node.Condition == nil), optionalNode(node.Body, node.Body == nil))
+
+	case *CallExpression:
+		nodes := []Node{optionalNode(node.Function, node.Function == nil)}
+
+		for _, argument := range node.Arguments {
+			nodes = append(nodes, optionalNode(argument, argument == nil))
+		}
+
+		writeSExprList(out, "call", nodes...)
+
+	case *IndexExpression:
+		writeSExprList(out, "index", optionalNode(node.Left, node.Left == nil), optionalNode(node.Index, node.Index == nil))
+
+	default:
+		out.WriteString("nil")
+	}
+}
+
+// optionalNode turns missing sub-nodes into a nil Node, whatever their
+// static type, so they can be told apart from actual nodes.
+func optionalNode(node Node, isNil bool) Node {
+	if isNil {
+		return nil
+	}
+
+	return node
+}
@@ -20,6 +20,15 @@ type LetStatement struct {
 
 func (statement *LetStatement) statementNode()       {}
 func (statement *LetStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *LetStatement) Children() []Node {
+	children := []Node{}
+
+	if statement.Identifier != nil {
+		children = append(children, statement.Identifier)
+	}
+
+	return append(children, collectExpressions(statement.Expression)...)
+}
 func (statement *LetStatement) String() string {
 	if statement == nil {
 		return ""
@@ -50,6 +59,9 @@ type ReturnStatement struct {
 
 func (statement *ReturnStatement) statementNode()       {}
 func (statement *ReturnStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *ReturnStatement) Children() []Node {
+	return collectExpressions(statement.Expression)
+}
 func (statement *ReturnStatement) String() string {
 	if statement == nil {
 		return ""
@@ -74,6 +86,9 @@ type BlockStatement struct {
 
 func (statement *BlockStatement) statementNode()       {}
 func (statement *BlockStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *BlockStatement) Children() []Node {
+	return collectStatements(statement.Statements)
+}
 func (statement *BlockStatement) String() string {
 	if statement == nil {
 		return ""
@@ -104,6 +119,9 @@ type ExpressionStatement struct {
 
 func (statement *ExpressionStatement) statementNode()       {}
 func (statement *ExpressionStatement) TokenLiteral() string { return statement.Token.Literal }
+func (statement *ExpressionStatement) Children() []Node {
+	return collectExpressions(statement.Expression)
+}
 func (statement *ExpressionStatement) String() string {
 	if statement == nil {
 		return ""
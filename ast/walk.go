@@ -0,0 +1,224 @@
+package ast
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// A Visitor's Visit method is invoked for each node encountered by Walk. If
+// the result visitor w is not nil, Walk visits each of the children of node
+// with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order.
+func Walk(visitor Visitor, node Node) {
+	if visitor = visitor.Visit(node); visitor == nil {
+		return
+	}
+
+	for _, child := range node.Children() {
+		Walk(visitor, child)
+	}
+
+	visitor.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order, calling f for each node. If
+// f returns true, Inspect goes on with the children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Rewrite replaces the nodes of an AST bottom-up: the children of a node are
+// rewritten first, then f is called with the node and its result takes the
+// node's place. f returns the node itself to keep it.
+//
+// Nodes are updated in place. A replacement without a position inherits the
+// position of the node it replaces, and returning nil in place of a statement
+// removes it. Rewrite panics if a replacement doesn't fit where the original
+// node was, like a statement in place of an expression.
+func Rewrite(node Node, f func(Node) Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		node.Statements = rewriteStatements(node.Statements, f)
+
+	case *LetStatement:
+		if node.Identifier != nil {
+			node.Identifier = Rewrite(node.Identifier, f).(*IdentifierLiteral)
+		}
+
+		node.Expression = rewriteExpression(node.Expression, f)
+
+	case *ReturnStatement:
+		node.Expression = rewriteExpression(node.Expression, f)
+
+	case *BlockStatement:
+		node.Statements = rewriteStatements(node.Statements, f)
+
+	case *ExpressionStatement:
+		node.Expression = rewriteExpression(node.Expression, f)
+
+	case *IdentifierLiteral, *BooleanLiteral, *IntegerLiteral, *FloatLiteral, *StringLiteral:
+
+	case *ArrayLiteral:
+		for i, element := range node.Elements {
+			node.Elements[i] = rewriteExpression(element, f)
+		}
+
+	case *HashLiteral:
+		for i := range node.Keys {
+			node.Keys[i] = rewriteExpression(node.Keys[i], f)
+			node.Values[i] = rewriteExpression(node.Values[i], f)
+		}
+
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i] = Rewrite(param, f).(*IdentifierLiteral)
+		}
+
+		if node.Body != nil {
+			node.Body = Rewrite(node.Body, f).(*BlockStatement)
+		}
+
+	case *PrefixOperatorExpression:
+		node.Right = rewriteExpression(node.Right, f)
+
+	case *InfixOperatorExpression:
+		node.Left = rewriteExpression(node.Left, f)
+		node.Right = rewriteExpression(node.Right, f)
+
+	case *PostfixOperatorExpression:
+		node.Left = rewriteExpression(node.Left, f)
+
+	case *IfExpression:
+		node.Condition = rewriteExpression(node.Condition, f)
+
+		if node.Consequence != nil {
+			node.Consequence = Rewrite(node.Consequence, f).(*BlockStatement)
+		}
+
+		if node.Alternative != nil {
+			node.Alternative = Rewrite(node.Alternative, f).(*BlockStatement)
+		}
+
+	case *WhileExpression:
+		node.Condition = rewriteExpression(node.Condition, f)
+
+		if node.Body != nil {
+			node.Body = Rewrite(node.Body, f).(*BlockStatement)
+		}
+
+	case *CallExpression:
+		node.Function = rewriteExpression(node.Function, f)
+
+		for i, argument := range node.Arguments {
+			node.Arguments[i] = rewriteExpression(argument, f)
+		}
+
+	case *IndexExpression:
+		node.Left = rewriteExpression(node.Left, f)
+		node.Index = rewriteExpression(node.Index, f)
+
+	default:
+		panic(fmt.Sprintf("ast.Rewrite: unexpected node type %T", node))
+	}
+
+	replacement := f(node)
+
+	if replacement != nil && replacement != node {
+		if original, updated := TokenOf(node), TokenOf(replacement); original != nil && updated != nil && updated.Line == 0 {
+			updated.Line = original.Line
+			updated.Column = original.Column
+		}
+	}
+
+	return replacement
+}
+
+func rewriteExpression(expression Expression, f func(Node) Node) Expression {
+	if expression == nil {
+		return nil
+	}
+
+	if replacement := Rewrite(expression, f); replacement != nil {
+		return replacement.(Expression)
+	}
+
+	return nil
+}
+
+func rewriteStatements(statements []Statement, f func(Node) Node) []Statement {
+	rewritten := statements[:0]
+
+	for _, statement := range statements {
+		if statement == nil {
+			continue
+		}
+
+		if replacement := Rewrite(statement, f); replacement != nil {
+			rewritten = append(rewritten, replacement.(Statement))
+		}
+	}
+
+	return rewritten
+}
+
+// TokenOf returns a pointer to the token a node was created from, so its
+// position can be read or adjusted. It returns nil for a Program.
+func TokenOf(node Node) *token.Token {
+	switch node := node.(type) {
+	case *LetStatement:
+		return &node.Token
+	case *ReturnStatement:
+		return &node.Token
+	case *BlockStatement:
+		return &node.Token
+	case *ExpressionStatement:
+		return &node.Token
+	case *IdentifierLiteral:
+		return &node.Token
+	case *BooleanLiteral:
+		return &node.Token
+	case *IntegerLiteral:
+		return &node.Token
+	case *FloatLiteral:
+		return &node.Token
+	case *StringLiteral:
+		return &node.Token
+	case *ArrayLiteral:
+		return &node.Token
+	case *HashLiteral:
+		return &node.Token
+	case *FunctionLiteral:
+		return &node.Token
+	case *PrefixOperatorExpression:
+		return &node.Token
+	case *InfixOperatorExpression:
+		return &node.Token
+	case *PostfixOperatorExpression:
+		return &node.Token
+	case *IfExpression:
+		return &node.Token
+	case *WhileExpression:
+		return &node.Token
+	case *CallExpression:
+		return &node.Token
+	case *IndexExpression:
+		return &node.Token
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,240 @@
+package ast
+
+import (
+	goast "go/ast"
+	goparser "go/parser"
+	gotoken "go/token"
+	"monkey/token"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func ident(name string, line int, column int) *IdentifierLiteral {
+	return &IdentifierLiteral{Token: token.Token{Type: token.Identifier, Literal: name, Line: line, Column: column}, Value: name}
+}
+
+func integer(value int64, line int, column int) *IntegerLiteral {
+	return &IntegerLiteral{Token: token.Token{Type: token.Integer, Line: line, Column: column}, Value: value}
+}
+
+// sampleNodes has one fully populated instance of every node type, it must be
+// kept in sync with the node types declared in this package.
+func sampleNodes() []Node {
+	block := &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: ident("x", 2, 1)}}}
+
+	return []Node{
+		&Program{Statements: []Statement{&ExpressionStatement{Expression: ident("x", 1, 1)}}},
+		&LetStatement{Identifier: ident("x", 1, 5), Expression: integer(1, 1, 9)},
+		&ReturnStatement{Expression: ident("x", 1, 8)},
+		block,
+		&ExpressionStatement{Expression: ident("x", 1, 1)},
+		ident("x", 1, 1),
+		&BooleanLiteral{Value: true},
+		integer(1, 1, 1),
+		&FloatLiteral{Value: 1.5},
+		&StringLiteral{Value: "x"},
+		&ArrayLiteral{Elements: []Expression{ident("x", 1, 2), integer(1, 1, 5)}},
+		&HashLiteral{Keys: []Expression{ident("k", 1, 2)}, Values: []Expression{integer(1, 1, 5)}},
+		&FunctionLiteral{Parameters: []*IdentifierLiteral{ident("a", 1, 10)}, Body: block},
+		&PrefixOperatorExpression{Operator: "-", Right: ident("x", 1, 2)},
+		&InfixOperatorExpression{Operator: "+", Left: ident("x", 1, 1), Right: ident("y", 1, 5)},
+		&PostfixOperatorExpression{Operator: "++", Left: ident("x", 1, 1)},
+		&IfExpression{Condition: ident("x", 1, 5), Consequence: block, Alternative: block},
+		&WhileExpression{Condition: ident("x", 1, 8), Body: block},
+		&CallExpression{Function: ident("f", 1, 1), Arguments: []Expression{ident("x", 1, 3), integer(1, 1, 6)}},
+		&IndexExpression{Left: ident("a", 1, 1), Index: integer(1, 1, 3)},
+	}
+}
+
+// declaredNodeTypes lists the types of this package implementing Node by
+// reading its sources, so a new node type can't be forgotten by the walker.
+func declaredNodeTypes(t *testing.T) []string {
+	paths, err := filepath.Glob("*.go")
+
+	if err != nil {
+		t.Fatalf("couldn't list the ast package: %s", err)
+	}
+
+	types := []string{}
+
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+
+		file, err := goparser.ParseFile(gotoken.NewFileSet(), path, nil, 0)
+
+		if err != nil {
+			t.Fatalf("couldn't parse %s: %s", path, err)
+		}
+
+		for _, decl := range file.Decls {
+			function, ok := decl.(*goast.FuncDecl)
+
+			if !ok || function.Recv == nil || function.Name.Name != "TokenLiteral" {
+				continue
+			}
+
+			receiver := function.Recv.List[0].Type.(*goast.StarExpr).X.(*goast.Ident)
+			types = append(types, receiver.Name)
+		}
+	}
+
+	sort.Strings(types)
+
+	return types
+}
+
+func TestWalkerSupportsEveryNode(t *testing.T) {
+	sampled := []string{}
+
+	for _, node := range sampleNodes() {
+		sampled = append(sampled, reflect.TypeOf(node).Elem().Name())
+	}
+
+	sort.Strings(sampled)
+
+	if declared := declaredNodeTypes(t); !reflect.DeepEqual(declared, sampled) {
+		t.Fatalf("node types changed, update sampleNodes, Rewrite and TokenOf.\ndeclared: %v\nsampled:  %v", declared, sampled)
+	}
+
+	for _, node := range sampleNodes() {
+		name := reflect.TypeOf(node).Elem().Name()
+
+		if _, isProgram := node.(*Program); TokenOf(node) == nil && !isProgram {
+			t.Errorf("TokenOf doesn't support %s", name)
+		}
+
+		field, hasToken := reflect.TypeOf(node).Elem().FieldByName("Token")
+		if hasToken && field.Type != reflect.TypeOf(token.Token{}) {
+			t.Errorf("%s.Token isn't a token.Token", name)
+		}
+
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					t.Errorf("Rewrite doesn't support %s: %v", name, err)
+				}
+			}()
+
+			Rewrite(node, func(node Node) Node { return node })
+		}()
+	}
+}
+
+func TestChildren(t *testing.T) {
+	tests := []struct {
+		node     Node
+		expected int
+	}{
+		{&LetStatement{Identifier: ident("x", 1, 5)}, 1},
+		{&IfExpression{Condition: ident("x", 1, 5), Consequence: &BlockStatement{}}, 2},
+		{&FunctionLiteral{Parameters: []*IdentifierLiteral{ident("a", 1, 1), ident("b", 1, 3)}, Body: &BlockStatement{}}, 3},
+		{&BlockStatement{Statements: []Statement{nil, &ExpressionStatement{}}}, 1},
+		{ident("x", 1, 1), 0},
+	}
+
+	for _, test := range tests {
+		if children := test.node.Children(); len(children) != test.expected {
+			t.Errorf("%T - expected %d children, got %d", test.node, test.expected, len(children))
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	// let a = 1 + b;
+	program := &Program{Statements: []Statement{
+		&LetStatement{Identifier: ident("a", 1, 5), Expression: &InfixOperatorExpression{
+			Operator: "+",
+			Left:     integer(1, 1, 9),
+			Right:    ident("b", 1, 13),
+		}},
+	}}
+
+	visited := []string{}
+
+	Inspect(program, func(node Node) bool {
+		if node != nil {
+			visited = append(visited, reflect.TypeOf(node).Elem().Name())
+		}
+
+		_, isInfix := node.(*InfixOperatorExpression)
+		return !isInfix
+	})
+
+	expected := []string{"Program", "LetStatement", "IdentifierLiteral", "InfixOperatorExpression"}
+
+	if !reflect.DeepEqual(visited, expected) {
+		t.Errorf("expected %v, got %v", expected, visited)
+	}
+}
+
+type countingVisitor struct {
+	enter int
+	leave int
+}
+
+func (visitor *countingVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		visitor.leave++
+	} else {
+		visitor.enter++
+	}
+
+	return visitor
+}
+
+func TestWalk(t *testing.T) {
+	for _, node := range sampleNodes() {
+		visitor := &countingVisitor{}
+		Walk(visitor, node)
+
+		if visitor.enter != visitor.leave {
+			t.Errorf("%T - %d nodes entered but %d left", node, visitor.enter, visitor.leave)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	// let a = 1 + 2; 3;
+	program := &Program{Statements: []Statement{
+		&LetStatement{Token: token.Token{Type: token.Let, Literal: "let", Line: 1, Column: 1}, Identifier: ident("a", 1, 5), Expression: &InfixOperatorExpression{
+			Token:    token.Token{Type: token.Plus, Literal: "+", Line: 1, Column: 11},
+			Operator: "+",
+			Left:     integer(1, 1, 9),
+			Right:    integer(2, 1, 13),
+		}},
+		&ExpressionStatement{Expression: integer(3, 1, 16)},
+	}}
+
+	// Fold constant additions and drop expression statements, both
+	// rewrites only apply once their children are rewritten.
+	rewritten := Rewrite(program, func(node Node) Node {
+		switch node := node.(type) {
+		case *InfixOperatorExpression:
+			left, leftOk := node.Left.(*IntegerLiteral)
+			right, rightOk := node.Right.(*IntegerLiteral)
+
+			if leftOk && rightOk && node.Operator == "+" {
+				return &IntegerLiteral{Value: left.Value + right.Value}
+			}
+		case *ExpressionStatement:
+			return nil
+		}
+
+		return node
+	}).(*Program)
+
+	if rewritten.String() != "let a = 3;" {
+		t.Fatalf("expected 'let a = 3;', got '%s'", rewritten.String())
+	}
+
+	folded := rewritten.Statements[0].(*LetStatement).Expression.(*IntegerLiteral)
+
+	if folded.Token.Line != 1 || folded.Token.Column != 11 {
+		t.Errorf("expected the folded literal to keep the position 1:11, got %d:%d", folded.Token.Line, folded.Token.Column)
+	}
+}
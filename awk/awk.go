@@ -0,0 +1,288 @@
+// Package awk runs monkey programs over the lines of their input, the way
+// awk does, for one-liners in shell pipelines.
+//
+// A program is made of statements run for each line, with the optional
+// blocks BEGIN { ... } and END { ... } run before the first line and after
+// the last one. Everything runs in one environment where each line binds:
+//
+//	line    the line, without its end of line
+//	fields  the line followed by its fields, fields[1] is the first one
+//	NF      the number of fields
+//	NR      the number of the line, counted across inputs from 1
+//
+// The final semicolon of the program and of its blocks may be left out.
+package awk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/token"
+	"monkey/tokenizer"
+	"strings"
+)
+
+// Program is a parsed awk program, Begin and End are nil without their
+// block.
+type Program struct {
+	Begin *ast.Program
+	Main  *ast.Program
+	End   *ast.Program
+}
+
+// section is the source of a part of a program. It is as long as the whole
+// source, with the text of the other parts blanked, so that errors are
+// reported where they are in the source.
+type section []rune
+
+func blank(source []rune) section {
+	blanked := make(section, len(source))
+
+	for i, char := range source {
+		if char == '\n' {
+			blanked[i] = '\n'
+		} else {
+			blanked[i] = ' '
+		}
+	}
+
+	return blanked
+}
+
+// Parse splits source into its parts and parses them, it returns the syntax
+// errors of all of them.
+func Parse(source string) (*Program, []string) {
+	runes := []rune(source)
+	main := section(append([]rune{}, runes...))
+	var begin, end section
+
+	// offset converts the position of a token into an index in runes.
+	lines := []int{0}
+
+	for i, char := range runes {
+		if char == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+
+	offset := func(tok token.Token) int {
+		return lines[tok.Line-1] + tok.Column - 1
+	}
+
+	tokens := []token.Token{}
+	tok := tokenizer.New(source)
+
+	for t := tok.NextToken(); t.Type != token.EOF; t = tok.NextToken() {
+		tokens = append(tokens, t)
+	}
+
+	depth := 0
+	statementStart := true
+
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+
+		if depth == 0 && statementStart && t.Type == token.Identifier && (t.Literal == "BEGIN" || t.Literal == "END") &&
+			i+1 < len(tokens) && tokens[i+1].Type == token.OpeningBrace {
+			closing := matchingBrace(tokens, i+1)
+
+			if closing == -1 {
+				// Left to the parser to report.
+				break
+			}
+
+			target := &begin
+
+			if t.Literal == "END" {
+				target = &end
+			}
+
+			if *target == nil {
+				*target = blank(runes)
+			}
+
+			from, to := offset(tokens[i+1])+1, offset(tokens[closing])
+			copy((*target)[from:to], runes[from:to])
+
+			// The closing brace ends the last statement if it was left
+			// open.
+			if content := strings.TrimSpace(string(runes[from:to])); content != "" && !strings.HasSuffix(content, ";") {
+				(*target)[to] = ';'
+			}
+
+			last := closing
+
+			if last+1 < len(tokens) && tokens[last+1].Type == token.Semicolon {
+				last++
+			}
+
+			copy(main[offset(t):offset(tokens[last])+1], blank(runes[offset(t):offset(tokens[last])+1]))
+
+			i = last
+			continue
+		}
+
+		switch t.Type {
+		case token.OpeningParenthesis, token.OpeningBrace, token.OpeningBracket:
+			depth++
+		case token.ClosingParenthesis, token.ClosingBrace, token.ClosingBracket:
+			depth--
+		}
+
+		statementStart = depth == 0 && t.Type == token.Semicolon
+	}
+
+	if content := strings.TrimSpace(string(main)); content != "" && !strings.HasSuffix(content, ";") {
+		main = append(main, ';')
+	}
+
+	program := &Program{}
+	errors := []string{}
+
+	parse := func(source section) *ast.Program {
+		if source == nil {
+			return nil
+		}
+
+		parser := parser.New(tokenizer.New(string(source)))
+		parsed := parser.Parse()
+		errors = append(errors, parser.Errors...)
+
+		return parsed
+	}
+
+	program.Begin = parse(begin)
+	program.Main = parse(main)
+	program.End = parse(end)
+
+	return program, errors
+}
+
+// matchingBrace returns the index of the brace closing the one at opening,
+// -1 if it isn't closed.
+func matchingBrace(tokens []token.Token, opening int) int {
+	depth := 0
+
+	for i := opening; i < len(tokens); i++ {
+		switch tokens[i].Type {
+		case token.OpeningBrace:
+			depth++
+		case token.ClosingBrace:
+			depth--
+
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+// ReadsInput tells whether the program has anything to run on the lines of
+// its input, a program with only a BEGIN block doesn't read it.
+func (program *Program) ReadsInput() bool {
+	return len(program.Main.Statements) != 0 || program.End != nil
+}
+
+/* --- Runner --------------------------------------------------------------- */
+
+// Runner runs a program, keeping its environment and the line count across
+// the parts of the program and the inputs.
+type Runner struct {
+	// Separator splits lines into fields, runs of whitespace when empty.
+	Separator string
+
+	// Evaluator runs the parts of the program, NewRunner sets it up to
+	// write to out.
+	Evaluator *evaluator.Evaluator
+
+	program *Program
+	env     *object.Environment
+	count   int
+}
+
+// NewRunner creates a runner of program, writing its output to out.
+func NewRunner(program *Program, out io.Writer) *Runner {
+	evaluator := evaluator.New()
+	evaluator.Output = out
+
+	return &Runner{program: program, Evaluator: evaluator, env: object.NewEnvironment()}
+}
+
+func (runner *Runner) eval(program *ast.Program) (object.Object, error) {
+	if program == nil {
+		return evaluator.Null, nil
+	}
+
+	result := runner.Evaluator.Eval(program, runner.env)
+
+	if err, ok := result.(*object.ErrorObject); ok {
+		return nil, errors.New(err.Message)
+	}
+
+	return result, nil
+}
+
+// Begin runs the BEGIN block.
+func (runner *Runner) Begin() error {
+	_, err := runner.eval(runner.program.Begin)
+	return err
+}
+
+// Eval runs the statements of the program once, without a line, and returns
+// their value.
+func (runner *Runner) Eval() (object.Object, error) {
+	return runner.eval(runner.program.Main)
+}
+
+// Process runs the statements of the program on each line of in.
+func (runner *Runner) Process(in io.Reader) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(nil, 1<<20)
+
+	for scanner.Scan() {
+		runner.count++
+		runner.bindLine(scanner.Text())
+
+		if _, err := runner.eval(runner.program.Main); err != nil {
+			return fmt.Errorf("line %d: %s", runner.count, err)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// End runs the END block, the bindings of the last line are still there.
+func (runner *Runner) End() error {
+	_, err := runner.eval(runner.program.End)
+	return err
+}
+
+func (runner *Runner) bindLine(line string) {
+	var split []string
+
+	switch {
+	case line == "":
+	case runner.Separator == "":
+		split = strings.Fields(line)
+	default:
+		split = strings.Split(line, runner.Separator)
+	}
+
+	fields := []object.Object{&object.StringObject{Value: line}}
+
+	for _, field := range split {
+		fields = append(fields, &object.StringObject{Value: field})
+	}
+
+	runner.env.Set("line", &object.StringObject{Value: line})
+	runner.env.Set("fields", &object.ArrayObject{Elements: fields})
+	runner.env.Set("NF", &object.IntegerObject{Value: int64(len(split))})
+	runner.env.Set("NR", &object.IntegerObject{Value: int64(runner.count)})
+}
@@ -0,0 +1,88 @@
+package awk
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		source    string
+		separator string
+		input     string
+		expected  string
+	}{
+		{`puts(fields[1])`, ",", "a,b\nc,d\n", "a\nc\n"},
+		{`puts(NR, NF, fields[2])`, "", "  x   y z\n\nw\n", "1 3 y\n2 0 null\n3 1 null\n"},
+		{`BEGIN { let total = 0 } let total = total + int(fields[2]); END { puts(total, NR) }`, "\t", "a\t1\nb\t2\n", "3 2\n"},
+		{"BEGIN {\n\tputs(\"start\");\n};\nputs(line);\nEND { puts(\"end\"); };", "", "x\n", "start\nx\nend\n"},
+		{`if (NR > 1) { puts(line); }`, "", "head\nbody\n", "body\n"},
+		{`let f = function(x) { return x + "!"; }; puts(f(line))`, "", "a\n", "a!\n"},
+		{`BEGIN { puts(1) }`, "", "", "1\n"},
+		{`puts(int(fields[2]) + 1)`, ",", "b,010\nc,0009\n", "11\n10\n"},
+	}
+
+	for _, test := range tests {
+		program, errors := Parse(test.source)
+
+		if len(errors) != 0 {
+			t.Errorf("%q - unexpected errors %v", test.source, errors)
+			continue
+		}
+
+		var out bytes.Buffer
+		runner := NewRunner(program, &out)
+		runner.Separator = test.separator
+
+		if err := runner.Begin(); err != nil {
+			t.Errorf("%q - BEGIN failed: %s", test.source, err)
+		}
+
+		if program.ReadsInput() {
+			if err := runner.Process(strings.NewReader(test.input)); err != nil {
+				t.Errorf("%q - failed: %s", test.source, err)
+			}
+		}
+
+		if err := runner.End(); err != nil {
+			t.Errorf("%q - END failed: %s", test.source, err)
+		}
+
+		if out.String() != test.expected {
+			t.Errorf("%q - expected %q, got %q", test.source, test.expected, out.String())
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, errors := Parse("BEGIN { let = 1 }\nputs(a"); len(errors) == 0 || !strings.HasPrefix(errors[0], "Ln 1, Col 13:") {
+		t.Errorf("expected errors at their position in the source, got %v", errors)
+	}
+
+	if _, errors := Parse("BEGIN { 1;"); len(errors) == 0 {
+		t.Errorf("expected an unclosed BEGIN block to be an error")
+	}
+
+	program, _ := Parse("puts(1 + int(fields[1]))")
+	runner := NewRunner(program, &bytes.Buffer{})
+	err := runner.Process(strings.NewReader("1\nx\n"))
+
+	if err == nil || err.Error() != `line 2: Ln 1, Col 13: int: invalid integer "x"` {
+		t.Errorf("expected the error to tell the line, got %v", err)
+	}
+}
+
+func TestEval(t *testing.T) {
+	program, errors := Parse("let a = 2; a ** 10")
+
+	if len(errors) != 0 {
+		t.Fatalf("unexpected errors %v", errors)
+	}
+
+	result, err := NewRunner(program, &bytes.Buffer{}).Eval()
+
+	if err != nil || result.Inspect() != "1024" {
+		t.Errorf("expected 1024, got %v %v", result, err)
+	}
+}
@@ -0,0 +1,284 @@
+// Package cst is a lossless concrete syntax tree of monkey source code.
+//
+// Unlike the ast, the tree keeps every token of the input, punctuation,
+// whitespace and comments included, so that concatenating its leaves gives
+// back the source byte for byte. Tools that edit code use it to change only
+// what they mean to, Lower turns it into an ast.Program to evaluate it.
+package cst
+
+import (
+	"fmt"
+	"monkey/token"
+	"sort"
+	"strings"
+)
+
+// Kind tells which syntax a Node stands for.
+type Kind string
+
+const (
+	Program             Kind = "Program"
+	LetStatement        Kind = "LetStatement"
+	ReturnStatement     Kind = "ReturnStatement"
+	BlockStatement      Kind = "BlockStatement"
+	ExpressionStatement Kind = "ExpressionStatement"
+
+	Identifier        Kind = "Identifier"
+	IntegerLiteral    Kind = "IntegerLiteral"
+	FloatLiteral      Kind = "FloatLiteral"
+	BooleanLiteral    Kind = "BooleanLiteral"
+	StringLiteral     Kind = "StringLiteral"
+	ArrayLiteral      Kind = "ArrayLiteral"
+	HashLiteral       Kind = "HashLiteral"
+	FunctionLiteral   Kind = "FunctionLiteral"
+	ParameterList     Kind = "ParameterList"
+	PrefixExpression  Kind = "PrefixExpression"
+	InfixExpression   Kind = "InfixExpression"
+	PostfixExpression Kind = "PostfixExpression"
+	GroupedExpression Kind = "GroupedExpression"
+	IfExpression      Kind = "IfExpression"
+	ElseClause        Kind = "ElseClause"
+	WhileExpression   Kind = "WhileExpression"
+	CallExpression    Kind = "CallExpression"
+	ArgumentList      Kind = "ArgumentList"
+	IndexExpression   Kind = "IndexExpression"
+
+	// Error holds tokens the parser couldn't fit anywhere.
+	Error Kind = "Error"
+)
+
+// Element is either a *Node or a *Token.
+type Element interface {
+	// Text returns the exact source text of the element.
+	Text() string
+}
+
+// Node is an inner node of the tree, its children are in source order.
+type Node struct {
+	Kind     Kind
+	Children []Element
+
+	// The syntax errors found while parsing a statement stay on it, so they
+	// go along with it when the tree is reparsed.
+	errors []diagnostic
+
+	width    int
+	measured bool
+}
+
+// Token is a leaf of the tree. The whitespace and comments preceding a token
+// are attached to it, the trivia at the end of the input goes to the EOF
+// token closing the Program.
+type Token struct {
+	Leading []token.Token
+	token.Token
+
+	// Encoding errors found by the tokenizer while reading the token.
+	errors []diagnostic
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+// endPosition returns the position of the last character of text, counted
+// the way the tokenizer does from start.
+func endPosition(start position, text string) position {
+	for _, char := range text {
+		if char == '\n' {
+			start.line++
+			start.column = 0
+		} else {
+			start.column++
+		}
+	}
+
+	return start
+}
+
+type diagnostic struct {
+	line    int
+	column  int
+	message string
+}
+
+func (diagnostic diagnostic) String() string {
+	return fmt.Sprintf("Ln %d, Col %d: %s", diagnostic.line, diagnostic.column, diagnostic.message)
+}
+
+// parseDiagnostic splits an error of the tokenizer into its position and
+// message.
+func parseDiagnostic(err string) diagnostic {
+	var parsed diagnostic
+
+	if _, scanErr := fmt.Sscanf(err, "Ln %d, Col %d:", &parsed.line, &parsed.column); scanErr != nil {
+		return diagnostic{message: err}
+	}
+
+	parsed.message = err[strings.Index(err, ": ")+2:]
+
+	return parsed
+}
+
+func (tok *Token) Text() string {
+	var out strings.Builder
+
+	tok.writeText(&out)
+
+	return out.String()
+}
+
+func (tok *Token) width() int {
+	width := len(tok.Literal)
+
+	for _, trivia := range tok.Leading {
+		width += len(trivia.Literal)
+	}
+
+	return width
+}
+
+func (tok *Token) writeText(out *strings.Builder) {
+	for _, trivia := range tok.Leading {
+		out.WriteString(trivia.Literal)
+	}
+
+	out.WriteString(tok.Literal)
+}
+
+func (node *Node) Text() string {
+	var out strings.Builder
+
+	for _, tok := range node.Tokens() {
+		tok.writeText(&out)
+	}
+
+	return out.String()
+}
+
+// Errors returns the syntax and encoding errors found in the source of node,
+// ordered by position.
+func (node *Node) Errors() []string {
+	diagnostics := []diagnostic{}
+
+	var collect func(*Node)
+	collect = func(node *Node) {
+		diagnostics = append(diagnostics, node.errors...)
+
+		for _, child := range node.Children {
+			switch child := child.(type) {
+			case *Token:
+				diagnostics = append(diagnostics, child.errors...)
+			case *Node:
+				collect(child)
+			}
+		}
+	}
+
+	collect(node)
+
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if diagnostics[i].line != diagnostics[j].line {
+			return diagnostics[i].line < diagnostics[j].line
+		}
+
+		return diagnostics[i].column < diagnostics[j].column
+	})
+
+	errors := make([]string, len(diagnostics))
+
+	for i, diagnostic := range diagnostics {
+		errors[i] = diagnostic.String()
+	}
+
+	return errors
+}
+
+// measure records the length in bytes of the source of node and the nodes
+// under it.
+func (node *Node) measure() int {
+	if !node.measured {
+		node.width = 0
+
+		for _, child := range node.Children {
+			node.width += widthOf(child)
+		}
+
+		node.measured = true
+	}
+
+	return node.width
+}
+
+func widthOf(element Element) int {
+	switch element := element.(type) {
+	case *Token:
+		return element.width()
+	case *Node:
+		return element.measure()
+	default:
+		return 0
+	}
+}
+
+// Tokens returns the leaves under node in source order.
+func (node *Node) Tokens() []*Token {
+	tokens := []*Token{}
+
+	var collect func(*Node)
+	collect = func(node *Node) {
+		for _, child := range node.Children {
+			switch child := child.(type) {
+			case *Token:
+				tokens = append(tokens, child)
+			case *Node:
+				collect(child)
+			}
+		}
+	}
+
+	collect(node)
+
+	return tokens
+}
+
+// FirstToken returns the first leaf under node, or nil when it has none.
+func (node *Node) FirstToken() *Token {
+	for _, child := range node.Children {
+		switch child := child.(type) {
+		case *Token:
+			return child
+		case *Node:
+			if tok := child.FirstToken(); tok != nil {
+				return tok
+			}
+		}
+	}
+
+	return nil
+}
+
+// Nodes returns the children of node which are nodes.
+func (node *Node) Nodes() []*Node {
+	nodes := []*Node{}
+
+	for _, child := range node.Children {
+		if child, ok := child.(*Node); ok {
+			nodes = append(nodes, child)
+		}
+	}
+
+	return nodes
+}
+
+// Token returns the first child token of type t, or nil.
+func (node *Node) Token(t token.TokenType) *Token {
+	for _, child := range node.Children {
+		if child, ok := child.(*Token); ok && child.Type == t {
+			return child
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,221 @@
+package cst
+
+import (
+	"fmt"
+	"math/rand"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func parse(input string) (*Node, []string) {
+	parser := New(tokenizer.New(input))
+	tree := parser.Parse()
+
+	return tree, parser.Errors
+}
+
+// dump prints the tree as nested parentheses, tokens are quoted.
+func dump(element Element) string {
+	switch element := element.(type) {
+	case *Token:
+		return fmt.Sprintf("%q", element.Literal)
+	case *Node:
+		parts := []string{string(element.Kind)}
+
+		for _, child := range element.Children {
+			parts = append(parts, dump(child))
+		}
+
+		return "(" + strings.Join(parts, " ") + ")"
+	default:
+		return "?"
+	}
+}
+
+func TestTreeShape(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = (1 + b) ** 2;", `(Program (LetStatement "let" (Identifier "a") "=" (InfixExpression (GroupedExpression "(" (InfixExpression (IntegerLiteral "1") "+" (Identifier "b")) ")") "**" (IntegerLiteral "2")) ";") "")`},
+		{"a++;", `(Program (ExpressionStatement (PostfixExpression (Identifier "a") "++") ";") "")`},
+		{"if (a) { 1; } else { 2; };", `(Program (ExpressionStatement (IfExpression "if" "(" (Identifier "a") ")" (BlockStatement "{" (ExpressionStatement (IntegerLiteral "1") ";") "}") (ElseClause "else" (BlockStatement "{" (ExpressionStatement (IntegerLiteral "2") ";") "}"))) ";") "")`},
+		{"function(x, y) {};", `(Program (ExpressionStatement (FunctionLiteral "function" (ParameterList "(" (Identifier "x") "," (Identifier "y") ")") (BlockStatement "{" "}")) ";") "")`},
+		{"f(1, x);", `(Program (ExpressionStatement (CallExpression (Identifier "f") (ArgumentList "(" (IntegerLiteral "1") "," (Identifier "x") ")")) ";") "")`},
+		{`a[0] + ["b"];`, `(Program (ExpressionStatement (InfixExpression (IndexExpression (Identifier "a") "[" (IntegerLiteral "0") "]") "+" (ArrayLiteral "[" (StringLiteral "\"b\"") "]")) ";") "")`},
+		{`f({"a": 1});`, `(Program (ExpressionStatement (CallExpression (Identifier "f") (ArgumentList "(" (HashLiteral "{" (StringLiteral "\"a\"") ":" (IntegerLiteral "1") "}") ")")) ";") "")`},
+		{") 1;", `(Program (Error ")") (ExpressionStatement (IntegerLiteral "1") ";") "")`},
+	}
+
+	for _, test := range tests {
+		tree, _ := parse(test.input)
+
+		if actual := dump(tree); actual != test.expected {
+			t.Errorf("%q - expected\n%s\ngot\n%s", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	tree, errors := parse("// header\nlet a = 1; // one\n\n")
+
+	if len(errors) != 0 {
+		t.Fatalf("unexpected errors %v", errors)
+	}
+
+	tokens := tree.Tokens()
+
+	if let := tokens[0]; len(let.Leading) != 2 || let.Leading[0].Literal != "// header" {
+		t.Errorf("expected the header comment before let, got %+v", let.Leading)
+	}
+
+	if eof := tokens[len(tokens)-1]; eof.Text() != " // one\n\n" {
+		t.Errorf("expected the trailing trivia on EOF, got %q", eof.Text())
+	}
+}
+
+func TestLossless(t *testing.T) {
+	inputs := []string{
+		"",
+		"   ",
+		"let a = 1;",
+		"let  a=1 ;// no space\r\n",
+		"let café = function ( x ,y ) { return x**y ; } ;\n",
+		"if (a) {\n\t// comment\n} else { b-- };",
+		"let = ; ) } { ( while",
+		"let 1a = 0b12 # \xff\x00 ",
+		"function(a, 1) { ;",
+		"}}}",
+	}
+
+	random := rand.New(rand.NewSource(35))
+	alphabet := []string{"let", "a", " ", "\n", "=", "1", "2.5", "(", ")", "{", "}", ";", ",", "+", "**", "++", "if", "else", "while", "function", "return", "not", "// c\n", "\xff"}
+
+	for i := 0; i < 500; i++ {
+		var input strings.Builder
+
+		for n := random.Intn(30); n > 0; n-- {
+			input.WriteString(alphabet[random.Intn(len(alphabet))])
+		}
+
+		inputs = append(inputs, input.String())
+	}
+
+	for _, input := range inputs {
+		tree, _ := parse(input)
+
+		if actual := tree.Text(); actual != input {
+			t.Errorf("%q - text of the tree is %q", input, actual)
+		}
+
+		// Lowering must cope with any tree the parser builds.
+		Lower(tree)
+	}
+}
+
+func TestLowerMatchesParser(t *testing.T) {
+	inputs := []string{
+		"let a = 1;",
+		"let x = -a * b + c ** d ** 2 / 4;",
+		"a++; --b; not c and d or e;",
+		"let café = 1; café == café;",
+		"let f = function() { return; };",
+		"let g = function(a, b, c) { a < b; b > c; };",
+		"if (a != b) { a; } else { b; }; if (a) { };",
+		"while (i < 10) { i++; };",
+		"1.5e3 + 0x_ff + 0b101 + 0o17 + 123456789012345678901234567890;",
+		";;",
+		`let words = ["a", "b\tc", []]; words[1][0]; f(x)[len(y) - 1];`,
+		`let h = {"a": [1], 2: {}, x + 1: f(y)}; h["a"][0];`,
+		`{"a": [1], b: {}}; {}; {x: y}["x"];`,
+		"// comments\nlet a = 1; // are\n// ignored\n",
+	}
+
+	files, _ := filepath.Glob(filepath.Join("..", "parser", "testdata", "*.monkey"))
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		inputs = append(inputs, string(source))
+	}
+
+	for _, input := range inputs {
+		parser := parser.New(tokenizer.New(input))
+		expected := parser.Parse()
+
+		if len(parser.Errors) != 0 {
+			t.Fatalf("%q - the parser failed: %v", input, parser.Errors)
+		}
+
+		tree, errors := parse(input)
+		actual, lowerErrors := Lower(tree)
+
+		if len(errors) != 0 || len(lowerErrors) != 0 {
+			t.Errorf("%q - unexpected errors %v %v", input, errors, lowerErrors)
+		}
+
+		expectedJSON, _ := ast.MarshalJSON(expected)
+		actualJSON, _ := ast.MarshalJSON(actual)
+
+		if string(actualJSON) != string(expectedJSON) {
+			t.Errorf("%q - expected\n%s\ngot\n%s", input, expectedJSON, actualJSON)
+		}
+	}
+}
+
+func TestErrorsMatchParser(t *testing.T) {
+	inputs := []string{
+		"++;", `++"s";`, "5++;", "--f();", "++a++;", "(a + b)--;",
+		"1 + ;", "-;", "let a = ;", "(;", "if (;) { };",
+		"010;", "f(;", "f(1,;", "let x = [;", "a[;", `let h = {"a": ;`, "let h = {;",
+	}
+
+	for _, input := range inputs {
+		parser := parser.New(tokenizer.New(input))
+		parser.Parse()
+
+		if len(parser.Errors) == 0 {
+			t.Fatalf("%q - expected the parser to fail", input)
+		}
+
+		tree, errors := parse(input)
+		_, lowerErrors := Lower(tree)
+
+		if len(errors) == 0 && len(lowerErrors) == 0 {
+			t.Errorf("%q - expected errors as the parser reports %v", input, parser.Errors)
+		}
+	}
+
+	for _, input := range []string{"(a)++;", "--(b);"} {
+		if _, errors := parse(input); len(errors) != 0 {
+			t.Errorf("%q - unexpected errors %v", input, errors)
+		}
+	}
+}
+
+func TestLowerErrors(t *testing.T) {
+	tree, _ := parse(`let a = 0b12; let b = 1e999; let c = "\q";`)
+	program, errors := Lower(tree)
+
+	expected := []string{
+		"Ln 1, Col 9: malformed integer literal 0b12",
+		"Ln 1, Col 23: float literal 1e999 is out of range",
+		`Ln 1, Col 38: malformed string literal "\q"`,
+	}
+
+	if strings.Join(errors, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected errors %v, got %v", expected, errors)
+	}
+
+	if len(program.Statements) != 3 {
+		t.Errorf("expected 3 statements, got %d", len(program.Statements))
+	}
+}
@@ -0,0 +1,323 @@
+package cst
+
+import (
+	"fmt"
+	"monkey/token"
+	"monkey/tokenizer"
+	"strings"
+	"unicode/utf8"
+)
+
+// Edit replaces the source text from byte offset Start up to End with Text.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Reparse returns the tree of the source of tree with edit applied, as Parse
+// would build it, leaving tree untouched.
+//
+// Only the statements around the edit are tokenized and parsed again, in the
+// innermost block containing the edit when possible. Parsing stops as soon as
+// it is back in step with the previous tree, the statements before the edit
+// are shared with it and the ones after are reused with their positions
+// shifted.
+func Reparse(tree *Node, edit Edit) (*Node, error) {
+	text := tree.Text()
+
+	if edit.Start < 0 || edit.Start > edit.End || edit.End > len(text) {
+		return nil, fmt.Errorf("edit range %d-%d is outside of the source, its length is %d", edit.Start, edit.End, len(text))
+	}
+
+	reparser := &reparser{
+		edit:    edit,
+		newText: text[:edit.Start] + edit.Text + text[edit.End:],
+		delta:   len(edit.Text) - (edit.End - edit.Start),
+	}
+
+	return reparser.node(tree, 0), nil
+}
+
+type reparser struct {
+	edit    Edit
+	newText string
+	delta   int
+
+	// The source from moved line and column wise, positions before it stay.
+	from        position
+	lineDelta   int
+	columnDelta int
+}
+
+// moved records that the source starting at from in the previous tree now
+// starts at to.
+func (reparser *reparser) moved(from position, to position) {
+	reparser.from = from
+	reparser.lineDelta = to.line - from.line
+	reparser.columnDelta = to.column - from.column
+}
+
+// firstPosition returns where the source of element starts, trivia included.
+func firstPosition(element Element) position {
+	tok, _ := element.(*Token)
+
+	if node, ok := element.(*Node); ok {
+		tok = node.FirstToken()
+	}
+
+	if tok == nil {
+		return position{}
+	}
+
+	if len(tok.Leading) > 0 {
+		return position{tok.Leading[0].Line, tok.Leading[0].Column}
+	}
+
+	return position{tok.Line, tok.Column}
+}
+
+// node returns the reparsed copy of node, whose source starts at offset, or
+// nil when the edit can't be handled inside of it.
+func (reparser *reparser) node(node *Node, offset int) *Node {
+	childOffset := offset
+
+	for i, child := range node.Children {
+		width := widthOf(child)
+
+		if child, ok := child.(*Node); ok && childOffset < reparser.edit.Start && reparser.edit.End < childOffset+width {
+			// A block standing as a statement may turn into a hash, it is
+			// parsed again along with the statements around it.
+			if child.Kind == BlockStatement && (node.Kind == Program || node.Kind == BlockStatement) {
+				break
+			}
+
+			if reparsed := reparser.node(child, childOffset); reparsed != nil {
+				return reparser.replace(node, i, reparsed)
+			}
+
+			break
+		}
+
+		childOffset += width
+	}
+
+	switch node.Kind {
+	case Program:
+		return reparser.statements(node, offset, 0)
+	case BlockStatement:
+		last := len(node.Children) - 1
+		closing, ok := node.Children[last].(*Token)
+
+		// Both braces must stay as they are, the edit may only touch the
+		// trivia before the closing one.
+		if !ok || last == 0 || closing.Type != token.ClosingBrace {
+			return nil
+		}
+
+		if reparser.edit.Start < offset+widthOf(node.Children[0]) || reparser.edit.End > offset+node.measure()-len(closing.Literal) {
+			return nil
+		}
+
+		return reparser.statements(node, offset, 1)
+	default:
+		return nil
+	}
+}
+
+// replace copies node with its child i replaced.
+func (reparser *reparser) replace(node *Node, i int, child *Node) *Node {
+	children := make([]Element, len(node.Children))
+
+	copy(children, node.Children[:i])
+	children[i] = child
+
+	for j := i + 1; j < len(children); j++ {
+		children[j] = reparser.shift(node.Children[j])
+	}
+
+	replaced := &Node{Kind: node.Kind, Children: children, errors: reparser.shiftDiagnostics(node.errors)}
+	replaced.measure()
+
+	return replaced
+}
+
+// statements parses again the statements of a Program or a block around the
+// edit, they are the children of node from first up to its last child, the
+// EOF or closing brace token.
+func (reparser *reparser) statements(node *Node, offset int, first int) *Node {
+	last := len(node.Children) - 1
+	offsets := make([]int, len(node.Children)+1)
+	offsets[0] = offset
+
+	for i, child := range node.Children {
+		offsets[i+1] = offsets[i] + widthOf(child)
+	}
+
+	// The tokenizer looks up to two characters past a token to find where
+	// it ends, and the parser one token past a statement. The statement
+	// before the first one the edit can change is parsed again as well.
+	start := first
+
+	for start < last && offsets[start+1]+2*utf8.UTFMax < reparser.edit.Start {
+		start++
+	}
+
+	if start > first {
+		start--
+	}
+
+	end := start
+
+	for end < last && offsets[end+1] <= reparser.edit.End {
+		end++
+	}
+
+	from := offsets[start]
+	at := endPosition(position{1, 0}, reparser.newText[:from])
+	parser := New(tokenizer.NewFromReaderAt(strings.NewReader(reparser.newText[from:]), at.line, at.column+1))
+
+	children := append([]Element{}, node.Children[:start]...)
+	editEnd := reparser.edit.Start + len(reparser.edit.Text)
+	closer := token.TokenType(token.EOF)
+
+	if node.Kind == BlockStatement {
+		closer = token.ClosingBrace
+	}
+
+	for {
+		consumed := from + parser.consumed
+
+		// Back in step with the previous tree, the rest is unchanged.
+		if consumed >= editEnd {
+			for k := end + 1; k <= last; k++ {
+				if offsets[k] == consumed-reparser.delta {
+					reparser.moved(firstPosition(node.Children[k]), firstPosition(parser.current))
+
+					for _, child := range node.Children[k:] {
+						children = append(children, reparser.shift(child))
+					}
+
+					return reparser.finish(node, children)
+				}
+			}
+		}
+
+		if parser.currentIs(closer) || parser.currentIs(token.EOF) || consumed > offsets[last+1]+reparser.delta {
+			break
+		}
+
+		children = append(children, parser.parseStatementOrError())
+	}
+
+	if !parser.currentIs(closer) {
+		return nil
+	}
+
+	closing := parser.advance()
+	children = append(children, closing)
+
+	if old, ok := node.Children[last].(*Token); ok {
+		reparser.moved(position{old.Line, old.Column}, position{closing.Line, closing.Column})
+	}
+
+	// A block must still end where it used to, otherwise its braces now pair
+	// up differently.
+	if node.Kind == BlockStatement && from+parser.consumed != offsets[last+1]+reparser.delta {
+		return nil
+	}
+
+	return reparser.finish(node, children)
+}
+
+func (reparser *reparser) finish(node *Node, children []Element) *Node {
+	reparsed := &Node{Kind: node.Kind, Children: children, errors: reparser.shiftDiagnostics(node.errors)}
+	reparsed.measure()
+
+	return reparsed
+}
+
+// shift returns element with the positions past the edit moved, element
+// itself when none of them moves.
+func (reparser *reparser) shift(element Element) Element {
+	if reparser.lineDelta == 0 && reparser.columnDelta == 0 {
+		return element
+	}
+
+	switch element := element.(type) {
+	case *Token:
+		shifted := *element
+		changed := reparser.shiftToken(&shifted.Token)
+
+		if element.Leading != nil {
+			shifted.Leading = make([]token.Token, len(element.Leading))
+			copy(shifted.Leading, element.Leading)
+		}
+
+		for i := range shifted.Leading {
+			changed = reparser.shiftToken(&shifted.Leading[i]) || changed
+		}
+
+		shifted.errors = reparser.shiftDiagnostics(element.errors)
+
+		if !changed && len(element.errors) == 0 {
+			return element
+		}
+
+		return &shifted
+	case *Node:
+		shifted := &Node{Kind: element.Kind, errors: reparser.shiftDiagnostics(element.errors), width: element.width, measured: element.measured}
+		changed := len(element.errors) > 0
+
+		shifted.Children = make([]Element, len(element.Children))
+
+		for i, child := range element.Children {
+			shifted.Children[i] = reparser.shift(child)
+			changed = changed || shifted.Children[i] != child
+		}
+
+		if !changed {
+			return element
+		}
+
+		return shifted
+	default:
+		return element
+	}
+}
+
+func (reparser *reparser) shiftPosition(line int, column int) (int, int) {
+	if line < reparser.from.line || line == reparser.from.line && column < reparser.from.column {
+		return line, column
+	}
+
+	if line == reparser.from.line {
+		column += reparser.columnDelta
+	}
+
+	return line + reparser.lineDelta, column
+}
+
+func (reparser *reparser) shiftToken(tok *token.Token) bool {
+	line, column := reparser.shiftPosition(tok.Line, tok.Column)
+	changed := line != tok.Line || column != tok.Column
+
+	tok.Line, tok.Column = line, column
+
+	return changed
+}
+
+func (reparser *reparser) shiftDiagnostics(diagnostics []diagnostic) []diagnostic {
+	if diagnostics == nil {
+		return nil
+	}
+
+	shifted := make([]diagnostic, len(diagnostics))
+
+	for i, diagnostic := range diagnostics {
+		diagnostic.line, diagnostic.column = reparser.shiftPosition(diagnostic.line, diagnostic.column)
+		shifted[i] = diagnostic
+	}
+
+	return shifted
+}
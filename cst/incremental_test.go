@@ -0,0 +1,131 @@
+package cst
+
+import (
+	"fmt"
+	"math/rand"
+	"monkey/tokenizer"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReparseMatchesParse(t *testing.T) {
+	sources := []string{
+		"",
+		"let add = function(a, b) {\n\treturn a + b;\n};\n\nlet i = 0;\nwhile (i < 10) {\n\ti++;\n};\n",
+		"if (a) { let b = 1; { c; }; } else { d--; };\n// done\n",
+	}
+
+	snippets := []string{"", "a", "b1", " ", "\n", "\t", ";", "{", "}", "(", ")", "+", "*", "=", ",", "1", "2.5", "let ", "let x = 1;", "if (x) { y; };", "function(p) { p; }", "else", "// note\n", "/", "\xff", "é", "é", ":", `{"k": 1};`}
+
+	random := rand.New(rand.NewSource(36))
+
+	for _, source := range sources {
+		tree, _ := parse(source)
+		text := source
+
+		for step := 0; step < 400; step++ {
+			start := random.Intn(len(text) + 1)
+			end := start + random.Intn(min(4, len(text)-start)+1)
+			edit := Edit{Start: start, End: end, Text: snippets[random.Intn(len(snippets))]}
+
+			reparsed, err := Reparse(tree, edit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text = text[:start] + edit.Text + text[end:]
+			expected, _ := parse(text)
+
+			if !reflect.DeepEqual(reparsed, expected) {
+				t.Fatalf("%q after %+v - trees differ\n%s", text, edit, difference(detail(expected), detail(reparsed)))
+			}
+
+			if actual, want := reparsed.Errors(), expected.Errors(); !reflect.DeepEqual(actual, want) {
+				t.Fatalf("%q after %+v - expected errors %v, got %v", text, edit, want, actual)
+			}
+
+			tree = reparsed
+		}
+	}
+}
+
+func TestReparseReusesStatements(t *testing.T) {
+	source := "let a = 1;\nlet f = function(x) {\n\tlet y = x;\n\tlet w = y;\n\treturn y;\n};\nlet b = 2;\n"
+	tree, _ := parse(source)
+
+	// Rename y to z in the return statement.
+	offset := strings.Index(source, "return y") + len("return ")
+	reparsed, err := Reparse(tree, Edit{Start: offset, End: offset + 1, Text: "z"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	old, statements := tree.Nodes(), reparsed.Nodes()
+
+	if statements[0] != old[0] || statements[2] != old[2] {
+		t.Errorf("expected the statements around the function to be reused")
+	}
+
+	oldBody := old[1].Nodes()[1].Nodes()[1]
+	body := statements[1].Nodes()[1].Nodes()[1]
+
+	if body.Kind != BlockStatement || body.Nodes()[0] != oldBody.Nodes()[0] {
+		t.Errorf("expected the first statement of the function body to be reused")
+	}
+
+	if text := reparsed.Text(); !strings.Contains(text, "return z;") {
+		t.Errorf("expected the edit in the text, got %q", text)
+	}
+
+	// Adding a line moves the statements after it.
+	reparsed, _ = Reparse(tree, Edit{Start: 0, End: 0, Text: "\n"})
+
+	if tok := reparsed.Nodes()[2].FirstToken(); tok.Line != 8 || tok.Column != 1 {
+		t.Errorf("expected the last statement at 8:1, got %d:%d", tok.Line, tok.Column)
+	}
+
+	if tree.Nodes()[2].FirstToken().Line != 7 {
+		t.Errorf("expected the previous tree to be left untouched")
+	}
+}
+
+func TestReparseInvalidEdit(t *testing.T) {
+	tree := New(tokenizer.New("let a = 1;")).Parse()
+
+	if _, err := Reparse(tree, Edit{Start: 5, End: 20}); err == nil {
+		t.Errorf("expected an error for an edit past the end of the source")
+	}
+}
+
+// detail prints the tree along with the positions, widths and errors.
+func detail(element Element) string {
+	switch element := element.(type) {
+	case *Token:
+		return fmt.Sprintf("%q@%d:%d%v%v", element.Literal, element.Line, element.Column, element.Leading, element.errors)
+	case *Node:
+		parts := []string{fmt.Sprintf("%s/%d%v", element.Kind, element.width, element.errors)}
+
+		for _, child := range element.Children {
+			parts = append(parts, detail(child))
+		}
+
+		return "(" + strings.Join(parts, " ") + ")"
+	default:
+		return "?"
+	}
+}
+
+// difference shows where two dumps start to differ.
+func difference(expected string, actual string) string {
+	i := 0
+
+	for i < len(expected) && i < len(actual) && expected[i] == actual[i] {
+		i++
+	}
+
+	from := max(0, i-200)
+
+	return fmt.Sprintf("expected ...%s\ngot      ...%s", expected[from:min(len(expected), i+100)], actual[from:min(len(actual), i+100)])
+}
@@ -0,0 +1,383 @@
+package cst
+
+import (
+	"fmt"
+	"math/big"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/token"
+	"strconv"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Lower converts a Program node into the ast.Program the parser package would
+// build from the same source. Malformed number and string literals are left
+// out of the tree and reported in the returned errors, the syntax errors were already
+// reported by the Parser.
+func Lower(program *Node) (*ast.Program, []string) {
+	lowering := &lowering{}
+
+	return &ast.Program{Statements: lowering.statements(program)}, lowering.errors
+}
+
+type lowering struct {
+	errors []string
+}
+
+func (lowering *lowering) errorf(tok token.Token, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	lowering.errors = append(lowering.errors, fmt.Sprintf("Ln %d, Col %d: %s", tok.Line, tok.Column, msg))
+}
+
+// astToken returns the token as the parser sees it, with identifiers in their
+// normalized spelling.
+func astToken(tok *Token) token.Token {
+	if tok.Type == token.Identifier {
+		normalized := tok.Token
+		normalized.Literal = norm.NFC.String(tok.Literal)
+
+		return normalized
+	}
+
+	return tok.Token
+}
+
+func (lowering *lowering) statements(node *Node) []ast.Statement {
+	statements := []ast.Statement{}
+
+	for _, child := range node.Nodes() {
+		if statement := lowering.statement(child); statement != nil {
+			statements = append(statements, statement)
+		}
+	}
+
+	return statements
+}
+
+func (lowering *lowering) statement(node *Node) ast.Statement {
+	switch node.Kind {
+	case LetStatement:
+		nodes := node.Nodes()
+
+		if len(nodes) == 0 || nodes[0].Kind != Identifier || node.Token(token.Assign) == nil {
+			return nil
+		}
+
+		return &ast.LetStatement{
+			Token:      node.Children[0].(*Token).Token,
+			Identifier: lowering.identifier(nodes[0]),
+			Expression: lowering.expression(nth(nodes, 1)),
+		}
+	case ReturnStatement:
+		return &ast.ReturnStatement{
+			Token:      node.Children[0].(*Token).Token,
+			Expression: lowering.expression(nth(node.Nodes(), 0)),
+		}
+	case BlockStatement:
+		return lowering.block(node)
+	case ExpressionStatement:
+		return &ast.ExpressionStatement{
+			Token:      astToken(node.FirstToken()),
+			Expression: lowering.expression(nth(node.Nodes(), 0)),
+		}
+	default:
+		return nil
+	}
+}
+
+func (lowering *lowering) block(node *Node) *ast.BlockStatement {
+	if node == nil || node.Kind != BlockStatement {
+		return nil
+	}
+
+	return &ast.BlockStatement{Token: node.Children[0].(*Token).Token, Statements: lowering.statements(node)}
+}
+
+func (lowering *lowering) identifier(node *Node) *ast.IdentifierLiteral {
+	tok := astToken(node.Children[0].(*Token))
+
+	return &ast.IdentifierLiteral{Token: tok, Value: tok.Literal}
+}
+
+func nth(nodes []*Node, i int) *Node {
+	if 0 <= i && i < len(nodes) {
+		return nodes[i]
+	}
+
+	return nil
+}
+
+func (lowering *lowering) expression(node *Node) ast.Expression {
+	if node == nil {
+		return nil
+	}
+
+	nodes := node.Nodes()
+
+	switch node.Kind {
+	case Identifier:
+		return lowering.identifier(node)
+	case IntegerLiteral:
+		return lowering.integer(node.Children[0].(*Token).Token)
+	case FloatLiteral:
+		return lowering.float(node.Children[0].(*Token).Token)
+	case BooleanLiteral:
+		tok := node.Children[0].(*Token).Token
+
+		return &ast.BooleanLiteral{Token: tok, Value: tok.Type == token.True}
+	case StringLiteral:
+		tok := node.Children[0].(*Token).Token
+		value, err := parser.Unquote(tok.Literal)
+
+		if err != nil {
+			lowering.errorf(tok, "%s", err)
+			return nil
+		}
+
+		return &ast.StringLiteral{Token: tok, Value: value}
+	case ArrayLiteral:
+		if node.Token(token.ClosingBracket) == nil {
+			return nil
+		}
+
+		elements := lowering.expressions(nodes)
+
+		if elements == nil {
+			return nil
+		}
+
+		return &ast.ArrayLiteral{Token: node.Children[0].(*Token).Token, Elements: elements}
+	case HashLiteral:
+		return lowering.hash(node)
+	case FunctionLiteral:
+		return lowering.function(node)
+	case PrefixExpression:
+		tok := node.Children[0].(*Token).Token
+
+		return &ast.PrefixOperatorExpression{Token: tok, Operator: tok.Literal, Right: lowering.expression(nth(nodes, 0))}
+	case InfixExpression:
+		tok := node.Children[1].(*Token).Token
+
+		return &ast.InfixOperatorExpression{
+			Token:    tok,
+			Operator: tok.Literal,
+			Left:     lowering.expression(nodes[0]),
+			Right:    lowering.expression(nth(nodes, 1)),
+		}
+	case PostfixExpression:
+		tok := node.Children[1].(*Token).Token
+
+		return &ast.PostfixOperatorExpression{Token: tok, Operator: tok.Literal, Left: lowering.expression(nodes[0])}
+	case GroupedExpression:
+		if node.Token(token.ClosingParenthesis) == nil {
+			return nil
+		}
+
+		return lowering.expression(nth(nodes, 0))
+	case CallExpression:
+		return lowering.call(node)
+	case IndexExpression:
+		index := lowering.expression(nth(nodes, 1))
+
+		if index == nil || node.Token(token.ClosingBracket) == nil {
+			return nil
+		}
+
+		return &ast.IndexExpression{Token: node.Children[1].(*Token).Token, Left: lowering.expression(nodes[0]), Index: index}
+	case IfExpression:
+		return lowering.ifExpression(node)
+	case WhileExpression:
+		body := lowering.block(nth(nodes, len(nodes)-1))
+
+		if body == nil {
+			return nil
+		}
+
+		return &ast.WhileExpression{
+			Token:     node.Children[0].(*Token).Token,
+			Condition: lowering.condition(nodes),
+			Body:      body,
+		}
+	default:
+		return nil
+	}
+}
+
+// condition returns the condition of an if or while, the first child node
+// unless it is already the body.
+func (lowering *lowering) condition(nodes []*Node) ast.Expression {
+	if len(nodes) == 0 || nodes[0].Kind == BlockStatement {
+		return nil
+	}
+
+	return lowering.expression(nodes[0])
+}
+
+func (lowering *lowering) ifExpression(node *Node) ast.Expression {
+	nodes := node.Nodes()
+	expression := &ast.IfExpression{Token: node.Children[0].(*Token).Token, Condition: lowering.condition(nodes)}
+
+	for _, child := range nodes {
+		switch child.Kind {
+		case BlockStatement:
+			expression.Consequence = lowering.block(child)
+		case ElseClause:
+			if expression.Alternative = lowering.block(nth(child.Nodes(), 0)); expression.Alternative == nil {
+				return nil
+			}
+		}
+	}
+
+	if expression.Consequence == nil {
+		return nil
+	}
+
+	return expression
+}
+
+func (lowering *lowering) function(node *Node) ast.Expression {
+	nodes := node.Nodes()
+
+	if len(nodes) != 2 || nodes[0].Token(token.ClosingParenthesis) == nil {
+		return nil
+	}
+
+	function := &ast.FunctionLiteral{
+		Token:      node.Children[0].(*Token).Token,
+		Parameters: []*ast.IdentifierLiteral{},
+		Body:       lowering.block(nodes[1]),
+	}
+
+	for _, parameter := range nodes[0].Nodes() {
+		function.Parameters = append(function.Parameters, lowering.identifier(parameter))
+	}
+
+	return function
+}
+
+func (lowering *lowering) call(node *Node) ast.Expression {
+	nodes := node.Nodes()
+	arguments := nodes[1]
+	opening := arguments.Children[0].(*Token).Token
+
+	if arguments.Token(token.ClosingParenthesis) == nil {
+		return nil
+	}
+
+	call := &ast.CallExpression{Token: opening, Function: lowering.expression(nodes[0]), Arguments: lowering.expressions(arguments.Nodes())}
+
+	if call.Arguments == nil {
+		return nil
+	}
+
+	return call
+}
+
+func (lowering *lowering) hash(node *Node) ast.Expression {
+	if node.Token(token.ClosingBrace) == nil {
+		return nil
+	}
+
+	hash := &ast.HashLiteral{Token: node.Children[0].(*Token).Token, Keys: []ast.Expression{}, Values: []ast.Expression{}}
+	var key ast.Expression
+
+	// Keys and values alternate, a key is followed by a colon.
+	for i, child := range node.Children {
+		child, ok := child.(*Node)
+
+		if !ok {
+			continue
+		}
+
+		expression := lowering.expression(child)
+
+		if expression == nil {
+			return nil
+		}
+
+		if colon, ok := nthElement(node.Children, i+1).(*Token); ok && colon.Type == token.Colon {
+			key = expression
+			continue
+		}
+
+		if key == nil {
+			return nil
+		}
+
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, expression)
+		key = nil
+	}
+
+	if key != nil {
+		return nil
+	}
+
+	return hash
+}
+
+func nthElement(elements []Element, i int) Element {
+	if 0 <= i && i < len(elements) {
+		return elements[i]
+	}
+
+	return nil
+}
+
+// expressions lowers the elements of a list, it returns nil if one of them
+// is missing from the ast.
+func (lowering *lowering) expressions(nodes []*Node) []ast.Expression {
+	expressions := []ast.Expression{}
+
+	for _, node := range nodes {
+		expression := lowering.expression(node)
+
+		if expression == nil {
+			return nil
+		}
+
+		expressions = append(expressions, expression)
+	}
+
+	return expressions
+}
+
+func (lowering *lowering) integer(tok token.Token) ast.Expression {
+	if parser.IsLegacyOctal(tok.Literal) {
+		lowering.errorf(tok, "leading zero in integer literal %s, octal integers start with 0o", tok.Literal)
+		return nil
+	}
+
+	value, err := strconv.ParseInt(tok.Literal, 0, 64)
+	if err == nil {
+		return &ast.IntegerLiteral{Token: tok, Value: value}
+	}
+
+	if numError, ok := err.(*strconv.NumError); ok && numError.Err == strconv.ErrRange {
+		if value, ok := new(big.Int).SetString(tok.Literal, 0); ok {
+			return &ast.IntegerLiteral{Token: tok, Big: value}
+		}
+	}
+
+	lowering.numberLiteralError(tok, err, "integer")
+	return nil
+}
+
+func (lowering *lowering) float(tok token.Token) ast.Expression {
+	value, err := strconv.ParseFloat(tok.Literal, 64)
+	if err == nil {
+		return &ast.FloatLiteral{Token: tok, Value: value}
+	}
+
+	lowering.numberLiteralError(tok, err, "float")
+	return nil
+}
+
+func (lowering *lowering) numberLiteralError(tok token.Token, err error, kind string) {
+	if numError, ok := err.(*strconv.NumError); ok && numError.Err == strconv.ErrRange {
+		lowering.errorf(tok, "%s literal %s is out of range", kind, tok.Literal)
+	} else {
+		lowering.errorf(tok, "malformed %s literal %s", kind, tok.Literal)
+	}
+}
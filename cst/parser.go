@@ -0,0 +1,540 @@
+package cst
+
+import (
+	"fmt"
+	"monkey/parser"
+	"monkey/token"
+	"monkey/tokenizer"
+)
+
+// Parser builds a concrete syntax tree following the grammar of the parser
+// package. It never gives up on a token: what doesn't fit the grammar ends up
+// in an Error node or is left out of the node expecting it, with an entry in
+// Errors.
+type Parser struct {
+	tokenizer       *tokenizer.Tokenizer
+	tokenizerErrors int
+	current         *Token
+	ahead           []*Token
+	consumed        int
+	pending         []diagnostic
+	carried         []diagnostic
+	Errors          []string
+}
+
+func New(source *tokenizer.Tokenizer) *Parser {
+	parser := &Parser{tokenizer: source}
+
+	parser.current = parser.scan()
+
+	return parser
+}
+
+func (parser *Parser) Parse() *Node {
+	program := &Node{Kind: Program}
+
+	for !parser.currentIs(token.EOF) {
+		program.Children = append(program.Children, parser.parseStatementOrError())
+	}
+
+	program.Children = append(program.Children, parser.current)
+	program.measure()
+
+	return program
+}
+
+// scan reads the next token along with the trivia in front of it.
+func (parser *Parser) scan() *Token {
+	leaf := &Token{}
+
+	for {
+		tok := parser.tokenizer.Scan()
+
+		leaf.errors = append(leaf.errors, parser.carried...)
+		parser.carried = nil
+
+		// The tokenizer reads one character past a token, an error about it
+		// goes with the next token, wherever the tokenizer started.
+		next := endPosition(position{tok.Line, tok.Column - 1}, tok.Literal)
+		next.column++
+
+		for ; parser.tokenizerErrors < len(parser.tokenizer.Errors); parser.tokenizerErrors++ {
+			err := parser.tokenizer.Errors[parser.tokenizerErrors]
+			diagnostic := parseDiagnostic(err)
+
+			parser.Errors = append(parser.Errors, err)
+
+			if tok.Type != token.EOF && diagnostic.line == next.line && diagnostic.column == next.column {
+				parser.carried = append(parser.carried, diagnostic)
+			} else {
+				leaf.errors = append(leaf.errors, diagnostic)
+			}
+		}
+
+		if tok.Type == token.Whitespace || tok.Type == token.Comment {
+			leaf.Leading = append(leaf.Leading, tok)
+			continue
+		}
+
+		leaf.Token = tok
+
+		return leaf
+	}
+}
+
+// advance moves past the current token and returns it.
+func (parser *Parser) advance() *Token {
+	tok := parser.current
+
+	if tok.Type != token.EOF {
+		parser.consumed += tok.width()
+
+		if len(parser.ahead) != 0 {
+			parser.current, parser.ahead = parser.ahead[0], parser.ahead[1:]
+		} else {
+			parser.current = parser.scan()
+		}
+	}
+
+	return tok
+}
+
+// peek returns the token n past the current one, scanning it ahead of time.
+// Past the end it is the end of file.
+func (parser *Parser) peek(n int) token.Token {
+	for len(parser.ahead) < n {
+		last := parser.current
+
+		if len(parser.ahead) != 0 {
+			last = parser.ahead[len(parser.ahead)-1]
+		}
+
+		if last.Type == token.EOF {
+			return last.Token
+		}
+
+		parser.ahead = append(parser.ahead, parser.scan())
+	}
+
+	return parser.ahead[n-1].Token
+}
+
+func (parser *Parser) currentIs(t token.TokenType) bool {
+	return parser.current.Type == t
+}
+
+// expect adds the current token to node if it is of type t.
+func (parser *Parser) expect(node *Node, t token.TokenType) bool {
+	if !parser.currentIs(t) {
+		parser.errorf(parser.current.Token, "expected %s, got %s instead", t, parser.current.Type)
+		return false
+	}
+
+	node.Children = append(node.Children, parser.advance())
+
+	return true
+}
+
+func (parser *Parser) errorf(tok token.Token, format string, args ...interface{}) {
+	diagnostic := diagnostic{line: tok.Line, column: tok.Column, message: fmt.Sprintf(format, args...)}
+
+	parser.Errors = append(parser.Errors, diagnostic.String())
+	parser.pending = append(parser.pending, diagnostic)
+}
+
+func appendNode(node *Node, child *Node) {
+	if child != nil {
+		node.Children = append(node.Children, child)
+	}
+}
+
+/* --- Statements ----------------------------------------------------------- */
+
+// parseStatementOrError always consumes at least one token, so the callers'
+// loops end.
+func (parser *Parser) parseStatementOrError() *Node {
+	before := parser.current
+	enclosing := parser.pending
+	parser.pending = nil
+
+	statement := parser.parseStatement()
+
+	if parser.current == before {
+		statement = &Node{Kind: Error, Children: []Element{parser.advance()}}
+	}
+
+	statement.errors = parser.pending
+	parser.pending = enclosing
+
+	return statement
+}
+
+func (parser *Parser) parseStatement() *Node {
+	switch parser.current.Type {
+	case token.Let:
+		return parser.parseLetStatement()
+	case token.Return:
+		return parser.parseReturnStatement()
+	case token.OpeningBrace:
+		if startsHashLiteral(parser.peek) {
+			return parser.parseExpressionStatement()
+		}
+
+		// As in the parser, a block isn't ended by a semicolon, the one
+		// after it is an empty statement of its own.
+		block := parser.parseBlockStatement()
+
+		parser.errorf(parser.current.Token, "expected %s at end of statement, got %s", token.Semicolon, parser.current.Type)
+
+		return block
+	default:
+		return parser.parseExpressionStatement()
+	}
+}
+
+func (parser *Parser) endStatement(statement *Node) {
+	if parser.currentIs(token.Semicolon) {
+		statement.Children = append(statement.Children, parser.advance())
+	} else {
+		parser.errorf(parser.current.Token, "expected %s at end of statement, got %s", token.Semicolon, parser.current.Type)
+	}
+}
+
+func (parser *Parser) parseLetStatement() *Node {
+	statement := &Node{Kind: LetStatement, Children: []Element{parser.advance()}}
+
+	if parser.currentIs(token.Identifier) {
+		appendNode(statement, &Node{Kind: Identifier, Children: []Element{parser.advance()}})
+	} else {
+		parser.errorf(parser.current.Token, "expected %s, got %s instead", token.Identifier, parser.current.Type)
+	}
+
+	if parser.expect(statement, token.Assign) {
+		appendNode(statement, parser.parseRequiredExpression(precedenceLowest))
+	}
+
+	parser.endStatement(statement)
+
+	return statement
+}
+
+func (parser *Parser) parseReturnStatement() *Node {
+	statement := &Node{Kind: ReturnStatement, Children: []Element{parser.advance()}}
+
+	appendNode(statement, parser.parseExpression(precedenceLowest))
+	parser.endStatement(statement)
+
+	return statement
+}
+
+func (parser *Parser) parseBlockStatement() *Node {
+	block := &Node{Kind: BlockStatement, Children: []Element{parser.advance()}}
+
+	for !parser.currentIs(token.ClosingBrace) && !parser.currentIs(token.EOF) {
+		block.Children = append(block.Children, parser.parseStatementOrError())
+	}
+
+	parser.expect(block, token.ClosingBrace)
+
+	return block
+}
+
+func (parser *Parser) parseExpressionStatement() *Node {
+	statement := &Node{Kind: ExpressionStatement}
+
+	appendNode(statement, parser.parseExpression(precedenceLowest))
+
+	if len(statement.Children) == 0 && !parser.currentIs(token.Semicolon) {
+		return nil
+	}
+
+	parser.endStatement(statement)
+
+	return statement
+}
+
+/* --- Expressions ---------------------------------------------------------- */
+
+// The precedences are the parser package's, the rules below can't refer to
+// it by name as their receiver shadows it.
+var (
+	precedenceLowest = parser.PrecedenceLowest
+	precedencePrefix = parser.PrecedencePrefix
+	infixPrecedence  = parser.InfixPrecedence
+)
+
+// startsHashLiteral is the parser package's rule telling a hash opening a
+// statement from a block.
+var startsHashLiteral = parser.StartsHashLiteral
+
+func (parser *Parser) parseExpression(precedence int) *Node {
+	if parser.currentIs(token.Semicolon) {
+		return nil
+	}
+
+	left := parser.parsePrefix()
+
+	if left == nil {
+		return nil
+	}
+
+	for !parser.currentIs(token.Semicolon) {
+		next, ok := infixPrecedence(parser.current.Type)
+
+		if precedence >= next {
+			break
+		}
+
+		if !ok {
+			parser.errorf(parser.current.Token, "unexpected %s after an expression", parser.current.Type)
+			break
+		}
+
+		left = parser.parseInfix(left, next)
+	}
+
+	return left
+}
+
+// parseRequiredExpression parses an expression that can't be left out,
+// parseExpression takes a semicolon for an empty expression without an error.
+func (parser *Parser) parseRequiredExpression(precedence int) *Node {
+	if parser.currentIs(token.Semicolon) {
+		parser.errorf(parser.current.Token, "expected an expression")
+		return nil
+	}
+
+	return parser.parseExpression(precedence)
+}
+
+func (parser *Parser) parsePrefix() *Node {
+	switch parser.current.Type {
+	case token.Identifier:
+		return &Node{Kind: Identifier, Children: []Element{parser.advance()}}
+	case token.Integer:
+		return &Node{Kind: IntegerLiteral, Children: []Element{parser.advance()}}
+	case token.Float:
+		return &Node{Kind: FloatLiteral, Children: []Element{parser.advance()}}
+	case token.True, token.False:
+		return &Node{Kind: BooleanLiteral, Children: []Element{parser.advance()}}
+	case token.String:
+		return &Node{Kind: StringLiteral, Children: []Element{parser.advance()}}
+	case token.OpeningBracket:
+		array := &Node{Kind: ArrayLiteral, Children: []Element{parser.advance()}}
+		parser.parseList(array, token.ClosingBracket)
+
+		return array
+	case token.OpeningBrace:
+		return parser.parseHashLiteral()
+	case token.Function:
+		return parser.parseFunctionLiteral()
+	case token.Not, token.Plus, token.Minus, token.Increment, token.Decrement:
+		operator := parser.advance()
+		operand := parser.parseRequiredExpression(precedencePrefix)
+
+		if operator.Type == token.Increment || operator.Type == token.Decrement {
+			parser.expectAssignable(operator, operand)
+		}
+
+		expression := &Node{Kind: PrefixExpression, Children: []Element{operator}}
+		appendNode(expression, operand)
+
+		return expression
+	case token.OpeningParenthesis:
+		expression := &Node{Kind: GroupedExpression, Children: []Element{parser.advance()}}
+		appendNode(expression, parser.parseRequiredExpression(precedenceLowest))
+		parser.expect(expression, token.ClosingParenthesis)
+
+		return expression
+	case token.If:
+		return parser.parseIfExpression()
+	case token.While:
+		return parser.parseWhileExpression()
+	default:
+		parser.errorf(parser.current.Token, "unexpected %s, expected an expression", parser.current.Type)
+		return nil
+	}
+}
+
+func (parser *Parser) parseInfix(left *Node, precedence int) *Node {
+	operator := parser.advance()
+
+	if operator.Type == token.Increment || operator.Type == token.Decrement {
+		parser.expectAssignable(operator, left)
+
+		return &Node{Kind: PostfixExpression, Children: []Element{left, operator}}
+	}
+
+	if operator.Type == token.OpeningParenthesis {
+		arguments := &Node{Kind: ArgumentList, Children: []Element{operator}}
+		parser.parseList(arguments, token.ClosingParenthesis)
+
+		return &Node{Kind: CallExpression, Children: []Element{left, arguments}}
+	}
+
+	if operator.Type == token.OpeningBracket {
+		expression := &Node{Kind: IndexExpression, Children: []Element{left, operator}}
+		appendNode(expression, parser.parseRequiredExpression(precedenceLowest))
+		parser.expect(expression, token.ClosingBracket)
+
+		return expression
+	}
+
+	// ** is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2).
+	if operator.Type == token.Power {
+		precedence--
+	}
+
+	expression := &Node{Kind: InfixExpression, Children: []Element{left, operator}}
+	appendNode(expression, parser.parseRequiredExpression(precedence))
+
+	return expression
+}
+
+// expectAssignable reports an error when target can't be the operand of an
+// update operator, only identifiers, parenthesized or not, can be assigned
+// to. A missing target is already reported.
+func (parser *Parser) expectAssignable(operator *Token, target *Node) {
+	for target != nil && target.Kind == GroupedExpression {
+		target = nth(target.Nodes(), 0)
+	}
+
+	if target != nil && target.Kind != Identifier {
+		parser.errorf(operator.Token, "invalid operand for %s, expected an identifier", operator.Literal)
+	}
+}
+
+// parseList parses expressions separated by commas into list up to the end
+// token, the arguments of a call or the elements of an array. The opening
+// token is already in list.
+func (parser *Parser) parseList(list *Node, end token.TokenType) {
+	for !parser.currentIs(end) {
+		element := parser.parseRequiredExpression(precedenceLowest)
+
+		if element == nil {
+			break
+		}
+
+		appendNode(list, element)
+
+		if !parser.currentIs(token.Comma) {
+			break
+		}
+
+		list.Children = append(list.Children, parser.advance())
+	}
+
+	parser.expect(list, end)
+}
+
+// parseHashLiteral parses the pairs of a hash, each key and value are
+// children of the hash with the colon between them.
+func (parser *Parser) parseHashLiteral() *Node {
+	hash := &Node{Kind: HashLiteral, Children: []Element{parser.advance()}}
+
+	for !parser.currentIs(token.ClosingBrace) {
+		key := parser.parseRequiredExpression(precedenceLowest)
+
+		if key == nil {
+			break
+		}
+
+		appendNode(hash, key)
+
+		if !parser.expect(hash, token.Colon) {
+			break
+		}
+
+		appendNode(hash, parser.parseRequiredExpression(precedenceLowest))
+
+		if !parser.currentIs(token.Comma) {
+			break
+		}
+
+		hash.Children = append(hash.Children, parser.advance())
+	}
+
+	parser.expect(hash, token.ClosingBrace)
+
+	return hash
+}
+
+// parseCondition parses the parenthesized condition of if and while.
+func (parser *Parser) parseCondition(expression *Node) bool {
+	if !parser.expect(expression, token.OpeningParenthesis) {
+		return false
+	}
+
+	appendNode(expression, parser.parseRequiredExpression(precedenceLowest))
+
+	return parser.expect(expression, token.ClosingParenthesis)
+}
+
+func (parser *Parser) parseBody(expression *Node) {
+	if parser.currentIs(token.OpeningBrace) {
+		appendNode(expression, parser.parseBlockStatement())
+	} else {
+		parser.errorf(parser.current.Token, "expected %s, got %s instead", token.OpeningBrace, parser.current.Type)
+	}
+}
+
+func (parser *Parser) parseIfExpression() *Node {
+	expression := &Node{Kind: IfExpression, Children: []Element{parser.advance()}}
+
+	if !parser.parseCondition(expression) {
+		return expression
+	}
+
+	parser.parseBody(expression)
+
+	if parser.currentIs(token.Else) {
+		alternative := &Node{Kind: ElseClause, Children: []Element{parser.advance()}}
+		parser.parseBody(alternative)
+		appendNode(expression, alternative)
+	}
+
+	return expression
+}
+
+func (parser *Parser) parseWhileExpression() *Node {
+	expression := &Node{Kind: WhileExpression, Children: []Element{parser.advance()}}
+
+	if parser.parseCondition(expression) {
+		parser.parseBody(expression)
+	}
+
+	return expression
+}
+
+func (parser *Parser) parseFunctionLiteral() *Node {
+	function := &Node{Kind: FunctionLiteral, Children: []Element{parser.advance()}}
+
+	if !parser.currentIs(token.OpeningParenthesis) {
+		parser.errorf(parser.current.Token, "expected %s, got %s instead", token.OpeningParenthesis, parser.current.Type)
+		return function
+	}
+
+	parameters := &Node{Kind: ParameterList, Children: []Element{parser.advance()}}
+	appendNode(function, parameters)
+
+	for !parser.currentIs(token.ClosingParenthesis) {
+		if !parser.currentIs(token.Identifier) {
+			parser.errorf(parser.current.Token, "expected %s, got %s instead", token.Identifier, parser.current.Type)
+			break
+		}
+
+		appendNode(parameters, &Node{Kind: Identifier, Children: []Element{parser.advance()}})
+
+		if !parser.currentIs(token.Comma) {
+			break
+		}
+
+		parameters.Children = append(parameters.Children, parser.advance())
+	}
+
+	if parser.expect(parameters, token.ClosingParenthesis) {
+		parser.parseBody(function)
+	}
+
+	return function
+}
@@ -0,0 +1,388 @@
+// Package dap implements a debug adapter for monkey speaking the Debug
+// Adapter Protocol, so editors can debug monkey programs with breakpoints,
+// stepping, the call stack, variables and evaluation in a frame.
+//
+// The program has a single thread. Frames and variables are only valid while
+// the program is stopped, their ids are handed out again at every stack
+// trace.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/debugger"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const threadID = 1
+
+// Server is a debug adapter reading requests from one stream and writing
+// responses and events to another.
+type Server struct {
+	reader *bufio.Reader
+
+	// Events are written from the program's goroutine too.
+	mutex  sync.Mutex
+	writer io.Writer
+	seq    int
+
+	source      Source
+	debugger    *debugger.Debugger
+	breakpoints []debugger.Breakpoint
+	stopOnEntry bool
+	exited      chan struct{}
+
+	// Handed out by the last stack trace.
+	frames    []debugger.Frame
+	variables [][]debugger.Variable
+}
+
+// NewServer creates a server talking over in and out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{reader: bufio.NewReader(in), writer: out}
+}
+
+// Serve handles requests until the client disconnects or closes the stream.
+func (server *Server) Serve() error {
+	for {
+		request, err := readRequest(server.reader)
+
+		if errors.Is(err, io.EOF) {
+			server.terminate()
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		body, err := server.dispatch(request)
+
+		if request.Command == "disconnect" {
+			server.terminate()
+		}
+
+		if err := server.respond(request, body, err); err != nil {
+			return err
+		}
+
+		switch request.Command {
+		case "initialize":
+			// The client configures the breakpoints once told so.
+			if err := server.send("initialized", nil); err != nil {
+				return err
+			}
+
+		case "disconnect":
+			return nil
+		}
+	}
+}
+
+func (server *Server) write(msg interface{}) error {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	server.seq++
+
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = server.seq
+	case *event:
+		msg.Seq = server.seq
+	}
+
+	return writeMessage(server.writer, msg)
+}
+
+func (server *Server) respond(request *request, body interface{}, err error) error {
+	response := &response{Type: "response", RequestSeq: request.Seq, Success: err == nil, Command: request.Command, Body: body}
+
+	if err != nil {
+		response.Message = err.Error()
+	}
+
+	return server.write(response)
+}
+
+func (server *Server) send(name string, body interface{}) error {
+	return server.write(&event{Type: "event", Event: name, Body: body})
+}
+
+func arguments(request *request, arguments interface{}) error {
+	if len(request.Arguments) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(request.Arguments, arguments); err != nil {
+		return fmt.Errorf("invalid arguments for %s: %s", request.Command, err)
+	}
+
+	return nil
+}
+
+func (server *Server) dispatch(request *request) (interface{}, error) {
+	if server.debugger == nil {
+		switch request.Command {
+		case "initialize", "launch", "setBreakpoints", "disconnect":
+		default:
+			return nil, fmt.Errorf("%s before launch", request.Command)
+		}
+	}
+
+	switch request.Command {
+	case "initialize":
+		return Capabilities{
+			SupportsConfigurationDoneRequest: true,
+			SupportsConditionalBreakpoints:   true,
+			SupportsEvaluateForHovers:        true,
+			SupportsTerminateRequest:         true,
+		}, nil
+
+	case "launch":
+		var launch LaunchArguments
+
+		if err := arguments(request, &launch); err != nil {
+			return nil, err
+		}
+
+		return nil, server.launch(launch)
+
+	case "setBreakpoints":
+		var setBreakpoints SetBreakpointsArguments
+
+		if err := arguments(request, &setBreakpoints); err != nil {
+			return nil, err
+		}
+
+		return server.setBreakpoints(setBreakpoints), nil
+
+	case "configurationDone":
+		if server.exited == nil {
+			server.start()
+		}
+
+		return nil, nil
+
+	case "threads":
+		return ThreadsResponse{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+
+	case "stackTrace":
+		return server.stackTrace()
+
+	case "scopes":
+		var scopes ScopesArguments
+
+		if err := arguments(request, &scopes); err != nil {
+			return nil, err
+		}
+
+		return server.scopes(scopes.FrameID)
+
+	case "variables":
+		var variables VariablesArguments
+
+		if err := arguments(request, &variables); err != nil {
+			return nil, err
+		}
+
+		if variables.VariablesReference < 1 || variables.VariablesReference > len(server.variables) {
+			return nil, fmt.Errorf("unknown variables reference %d", variables.VariablesReference)
+		}
+
+		response := VariablesResponse{Variables: []Variable{}}
+
+		for _, variable := range server.variables[variables.VariablesReference-1] {
+			response.Variables = append(response.Variables, Variable{Name: variable.Name, Value: variable.Value.Inspect(), Type: string(variable.Value.Type())})
+		}
+
+		return response, nil
+
+	case "evaluate":
+		var evaluate EvaluateArguments
+
+		if err := arguments(request, &evaluate); err != nil {
+			return nil, err
+		}
+
+		return server.evaluate(evaluate)
+
+	case "continue":
+		return ContinueResponse{AllThreadsContinued: true}, server.debugger.Continue()
+
+	case "next":
+		return nil, server.debugger.Next()
+
+	case "stepIn":
+		return nil, server.debugger.StepIn()
+
+	case "stepOut":
+		return nil, server.debugger.StepOut()
+
+	case "pause":
+		server.debugger.Pause()
+		return nil, nil
+
+	case "terminate":
+		server.debugger.Terminate()
+		return nil, nil
+
+	case "disconnect":
+		return nil, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported request %s", request.Command)
+	}
+}
+
+func (server *Server) launch(launch LaunchArguments) error {
+	if server.debugger != nil {
+		return errors.New("a program is already launched")
+	}
+
+	source, err := os.ReadFile(launch.Program)
+
+	if err != nil {
+		return err
+	}
+
+	parser := parser.New(tokenizer.New(string(source)))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		return fmt.Errorf("%s has syntax errors:\n%s", launch.Program, strings.Join(parser.Errors, "\n"))
+	}
+
+	path, _ := filepath.Abs(launch.Program)
+
+	server.source = Source{Name: filepath.Base(path), Path: path}
+	server.debugger = debugger.New(program)
+	server.debugger.SetBreakpoints(server.breakpoints)
+	server.stopOnEntry = launch.StopOnEntry
+
+	return nil
+}
+
+func (server *Server) setBreakpoints(setBreakpoints SetBreakpointsArguments) SetBreakpointsResponse {
+	server.breakpoints = []debugger.Breakpoint{}
+
+	for _, breakpoint := range setBreakpoints.Breakpoints {
+		server.breakpoints = append(server.breakpoints, debugger.Breakpoint{Line: breakpoint.Line, Condition: breakpoint.Condition})
+	}
+
+	response := SetBreakpointsResponse{Breakpoints: []Breakpoint{}}
+	placed := server.breakpoints
+
+	// Before launch there's no program to place the breakpoints in yet.
+	if server.debugger != nil {
+		placed = server.debugger.SetBreakpoints(server.breakpoints)
+	}
+
+	for i, breakpoint := range placed {
+		response.Breakpoints = append(response.Breakpoints, Breakpoint{
+			ID:       i + 1,
+			Verified: breakpoint.Verified || server.debugger == nil,
+			Line:     breakpoint.Line,
+			Message:  breakpoint.Message,
+			Source:   &setBreakpoints.Source,
+		})
+	}
+
+	return response
+}
+
+// start runs the program, its events are forwarded until it exits.
+func (server *Server) start() {
+	server.exited = make(chan struct{})
+	server.debugger.Start(server.stopOnEntry)
+
+	go func() {
+		defer close(server.exited)
+
+		for event := range server.debugger.Events {
+			if event.Reason != debugger.ReasonExited {
+				server.send("stopped", StoppedEvent{Reason: string(event.Reason), ThreadID: threadID, AllThreadsStopped: true})
+				continue
+			}
+
+			exitCode := 0
+
+			if errorObject, ok := event.Result.(*object.ErrorObject); ok {
+				server.send("output", OutputEvent{Category: "stderr", Output: errorObject.Inspect() + "\n"})
+				exitCode = 1
+			}
+
+			server.send("exited", ExitedEvent{ExitCode: exitCode})
+			server.send("terminated", nil)
+		}
+	}()
+}
+
+// terminate ends the program, if it runs, and waits for its last events.
+func (server *Server) terminate() {
+	if server.exited == nil {
+		return
+	}
+
+	server.debugger.Terminate()
+	<-server.exited
+}
+
+func (server *Server) stackTrace() (interface{}, error) {
+	frames, err := server.debugger.Stack()
+
+	if err != nil {
+		return nil, err
+	}
+
+	server.frames = frames
+	server.variables = nil
+
+	response := StackTraceResponse{StackFrames: []StackFrame{}, TotalFrames: len(frames)}
+
+	for i, frame := range frames {
+		response.StackFrames = append(response.StackFrames, StackFrame{ID: i + 1, Name: frame.Name, Source: &server.source, Line: frame.Line, Column: frame.Column})
+	}
+
+	return response, nil
+}
+
+func (server *Server) scopes(frameID int) (interface{}, error) {
+	if frameID < 1 || frameID > len(server.frames) {
+		return nil, fmt.Errorf("unknown frame %d", frameID)
+	}
+
+	response := ScopesResponse{Scopes: []Scope{}}
+
+	for _, scope := range server.frames[frameID-1].Scopes {
+		server.variables = append(server.variables, scope.Variables)
+		response.Scopes = append(response.Scopes, Scope{Name: scope.Name, VariablesReference: len(server.variables)})
+	}
+
+	return response, nil
+}
+
+func (server *Server) evaluate(evaluate EvaluateArguments) (interface{}, error) {
+	frame := 0
+
+	if evaluate.FrameID > 0 {
+		frame = evaluate.FrameID - 1
+	}
+
+	result, err := server.debugger.Evaluate(evaluate.Expression, frame)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return EvaluateResponse{Result: result.Inspect(), Type: string(result.Type())}, nil
+}
@@ -0,0 +1,314 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// received is a response or an event read by the client.
+type received struct {
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client talks to a server running in the background, it keeps the events
+// arriving while it waits for a response.
+type client struct {
+	t      *testing.T
+	writer *io.PipeWriter
+	reader *bufio.Reader
+	seq    int
+	events []received
+	done   chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	client := &client{t: t, writer: clientOut, reader: bufio.NewReader(clientIn), done: make(chan error, 1)}
+
+	go func() {
+		client.done <- NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+
+	return client
+}
+
+// next reads the next message from the server.
+func (client *client) next() received {
+	var msg received
+	var length int
+
+	for {
+		line, err := client.reader.ReadString('\n')
+
+		if err != nil {
+			client.t.Fatalf("reading from the server: %s", err)
+		}
+
+		if line == "\r\n" {
+			break
+		}
+
+		if _, err := fmt.Sscanf(line, "Content-Length: %d", &length); err != nil {
+			client.t.Fatalf("unexpected header %q", line)
+		}
+	}
+
+	body := make([]byte, length)
+
+	if _, err := io.ReadFull(client.reader, body); err != nil {
+		client.t.Fatal(err)
+	}
+
+	if err := json.Unmarshal(body, &msg); err != nil {
+		client.t.Fatal(err)
+	}
+
+	return msg
+}
+
+// request sends a request and returns its response, decoding its body into
+// body.
+func (client *client) request(command string, arguments interface{}, body interface{}) received {
+	client.seq++
+
+	go writeMessage(client.writer, map[string]interface{}{"seq": client.seq, "type": "request", "command": command, "arguments": arguments})
+
+	for {
+		msg := client.next()
+
+		if msg.Type == "event" {
+			client.events = append(client.events, msg)
+			continue
+		}
+
+		if msg.RequestSeq != client.seq {
+			client.t.Fatalf("unexpected response %+v", msg)
+		}
+
+		if body != nil && msg.Success {
+			if err := json.Unmarshal(msg.Body, body); err != nil {
+				client.t.Fatalf("%s - %s in %s", command, err, msg.Body)
+			}
+		}
+
+		return msg
+	}
+}
+
+// succeed sends a request that must succeed.
+func (client *client) succeed(command string, arguments interface{}, body interface{}) {
+	if response := client.request(command, arguments, body); !response.Success {
+		client.t.Fatalf("%s failed: %s", command, response.Message)
+	}
+}
+
+// event waits for the event name, skipping the others.
+func (client *client) event(name string, body interface{}) {
+	for {
+		var msg received
+
+		if len(client.events) > 0 {
+			msg, client.events = client.events[0], client.events[1:]
+		} else {
+			msg = client.next()
+		}
+
+		if msg.Type == "event" && msg.Event == name {
+			if body != nil {
+				json.Unmarshal(msg.Body, body)
+			}
+
+			return
+		}
+	}
+}
+
+func (client *client) stopped(reason string) {
+	var stopped StoppedEvent
+	client.event("stopped", &stopped)
+
+	if stopped.Reason != reason {
+		client.t.Fatalf("expected to stop for %s, got %s", reason, stopped.Reason)
+	}
+}
+
+// location returns the name and line of the innermost frame.
+func (client *client) location() (string, int) {
+	var stackTrace StackTraceResponse
+	client.succeed("stackTrace", map[string]interface{}{"threadId": threadID}, &stackTrace)
+
+	return stackTrace.StackFrames[0].Name, stackTrace.StackFrames[0].Line
+}
+
+const source = `let add = function(a, b) {
+	let sum = a + b;
+	return sum;
+};
+let x = add(1, 2);
+let y = add(x, 3);
+y;
+`
+
+func launch(t *testing.T, source string, stopOnEntry bool, breakpoints ...SourceBreakpoint) (*client, string) {
+	path := filepath.Join(t.TempDir(), "main.monkey")
+
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client := newClient(t)
+
+	var capabilities Capabilities
+	client.succeed("initialize", map[string]interface{}{"adapterID": "monkey"}, &capabilities)
+
+	if !capabilities.SupportsConditionalBreakpoints {
+		t.Errorf("expected conditional breakpoints to be supported")
+	}
+
+	client.event("initialized", nil)
+	client.succeed("launch", LaunchArguments{Program: path, StopOnEntry: stopOnEntry}, nil)
+	client.succeed("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: breakpoints}, nil)
+	client.succeed("configurationDone", nil, nil)
+
+	return client, path
+}
+
+func (client *client) disconnect() {
+	client.succeed("disconnect", nil, nil)
+
+	if err := <-client.done; err != nil {
+		client.t.Errorf("server failed: %s", err)
+	}
+}
+
+func TestStepping(t *testing.T) {
+	client, _ := launch(t, source, true)
+	client.stopped("entry")
+
+	steps := []struct {
+		command string
+		name    string
+		line    int
+	}{
+		{"next", "main", 5},
+		{"stepIn", "add", 2},
+		{"next", "add", 3},
+		{"stepOut", "main", 6},
+	}
+
+	for _, step := range steps {
+		client.succeed(step.command, map[string]interface{}{"threadId": threadID}, nil)
+		client.stopped("step")
+
+		if name, line := client.location(); name != step.name || line != step.line {
+			t.Fatalf("after %s expected to be in %s on line %d, got %s on line %d", step.command, step.name, step.line, name, line)
+		}
+	}
+
+	client.succeed("continue", map[string]interface{}{"threadId": threadID}, nil)
+
+	var exited ExitedEvent
+	client.event("exited", &exited)
+	client.event("terminated", nil)
+
+	if exited.ExitCode != 0 {
+		t.Errorf("expected the program to succeed, got %d", exited.ExitCode)
+	}
+
+	client.disconnect()
+}
+
+func TestBreakpointsAndInspection(t *testing.T) {
+	client, path := launch(t, source, false, SourceBreakpoint{Line: 2, Condition: "a == 3"})
+	client.stopped("breakpoint")
+
+	var stackTrace StackTraceResponse
+	client.succeed("stackTrace", map[string]interface{}{"threadId": threadID}, &stackTrace)
+
+	if len(stackTrace.StackFrames) != 2 || stackTrace.StackFrames[1].Line != 6 || stackTrace.StackFrames[0].Source.Path != path {
+		t.Fatalf("unexpected stack %+v", stackTrace.StackFrames)
+	}
+
+	var scopes ScopesResponse
+	client.succeed("scopes", ScopesArguments{FrameID: stackTrace.StackFrames[0].ID}, &scopes)
+
+	if len(scopes.Scopes) != 2 || scopes.Scopes[0].Name != "Locals" || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("unexpected scopes %+v", scopes.Scopes)
+	}
+
+	var variables VariablesResponse
+	client.succeed("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}, &variables)
+
+	expected := []Variable{{Name: "a", Value: "3", Type: "Integer"}, {Name: "b", Value: "3", Type: "Integer"}}
+
+	if len(variables.Variables) != 2 || variables.Variables[0] != expected[0] || variables.Variables[1] != expected[1] {
+		t.Errorf("expected %+v, got %+v", expected, variables.Variables)
+	}
+
+	var evaluate EvaluateResponse
+	client.succeed("evaluate", EvaluateArguments{Expression: "add(a, x) * 2", FrameID: 1}, &evaluate)
+
+	if evaluate.Result != "12" {
+		t.Errorf("expected 12, got %s", evaluate.Result)
+	}
+
+	if response := client.request("evaluate", EvaluateArguments{Expression: "a", FrameID: 2}, nil); response.Success {
+		t.Errorf("expected a to be unknown in main")
+	}
+
+	client.disconnect()
+}
+
+func TestLaunchErrors(t *testing.T) {
+	client := newClient(t)
+	client.succeed("initialize", nil, nil)
+
+	if response := client.request("launch", LaunchArguments{Program: filepath.Join(t.TempDir(), "missing.monkey")}, nil); response.Success {
+		t.Errorf("expected launching a missing file to fail")
+	}
+
+	if response := client.request("stackTrace", nil, nil); response.Success {
+		t.Errorf("expected a stack trace before launch to fail")
+	}
+
+	client.disconnect()
+}
+
+func TestProgramError(t *testing.T) {
+	client, _ := launch(t, "let a = 1;\na + true;\n", false)
+
+	var output OutputEvent
+	client.event("output", &output)
+
+	var exited ExitedEvent
+	client.event("exited", &exited)
+
+	if exited.ExitCode != 1 || output.Category != "stderr" {
+		t.Errorf("expected the error to be reported, got %+v %+v", output, exited)
+	}
+
+	client.disconnect()
+}
+
+func TestRequestTooLarge(t *testing.T) {
+	reader := bufio.NewReader(strings.NewReader(fmt.Sprintf("Content-Length: %d\r\n\r\n", maxContentLength+1)))
+
+	if _, err := readRequest(reader); err == nil {
+		t.Errorf("expected a request over the limit to fail")
+	}
+}
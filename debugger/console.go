@@ -0,0 +1,454 @@
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/object"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const ConsolePrompt = "(monkey) "
+
+const consoleHelp = `break [file:]line [if condition]  stop on line, when condition holds
+delete [number]                   delete a breakpoint or watchpoint, or all of them
+watch name                        stop when the value of name changes
+run                               start the program
+continue                          resume until the next stop
+next                              step over calls
+step                              step into calls
+finish                            run until the current function returns
+print expression                  evaluate expression in the current frame
+locals                            show the variables of the current frame
+bt                                show the call stack
+quit                              leave the debugger
+`
+
+// point is a breakpoint or a watchpoint, they're numbered together. Line is
+// where the breakpoint was placed.
+type point struct {
+	number     int
+	breakpoint *Breakpoint
+	line       int
+	watch      string
+}
+
+// Console is a command line front-end in the manner of gdb, it reads
+// commands from a stream and writes what the program does to another.
+type Console struct {
+	path    string
+	lines   []string
+	program *ast.Program
+	out     io.Writer
+
+	debugger *Debugger
+	running  bool
+
+	points []point
+	number int
+	values int
+
+	// The innermost frame at the last stop, to tell when steps change it.
+	frame string
+	depth int
+}
+
+// NewConsole creates a console debugging program, parsed from source read
+// from path.
+func NewConsole(path string, source string, program *ast.Program, out io.Writer) *Console {
+	console := &Console{path: path, lines: strings.Split(source, "\n"), program: program, out: out}
+	console.reset()
+
+	return console
+}
+
+// reset prepares a new run of the program with the current breakpoints and
+// watchpoints.
+func (console *Console) reset() {
+	console.debugger = New(console.program)
+	console.running = false
+	console.frame, console.depth = "", 0
+
+	console.placeBreakpoints()
+
+	for _, point := range console.points {
+		if point.breakpoint == nil {
+			console.debugger.Watch(point.watch)
+		}
+	}
+}
+
+func (console *Console) placeBreakpoints() []Breakpoint {
+	breakpoints := []Breakpoint{}
+
+	for _, point := range console.points {
+		if point.breakpoint != nil {
+			breakpoints = append(breakpoints, *point.breakpoint)
+		}
+	}
+
+	return console.debugger.SetBreakpoints(breakpoints)
+}
+
+// Run reads and runs commands until the input ends or the user quits, an
+// empty line repeats the previous command.
+func (console *Console) Run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	previous := ""
+
+	for {
+		fmt.Fprint(console.out, ConsolePrompt)
+
+		if !scanner.Scan() {
+			fmt.Fprintln(console.out)
+			break
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			line = previous
+		}
+
+		previous = line
+
+		if line == "" {
+			continue
+		}
+
+		if !console.execute(line) {
+			break
+		}
+	}
+
+	if console.running {
+		console.debugger.Terminate()
+
+		for range console.debugger.Events {
+		}
+	}
+}
+
+// execute runs a command line, it returns false once the user quits.
+func (console *Console) execute(line string) bool {
+	command, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+
+	switch command {
+	case "break", "b":
+		console.setBreakpoint(argument)
+
+	case "delete", "d":
+		console.delete(argument)
+
+	case "watch":
+		console.watch(argument)
+
+	case "run", "r":
+		if console.running {
+			console.printf("The program is already running.\n")
+			break
+		}
+
+		console.running = true
+		console.debugger.Start(false)
+		console.wait()
+
+	case "continue", "c":
+		console.resume(console.debugger.Continue)
+
+	case "next", "n":
+		console.resume(console.debugger.Next)
+
+	case "step", "s":
+		console.resume(console.debugger.StepIn)
+
+	case "finish":
+		console.resume(console.debugger.StepOut)
+
+	case "print", "p":
+		console.print(argument)
+
+	case "locals":
+		console.locals()
+
+	case "bt", "backtrace", "where":
+		console.backtrace()
+
+	case "help", "h":
+		console.printf("%s", consoleHelp)
+
+	case "quit", "q":
+		return false
+
+	default:
+		console.printf("Undefined command: %q. Try \"help\".\n", command)
+	}
+
+	return true
+}
+
+func (console *Console) printf(format string, a ...interface{}) {
+	fmt.Fprintf(console.out, format, a...)
+}
+
+/* --- Breakpoints ---------------------------------------------------------- */
+
+func (console *Console) setBreakpoint(argument string) {
+	location, condition, _ := strings.Cut(argument, " if ")
+	location = strings.TrimSpace(location)
+
+	if file, line, ok := strings.Cut(location, ":"); ok {
+		if file != console.path && filepath.Base(file) != filepath.Base(console.path) {
+			console.printf("No source file named %s.\n", file)
+			return
+		}
+
+		location = line
+	}
+
+	line, err := strconv.Atoi(location)
+
+	if err != nil {
+		console.printf("Expected a line number, got %q.\n", location)
+		return
+	}
+
+	console.number++
+	console.points = append(console.points, point{number: console.number, breakpoint: &Breakpoint{Line: line, Condition: strings.TrimSpace(condition)}})
+
+	placed := console.placeBreakpoints()
+	breakpoint := placed[len(placed)-1]
+
+	if !breakpoint.Verified {
+		console.points = console.points[:len(console.points)-1]
+		console.number--
+		console.printf("Cannot set a breakpoint: %s.\n", breakpoint.Message)
+		console.placeBreakpoints()
+
+		return
+	}
+
+	console.points[len(console.points)-1].line = breakpoint.Line
+	console.printf("Breakpoint %d at %s:%d\n", console.number, filepath.Base(console.path), breakpoint.Line)
+}
+
+func (console *Console) delete(argument string) {
+	number, err := strconv.Atoi(argument)
+
+	if argument != "" && err != nil {
+		console.printf("Expected a breakpoint number, got %q.\n", argument)
+		return
+	}
+
+	kept := []point{}
+
+	for _, point := range console.points {
+		if argument != "" && point.number != number {
+			kept = append(kept, point)
+			continue
+		}
+
+		if point.breakpoint == nil {
+			if err := console.debugger.Unwatch(point.watch); err != nil {
+				console.printf("Cannot delete watchpoint %d: %s.\n", point.number, err)
+				kept = append(kept, point)
+			}
+		}
+	}
+
+	if argument != "" && len(kept) == len(console.points) {
+		console.printf("No breakpoint number %d.\n", number)
+		return
+	}
+
+	console.points = kept
+	console.placeBreakpoints()
+}
+
+func (console *Console) watch(name string) {
+	if name == "" {
+		console.printf("Expected a variable name.\n")
+		return
+	}
+
+	if err := console.debugger.Watch(name); err != nil {
+		console.printf("Cannot watch %s: %s.\n", name, err)
+		return
+	}
+
+	console.number++
+	console.points = append(console.points, point{number: console.number, watch: name})
+	console.printf("Watchpoint %d: %s\n", console.number, name)
+}
+
+/* --- Running -------------------------------------------------------------- */
+
+func (console *Console) resume(step func() error) {
+	if !console.running {
+		console.printf("The program is not being run.\n")
+		return
+	}
+
+	if err := step(); err != nil {
+		console.printf("%s.\n", err)
+		return
+	}
+
+	console.wait()
+}
+
+// wait waits for the program to stop or end and shows where it is.
+func (console *Console) wait() {
+	event := <-console.debugger.Events
+
+	if event.Reason == ReasonExited {
+		for range console.debugger.Events {
+		}
+
+		if errorObject, ok := event.Result.(*object.ErrorObject); ok {
+			console.printf("Program failed with %s\n", errorObject.Inspect())
+		} else if event.Result != nil {
+			console.printf("Program exited with %s\n", event.Result.Inspect())
+		} else {
+			console.printf("Program exited\n")
+		}
+
+		console.reset()
+
+		return
+	}
+
+	frames, err := console.debugger.Stack()
+
+	if err != nil {
+		console.printf("%s.\n", err)
+		return
+	}
+
+	location := fmt.Sprintf("%s at %s:%d", frames[0].Name, filepath.Base(console.path), event.Line)
+
+	switch event.Reason {
+	case ReasonBreakpoint:
+		console.printf("\nBreakpoint %d, %s\n", console.breakpointNumber(event.Line), location)
+
+	case ReasonWatch:
+		console.printf("\nWatchpoint %d: %s\n\nOld value = %s\nNew value = %s\n", console.watchNumber(event.Watch), event.Watch, show(event.Old), show(event.New))
+
+		if frames[0].Name != console.frame || len(frames) != console.depth {
+			console.printf("%s\n", location)
+		}
+
+	default:
+		if frames[0].Name != console.frame || len(frames) != console.depth {
+			console.printf("%s\n", location)
+		}
+	}
+
+	console.frame, console.depth = frames[0].Name, len(frames)
+
+	if event.Line >= 1 && event.Line <= len(console.lines) {
+		console.printf("%d\t%s\n", event.Line, console.lines[event.Line-1])
+	}
+}
+
+func (console *Console) breakpointNumber(line int) int {
+	for _, point := range console.points {
+		if point.breakpoint != nil && point.line == line {
+			return point.number
+		}
+	}
+
+	return 0
+}
+
+func (console *Console) watchNumber(name string) int {
+	for _, point := range console.points {
+		if point.breakpoint == nil && point.watch == name {
+			return point.number
+		}
+	}
+
+	return 0
+}
+
+func show(value object.Object) string {
+	if value == nil {
+		return "<unbound>"
+	}
+
+	return value.Inspect()
+}
+
+/* --- Inspection ----------------------------------------------------------- */
+
+func (console *Console) print(expression string) {
+	if !console.running {
+		console.printf("The program is not being run.\n")
+		return
+	}
+
+	if expression == "" {
+		console.printf("Expected an expression.\n")
+		return
+	}
+
+	result, err := console.debugger.Evaluate(expression, 0)
+
+	if err != nil {
+		console.printf("%s\n", err)
+		return
+	}
+
+	console.values++
+	console.printf("$%d = %s\n", console.values, result.Inspect())
+}
+
+func (console *Console) locals() {
+	frames, ok := console.stack()
+
+	if !ok {
+		return
+	}
+
+	scope := frames[0].Scopes[0]
+
+	if len(scope.Variables) == 0 {
+		console.printf("No locals.\n")
+	}
+
+	for _, variable := range scope.Variables {
+		console.printf("%s = %s\n", variable.Name, variable.Value.Inspect())
+	}
+}
+
+func (console *Console) backtrace() {
+	frames, ok := console.stack()
+
+	if !ok {
+		return
+	}
+
+	for i, frame := range frames {
+		console.printf("#%d  %s at %s:%d\n", i, frame.Name, filepath.Base(console.path), frame.Line)
+	}
+}
+
+func (console *Console) stack() ([]Frame, bool) {
+	if !console.running {
+		console.printf("The program is not being run.\n")
+		return nil, false
+	}
+
+	frames, err := console.debugger.Stack()
+
+	if err != nil {
+		console.printf("%s.\n", err)
+		return nil, false
+	}
+
+	return frames, true
+}
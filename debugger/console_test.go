@@ -0,0 +1,107 @@
+package debugger
+
+import (
+	"monkey/parser"
+	"monkey/tokenizer"
+	"strings"
+	"testing"
+)
+
+func runConsole(t *testing.T, source string, commands ...string) string {
+	parser := parser.New(tokenizer.New(source))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		t.Fatalf("parser errors %v", parser.Errors)
+	}
+
+	var out strings.Builder
+	NewConsole("/tmp/main.monkey", source, program, &out).Run(strings.NewReader(strings.Join(commands, "\n") + "\n"))
+
+	return strings.ReplaceAll(out.String(), ConsolePrompt, "")
+}
+
+func TestConsole(t *testing.T) {
+	tests := []struct {
+		commands []string
+		expected []string
+	}{
+		{
+			[]string{"break main.monkey:2 if a == 3", "run", "bt", "locals", "print a * 10", "finish", "", "continue"},
+			[]string{
+				"Breakpoint 1 at main.monkey:2",
+				"Breakpoint 1, add at main.monkey:2\n2\t\tlet sum = a + b;",
+				"#0  add at main.monkey:2\n#1  main at main.monkey:6",
+				"a = 3\nb = 3",
+				"$1 = 30",
+				"main at main.monkey:7\n7\ty;",
+				"Program exited with 6",
+			},
+		},
+		{
+			[]string{"break 4", "run", "step", "next", "next", "step"},
+			[]string{
+				"Breakpoint 1 at main.monkey:5",
+				"Breakpoint 1, main at main.monkey:5",
+				"add at main.monkey:2\n2\t\tlet sum = a + b;",
+				"3\t\treturn sum;",
+				"main at main.monkey:6",
+			},
+		},
+		{
+			[]string{"watch x", "run", "continue"},
+			[]string{
+				"Watchpoint 1: x",
+				"Watchpoint 1: x\n\nOld value = <unbound>\nNew value = 3\nmain at main.monkey:6",
+				"Program exited with 6",
+			},
+		},
+		{
+			[]string{"next", "print 1", "break other.monkey:2", "break 12", "break 2 if a )", "delete 3", "jump"},
+			[]string{
+				"The program is not being run.\nThe program is not being run.",
+				"No source file named other.monkey.",
+				"Cannot set a breakpoint: no statement on line 12 or after.",
+				"Cannot set a breakpoint:",
+				"No breakpoint number 3.",
+				`Undefined command: "jump".`,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		output := runConsole(t, source, test.commands...)
+
+		for _, expected := range test.expected {
+			if !strings.Contains(output, expected) {
+				t.Errorf("%v - expected the output to contain %q, got:\n%s", test.commands, expected, output)
+			}
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	debugger := start(t, "let i = 0;\nlet j = 0;\ni++;\nj++;\ni++;\n", true)
+	expectEvent(t, debugger, ReasonEntry, 1)
+
+	if err := debugger.Watch("i"); err == nil {
+		t.Errorf("expected i to be unknown before its let")
+	}
+
+	debugger.Next()
+	expectEvent(t, debugger, ReasonStep, 2)
+
+	if err := debugger.Watch("i"); err != nil {
+		t.Fatal(err)
+	}
+
+	debugger.Continue()
+
+	if event := expectEvent(t, debugger, ReasonWatch, 4); event.Watch != "i" || event.Old.Inspect() != "0" || event.New.Inspect() != "1" {
+		t.Errorf("unexpected watch event %+v", event)
+	}
+
+	debugger.Unwatch("i")
+	debugger.Continue()
+	expectEvent(t, debugger, ReasonExited, 0)
+}
@@ -0,0 +1,639 @@
+// Package debugger runs a program under the control of a debugger front-end:
+// it stops at breakpoints and after steps, and lets the front-end inspect the
+// call stack and evaluate expressions in a stopped frame.
+//
+// The program runs on its own goroutine. The front-end is told about stops
+// and the end of the program through Events, and drives it with the control
+// methods, which inspect the program only while it is stopped. Console is
+// such a front-end for the command line.
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrRunning    = errors.New("the program is running")
+	ErrTerminated = errors.New("the program was terminated")
+)
+
+// Reason tells why the program stopped.
+type Reason string
+
+const (
+	ReasonEntry      Reason = "entry"
+	ReasonBreakpoint Reason = "breakpoint"
+	ReasonStep       Reason = "step"
+	ReasonPause      Reason = "pause"
+	ReasonWatch      Reason = "watch"
+
+	// ReasonExited is the reason of the last event, once the program ended.
+	ReasonExited Reason = "exited"
+)
+
+// Event is sent when the program stops or ends, Result is the value of the
+// program once it ended. A watched variable that changed is reported with
+// its Old and New values, Old is nil if the variable wasn't bound yet.
+type Event struct {
+	Reason Reason
+	Line   int
+	Result object.Object
+
+	Watch    string
+	Old, New object.Object
+}
+
+// Runner evaluates program in env, notifying hook as it goes. The debugger
+// runs the tree-walking evaluator by default, any engine firing the same
+// hook can take its place.
+type Runner func(program *ast.Program, env *object.Environment, hook evaluator.Hook) object.Object
+
+func evaluatorRunner(program *ast.Program, env *object.Environment, hook evaluator.Hook) object.Object {
+	evaluator := evaluator.New()
+	evaluator.Hook = hook
+
+	return evaluator.Eval(program, env)
+}
+
+// Breakpoint stops the program before the first statement of a line, when
+// its condition, if any, is true.
+type Breakpoint struct {
+	Line      int
+	Condition string
+
+	// Verified is set when the breakpoint could be placed, Line is then
+	// moved to the first line with a statement. Message tells why it
+	// couldn't be placed.
+	Verified bool
+	Message  string
+
+	condition *ast.Program
+}
+
+// Frame is a snapshot of a function call of the stopped program.
+type Frame struct {
+	Name   string
+	Line   int
+	Column int
+	Scopes []Scope
+}
+
+// Scope holds the variables of one environment of a frame, from the
+// function's own to the global one.
+type Scope struct {
+	Name      string
+	Variables []Variable
+}
+
+type Variable struct {
+	Name  string
+	Value object.Object
+}
+
+// watch is a variable watched in the environment it was bound in when the
+// watch was set, the global one if it was set before the program started.
+type watch struct {
+	name        string
+	environment *object.Environment
+	value       object.Object
+}
+
+// frame is a function call of the running program.
+type frame struct {
+	name        string
+	statement   ast.Statement
+	environment *object.Environment
+}
+
+type stepMode int
+
+const (
+	stepNone stepMode = iota
+	stepIn
+	stepOver
+	stepOut
+)
+
+type Debugger struct {
+	// Runner runs the program, it can be replaced before Start.
+	Runner Runner
+
+	// Events receives a stopped event each time the program stops, and an
+	// exited event before it is closed.
+	Events chan Event
+
+	program *ast.Program
+
+	// firstStatements maps each line to the first statement starting on
+	// it, breakpoints stop there.
+	firstStatements map[int]ast.Statement
+
+	// Shared with the front-end.
+	mutex       sync.Mutex
+	breakpoints map[int]*Breakpoint
+	pause       bool
+	terminate   bool
+	stopped     bool
+
+	control  sync.Mutex
+	commands chan func() bool
+
+	// Owned by the front-end until Start.
+	started bool
+
+	// Owned by the program's goroutine.
+	frames  []*frame
+	watches []*watch
+	entry   bool
+	mode    stepMode
+	depth   int
+}
+
+// New creates a debugger for program, which isn't started yet.
+func New(program *ast.Program) *Debugger {
+	debugger := &Debugger{
+		Runner:          evaluatorRunner,
+		Events:          make(chan Event, 1),
+		program:         program,
+		firstStatements: map[int]ast.Statement{},
+		breakpoints:     map[int]*Breakpoint{},
+		commands:        make(chan func() bool),
+	}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if statement, ok := node.(ast.Statement); ok {
+			tok := ast.TokenOf(statement)
+
+			if first, ok := debugger.firstStatements[tok.Line]; !ok || ast.TokenOf(first).Column > tok.Column {
+				debugger.firstStatements[tok.Line] = statement
+			}
+		}
+
+		return true
+	})
+
+	return debugger
+}
+
+// SetBreakpoints replaces all the breakpoints and returns them as placed.
+func (debugger *Debugger) SetBreakpoints(breakpoints []Breakpoint) []Breakpoint {
+	lines := []int{}
+
+	for line := range debugger.firstStatements {
+		lines = append(lines, line)
+	}
+
+	sort.Ints(lines)
+
+	placed := map[int]*Breakpoint{}
+	result := []Breakpoint{}
+
+	for _, breakpoint := range breakpoints {
+		i := sort.SearchInts(lines, breakpoint.Line)
+
+		if i == len(lines) {
+			breakpoint.Message = fmt.Sprintf("no statement on line %d or after", breakpoint.Line)
+			result = append(result, breakpoint)
+			continue
+		}
+
+		breakpoint.Line = lines[i]
+
+		if breakpoint.Condition != "" {
+			condition, err := parse(breakpoint.Condition)
+
+			if err != nil {
+				breakpoint.Message = err.Error()
+				result = append(result, breakpoint)
+				continue
+			}
+
+			breakpoint.condition = condition
+		}
+
+		breakpoint.Verified = true
+		result = append(result, breakpoint)
+
+		stored := breakpoint
+		placed[breakpoint.Line] = &stored
+	}
+
+	debugger.mutex.Lock()
+	debugger.breakpoints = placed
+	debugger.mutex.Unlock()
+
+	return result
+}
+
+// Start runs the program in the background, stopping before its first
+// statement if stopOnEntry is set.
+func (debugger *Debugger) Start(stopOnEntry bool) {
+	env := object.NewEnvironment()
+	debugger.frames = []*frame{{name: "main", environment: env}}
+	debugger.started = true
+	debugger.entry = stopOnEntry
+
+	for _, watch := range debugger.watches {
+		watch.environment = env
+	}
+
+	go func() {
+		result := debugger.Runner(debugger.program, env, debugger)
+
+		debugger.Events <- Event{Reason: ReasonExited, Result: result}
+		close(debugger.Events)
+	}()
+}
+
+/* --- Control -------------------------------------------------------------- */
+
+// do runs command on the program's goroutine, the program must be stopped.
+// The program resumes if command returns true.
+func (debugger *Debugger) do(command func() bool) error {
+	debugger.control.Lock()
+	defer debugger.control.Unlock()
+
+	debugger.mutex.Lock()
+	stopped := debugger.stopped
+	debugger.mutex.Unlock()
+
+	if !stopped {
+		return ErrRunning
+	}
+
+	done := make(chan struct{})
+
+	debugger.commands <- func() bool {
+		defer close(done)
+
+		resume := command()
+
+		if resume {
+			debugger.mutex.Lock()
+			debugger.stopped = false
+			debugger.mutex.Unlock()
+		}
+
+		return resume
+	}
+
+	<-done
+
+	return nil
+}
+
+func (debugger *Debugger) resume(mode stepMode) error {
+	return debugger.do(func() bool {
+		debugger.mode = mode
+		debugger.depth = len(debugger.frames)
+
+		return true
+	})
+}
+
+// Continue resumes the program until the next breakpoint.
+func (debugger *Debugger) Continue() error {
+	return debugger.resume(stepNone)
+}
+
+// StepIn stops at the next statement, entering function calls.
+func (debugger *Debugger) StepIn() error {
+	return debugger.resume(stepIn)
+}
+
+// Next stops at the next statement of the current function or of its
+// callers.
+func (debugger *Debugger) Next() error {
+	return debugger.resume(stepOver)
+}
+
+// StepOut stops once the current function returned.
+func (debugger *Debugger) StepOut() error {
+	return debugger.resume(stepOut)
+}
+
+// Pause stops the running program before its next statement.
+func (debugger *Debugger) Pause() {
+	debugger.mutex.Lock()
+	debugger.pause = true
+	debugger.mutex.Unlock()
+}
+
+// Terminate ends the program before its next statement.
+func (debugger *Debugger) Terminate() {
+	debugger.mutex.Lock()
+	debugger.terminate = true
+	debugger.mutex.Unlock()
+
+	debugger.do(func() bool { return true })
+}
+
+// Watch stops the program when the value of the variable name changes. The
+// variable is looked up from the innermost frame of the stopped program, or
+// among the globals if the program isn't started yet.
+func (debugger *Debugger) Watch(name string) error {
+	if !debugger.started {
+		debugger.watches = append(debugger.watches, &watch{name: name})
+		return nil
+	}
+
+	var err error
+
+	if doErr := debugger.do(func() bool {
+		env := debugger.frames[len(debugger.frames)-1].environment
+		value, ok := env.Get(name)
+
+		if !ok {
+			err = fmt.Errorf("no variable %s in the current frame", name)
+			return false
+		}
+
+		debugger.watches = append(debugger.watches, &watch{name: name, environment: env, value: value})
+
+		return false
+	}); doErr != nil {
+		return doErr
+	}
+
+	return err
+}
+
+// Unwatch stops watching the variable name.
+func (debugger *Debugger) Unwatch(name string) error {
+	unwatch := func() bool {
+		kept := []*watch{}
+
+		for _, watch := range debugger.watches {
+			if watch.name != name {
+				kept = append(kept, watch)
+			}
+		}
+
+		debugger.watches = kept
+
+		return false
+	}
+
+	if !debugger.started {
+		unwatch()
+		return nil
+	}
+
+	return debugger.do(unwatch)
+}
+
+// Stack returns the frames of the stopped program, innermost first.
+func (debugger *Debugger) Stack() ([]Frame, error) {
+	frames := []Frame{}
+
+	err := debugger.do(func() bool {
+		for i := len(debugger.frames) - 1; i >= 0; i-- {
+			frames = append(frames, debugger.frames[i].snapshot())
+		}
+
+		return false
+	})
+
+	return frames, err
+}
+
+// Evaluate evaluates expression in a frame of the stopped program, frame
+// counts from the innermost one.
+func (debugger *Debugger) Evaluate(expression string, frame int) (object.Object, error) {
+	var result object.Object
+
+	err := debugger.do(func() bool {
+		program, err := parse(expression)
+
+		if err != nil {
+			result = &object.ErrorObject{Message: err.Error()}
+			return false
+		}
+
+		if frame < 0 || frame >= len(debugger.frames) {
+			result = &object.ErrorObject{Message: fmt.Sprintf("no frame %d", frame)}
+			return false
+		}
+
+		result = evaluate(program, debugger.frames[len(debugger.frames)-1-frame].environment)
+
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if errorObject, ok := result.(*object.ErrorObject); ok {
+		return nil, errors.New(errorObject.Message)
+	}
+
+	return result, nil
+}
+
+// parse parses an expression or statements typed by the user, who may leave
+// out the final semicolon.
+func parse(source string) (*ast.Program, error) {
+	if !strings.HasSuffix(strings.TrimSpace(source), ";") {
+		source += ";"
+	}
+
+	parser := parser.New(tokenizer.New(source))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		return nil, errors.New(strings.Join(parser.Errors, "\n"))
+	}
+
+	return program, nil
+}
+
+// evaluate evaluates program in env, out of reach of the hook.
+func evaluate(program *ast.Program, env *object.Environment) object.Object {
+	result := evaluator.Eval(program, env)
+
+	if result == nil {
+		return evaluator.Null
+	}
+
+	return result
+}
+
+func (frame *frame) snapshot() Frame {
+	snapshot := Frame{Name: frame.name}
+
+	if frame.statement != nil {
+		tok := ast.TokenOf(frame.statement)
+		snapshot.Line, snapshot.Column = tok.Line, tok.Column
+	}
+
+	for env := frame.environment; env != nil; env = env.Outer() {
+		scope := Scope{Name: "Closure"}
+
+		switch {
+		case env.Outer() == nil:
+			scope.Name = "Globals"
+		case env == frame.environment:
+			scope.Name = "Locals"
+		}
+
+		for _, name := range env.Names() {
+			value, _ := env.Get(name)
+			scope.Variables = append(scope.Variables, Variable{Name: name, Value: value})
+		}
+
+		snapshot.Scopes = append(snapshot.Scopes, scope)
+	}
+
+	return snapshot
+}
+
+/* --- Hook ----------------------------------------------------------------- */
+
+func (debugger *Debugger) BeforeStatement(statement ast.Statement, env *object.Environment) error {
+	current := debugger.frames[len(debugger.frames)-1]
+	current.statement = statement
+	current.environment = env
+
+	line := ast.TokenOf(statement).Line
+
+	debugger.mutex.Lock()
+	terminate, pause := debugger.terminate, debugger.pause
+	breakpoint := debugger.breakpoints[line]
+	debugger.pause = false
+	debugger.mutex.Unlock()
+
+	if terminate {
+		return ErrTerminated
+	}
+
+	changed, old := debugger.changedWatch()
+	reason := Reason("")
+
+	switch {
+	case debugger.entry:
+		reason = ReasonEntry
+		debugger.entry = false
+	case pause:
+		reason = ReasonPause
+	case breakpoint != nil && debugger.firstStatements[line] == statement && breakpoint.hit(env):
+		reason = ReasonBreakpoint
+	case changed != nil:
+		reason = ReasonWatch
+	case debugger.mode == stepIn,
+		debugger.mode == stepOver && len(debugger.frames) <= debugger.depth,
+		debugger.mode == stepOut && len(debugger.frames) < debugger.depth:
+		reason = ReasonStep
+	}
+
+	if reason == "" {
+		return nil
+	}
+
+	event := Event{Reason: reason, Line: line}
+
+	if reason == ReasonWatch {
+		event.Watch, event.Old, event.New = changed.name, old, changed.value
+	}
+
+	return debugger.stop(event)
+}
+
+// changedWatch returns the first watch whose variable changed since the
+// last statement and its previous value, all the watches are brought up to
+// date.
+func (debugger *Debugger) changedWatch() (*watch, object.Object) {
+	var changed *watch
+	var old object.Object
+
+	for _, watch := range debugger.watches {
+		value, _ := watch.environment.Get(watch.name)
+
+		if sameValue(watch.value, value) {
+			continue
+		}
+
+		if changed == nil {
+			changed, old = watch, watch.value
+		}
+
+		watch.value = value
+	}
+
+	return changed, old
+}
+
+// sameValue compares values the way they're shown, evaluating the same
+// literal twice gives two objects.
+func sameValue(a, b object.Object) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a == b || a.Type() == b.Type() && a.Inspect() == b.Inspect()
+}
+
+// stop tells the front-end the program stopped and runs its commands until
+// one resumes the program.
+func (debugger *Debugger) stop(event Event) error {
+	debugger.mode = stepNone
+
+	debugger.mutex.Lock()
+	debugger.stopped = true
+	debugger.mutex.Unlock()
+
+	debugger.Events <- event
+
+	for command := range debugger.commands {
+		if command() {
+			break
+		}
+	}
+
+	debugger.mutex.Lock()
+	defer debugger.mutex.Unlock()
+
+	if debugger.terminate {
+		return ErrTerminated
+	}
+
+	return nil
+}
+
+// hit tells whether the condition of breakpoint holds in env, a condition
+// that fails to evaluate counts as true so the failure can be looked into.
+func (breakpoint *Breakpoint) hit(env *object.Environment) bool {
+	if breakpoint.condition == nil {
+		return true
+	}
+
+	switch evaluate(breakpoint.condition, env) {
+	case evaluator.False, evaluator.Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func (debugger *Debugger) EnterFunction(call *ast.CallExpression, function *object.FunctionObject, env *object.Environment) {
+	name := "function"
+
+	if identifier, ok := call.Function.(*ast.IdentifierLiteral); ok {
+		name = identifier.Value
+	}
+
+	debugger.frames = append(debugger.frames, &frame{name: name, environment: env})
+}
+
+func (debugger *Debugger) LeaveFunction(call *ast.CallExpression, result object.Object) {
+	debugger.frames = debugger.frames[:len(debugger.frames)-1]
+}
@@ -0,0 +1,172 @@
+package debugger
+
+import (
+	"monkey/parser"
+	"monkey/tokenizer"
+	"testing"
+	"time"
+)
+
+const source = `let add = function(a, b) {
+	let sum = a + b;
+	return sum;
+};
+let x = add(1, 2);
+let y = add(x, 3);
+y;
+`
+
+func start(t *testing.T, source string, stopOnEntry bool, breakpoints ...Breakpoint) *Debugger {
+	parser := parser.New(tokenizer.New(source))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		t.Fatalf("parser errors %v", parser.Errors)
+	}
+
+	debugger := New(program)
+	debugger.SetBreakpoints(breakpoints)
+	debugger.Start(stopOnEntry)
+
+	return debugger
+}
+
+func expectEvent(t *testing.T, debugger *Debugger, reason Reason, line int) Event {
+	t.Helper()
+
+	select {
+	case event := <-debugger.Events:
+		if event.Reason != reason || event.Line != line {
+			t.Fatalf("expected to stop for %s on line %d, got %+v", reason, line, event)
+		}
+
+		return event
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s on line %d", reason, line)
+		return Event{}
+	}
+}
+
+func TestStepping(t *testing.T) {
+	debugger := start(t, source, true)
+
+	steps := []struct {
+		step   func() error
+		reason Reason
+		line   int
+	}{
+		{nil, ReasonEntry, 1},
+		{debugger.Next, ReasonStep, 5},
+		{debugger.StepIn, ReasonStep, 2},
+		{debugger.Next, ReasonStep, 3},
+		{debugger.StepOut, ReasonStep, 6},
+		{debugger.Next, ReasonStep, 7},
+	}
+
+	for _, step := range steps {
+		if step.step != nil {
+			if err := step.step(); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expectEvent(t, debugger, step.reason, step.line)
+	}
+
+	debugger.Continue()
+
+	if event := expectEvent(t, debugger, ReasonExited, 0); event.Result.Inspect() != "6" {
+		t.Errorf("expected the program to end with 6, got %s", event.Result.Inspect())
+	}
+}
+
+func TestBreakpoints(t *testing.T) {
+	debugger := start(t, source, false, Breakpoint{Line: 2, Condition: "a == 3"})
+
+	expectEvent(t, debugger, ReasonBreakpoint, 2)
+
+	frames, err := debugger.Stack()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(frames) != 2 || frames[0].Name != "add" || frames[0].Line != 2 || frames[1].Name != "main" || frames[1].Line != 6 {
+		t.Fatalf("unexpected stack %+v", frames)
+	}
+
+	locals := frames[0].Scopes[0]
+
+	if locals.Name != "Locals" || len(locals.Variables) != 2 || locals.Variables[0].Name != "a" || locals.Variables[0].Value.Inspect() != "3" {
+		t.Errorf("unexpected locals %+v", locals)
+	}
+
+	if globals := frames[0].Scopes[1]; globals.Name != "Globals" || len(globals.Variables) != 2 {
+		t.Errorf("unexpected globals %+v", globals)
+	}
+
+	evaluations := []struct {
+		expression string
+		frame      int
+		expected   string
+	}{
+		{"a * 10", 0, "30"},
+		{"add(x, x);", 1, "6"},
+		{"x == 3;", 1, "true"},
+	}
+
+	for _, evaluation := range evaluations {
+		result, err := debugger.Evaluate(evaluation.expression, evaluation.frame)
+
+		if err != nil || result.Inspect() != evaluation.expected {
+			t.Errorf("%s - expected %s, got %v %v", evaluation.expression, evaluation.expected, result, err)
+		}
+	}
+
+	if _, err := debugger.Evaluate("a;", 1); err == nil {
+		t.Errorf("expected a to be out of reach of main")
+	}
+
+	if _, err := debugger.Evaluate("let = 1;", 0); err == nil {
+		t.Errorf("expected a syntax error")
+	}
+
+	debugger.Continue()
+	expectEvent(t, debugger, ReasonExited, 0)
+
+	if _, err := debugger.Stack(); err != ErrRunning {
+		t.Errorf("expected ErrRunning once the program ended, got %v", err)
+	}
+}
+
+func TestSetBreakpoints(t *testing.T) {
+	debugger := New(parser.New(tokenizer.New(source)).Parse())
+
+	placed := debugger.SetBreakpoints([]Breakpoint{{Line: 4}, {Line: 10}, {Line: 2, Condition: "a )"}})
+
+	if !placed[0].Verified || placed[0].Line != 5 {
+		t.Errorf("expected the breakpoint to move to line 5, got %+v", placed[0])
+	}
+
+	if placed[1].Verified || placed[2].Verified || placed[2].Message == "" {
+		t.Errorf("expected the breakpoints to be rejected, got %+v", placed[1:])
+	}
+}
+
+func TestPauseAndTerminate(t *testing.T) {
+	debugger := start(t, "let i = 0;\nwhile (true) {\n\ti++;\n};", false)
+
+	debugger.Pause()
+
+	if event := <-debugger.Events; event.Reason != ReasonPause {
+		t.Fatalf("expected the program to pause, got %+v", event)
+	}
+
+	if frames, err := debugger.Stack(); err != nil || len(frames) != 1 {
+		t.Errorf("expected the paused program to be in main, got %+v %v", frames, err)
+	}
+
+	debugger.Terminate()
+	expectEvent(t, debugger, ReasonExited, 0)
+}
@@ -0,0 +1,229 @@
+package evaluator
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"monkey/object"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins are found when a name isn't bound in the environment, so programs
+// can shadow them.
+var builtins = map[string]*object.BuiltinObject{}
+
+func init() {
+	for name, function := range map[string]object.BuiltinFunction{
+		"puts":   builtinPuts,
+		"len":    builtinLen,
+		"int":    builtinInt,
+		"float":  builtinFloat,
+		"str":    builtinStr,
+		"keys":   builtinKeys,
+		"values": builtinValues,
+		"push":   builtinPush,
+	} {
+		builtins[name] = &object.BuiltinObject{Name: name, Function: function}
+	}
+}
+
+// BuiltinNames returns the names of the builtin functions, sorted.
+func BuiltinNames() []string {
+	names := make([]string, 0, len(builtins))
+
+	for name := range builtins {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// IsBuiltin tells whether name is a builtin function.
+func IsBuiltin(name string) bool {
+	_, ok := builtins[name]
+	return ok
+}
+
+func expectArguments(args []object.Object, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("wrong number of arguments: expected %d, got %d", count, len(args))
+	}
+
+	return nil
+}
+
+// toString is how values are written out, strings without their quotes.
+func toString(obj object.Object) string {
+	if str, ok := obj.(*object.StringObject); ok {
+		return str.Value
+	}
+
+	return obj.Inspect()
+}
+
+// builtinPuts writes its arguments separated by spaces on a line.
+func builtinPuts(out io.Writer, args []object.Object) (object.Object, error) {
+	parts := []string{}
+
+	for _, arg := range args {
+		parts = append(parts, toString(arg))
+	}
+
+	if _, err := fmt.Fprintln(out, strings.Join(parts, " ")); err != nil {
+		return nil, err
+	}
+
+	return Null, nil
+}
+
+// builtinLen returns the number of characters of a string, elements of an
+// array or pairs of a hash.
+func builtinLen(out io.Writer, args []object.Object) (object.Object, error) {
+	if err := expectArguments(args, 1); err != nil {
+		return nil, err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.StringObject:
+		return &object.IntegerObject{Value: int64(utf8.RuneCountInString(arg.Value))}, nil
+	case *object.ArrayObject:
+		return &object.IntegerObject{Value: int64(len(arg.Elements))}, nil
+	case *object.HashObject:
+		return &object.IntegerObject{Value: int64(len(arg.Pairs))}, nil
+	default:
+		return nil, fmt.Errorf("expected a String, an Array or a Hash, got %s", arg.Type())
+	}
+}
+
+// builtinInt converts strings and floats to integers, floats are truncated.
+// Strings are decimal, with an optional sign.
+func builtinInt(out io.Writer, args []object.Object) (object.Object, error) {
+	if err := expectArguments(args, 1); err != nil {
+		return nil, err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.IntegerObject, *object.BigIntegerObject:
+		return arg, nil
+	case *object.FloatObject:
+		if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+			return nil, fmt.Errorf("can't convert %s to an integer", arg.Inspect())
+		}
+
+		value, _ := big.NewFloat(arg.Value).Int(nil)
+		return object.NewInteger(value), nil
+	case *object.StringObject:
+		// Base 10, so zero-padded fields such as 010 aren't read as octal.
+		value, ok := new(big.Int).SetString(strings.TrimSpace(arg.Value), 10)
+
+		if !ok {
+			return nil, fmt.Errorf("invalid integer %s", arg.Inspect())
+		}
+
+		return object.NewInteger(value), nil
+	default:
+		return nil, fmt.Errorf("can't convert %s to an integer", arg.Type())
+	}
+}
+
+// builtinFloat converts strings and integers to floats.
+func builtinFloat(out io.Writer, args []object.Object) (object.Object, error) {
+	if err := expectArguments(args, 1); err != nil {
+		return nil, err
+	}
+
+	switch arg := args[0].(type) {
+	case *object.IntegerObject, *object.BigIntegerObject, *object.FloatObject:
+		return &object.FloatObject{Value: toFloat(arg)}, nil
+	case *object.StringObject:
+		value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s", arg.Inspect())
+		}
+
+		return &object.FloatObject{Value: value}, nil
+	default:
+		return nil, fmt.Errorf("can't convert %s to a float", arg.Type())
+	}
+}
+
+// builtinStr returns a value as puts would write it.
+func builtinStr(out io.Writer, args []object.Object) (object.Object, error) {
+	if err := expectArguments(args, 1); err != nil {
+		return nil, err
+	}
+
+	return &object.StringObject{Value: toString(args[0])}, nil
+}
+
+func expectHash(args []object.Object) (*object.HashObject, error) {
+	if err := expectArguments(args, 1); err != nil {
+		return nil, err
+	}
+
+	hash, ok := args[0].(*object.HashObject)
+
+	if !ok {
+		return nil, fmt.Errorf("expected a Hash, got %s", args[0].Type())
+	}
+
+	return hash, nil
+}
+
+// builtinKeys returns the keys of a hash in the order it prints them.
+func builtinKeys(out io.Writer, args []object.Object) (object.Object, error) {
+	hash, err := expectHash(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []object.Object{}
+
+	for _, pair := range hash.Sorted() {
+		keys = append(keys, pair.Key)
+	}
+
+	return &object.ArrayObject{Elements: keys}, nil
+}
+
+// builtinValues returns the values of a hash in the order of its keys.
+func builtinValues(out io.Writer, args []object.Object) (object.Object, error) {
+	hash, err := expectHash(args)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values := []object.Object{}
+
+	for _, pair := range hash.Sorted() {
+		values = append(values, pair.Value)
+	}
+
+	return &object.ArrayObject{Elements: values}, nil
+}
+
+// builtinPush returns a new array with value added at the end of array.
+func builtinPush(out io.Writer, args []object.Object) (object.Object, error) {
+	if err := expectArguments(args, 2); err != nil {
+		return nil, err
+	}
+
+	array, ok := args[0].(*object.ArrayObject)
+
+	if !ok {
+		return nil, fmt.Errorf("expected an Array, got %s", args[0].Type())
+	}
+
+	elements := append(append([]object.Object{}, array.Elements...), args[1])
+
+	return &object.ArrayObject{Elements: elements}, nil
+}
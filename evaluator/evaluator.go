@@ -1,10 +1,655 @@
 package evaluator
 
 import (
+	"fmt"
+	"io"
 	"monkey/ast"
 	"monkey/object"
+	"monkey/token"
+	"os"
+	"unicode/utf8"
 )
 
-func Eval(prog ast.Program) object.Object {
+var (
+	Null  = &object.NullObject{}
+	True  = &object.BooleanObject{Value: true}
+	False = &object.BooleanObject{Value: false}
+)
+
+type Evaluator struct {
+	// StrictIntegers makes integer overflows an error instead of promoting
+	// the result to a big integer.
+	StrictIntegers bool
+
+	// Hook, when set, is notified as the program runs.
+	Hook Hook
+
+	// Output is where puts writes, os.Stdout when nil.
+	Output io.Writer
+
+	// depth is the number of function calls being evaluated.
+	depth int
+}
+
+// maxCallDepth bounds the nesting of function calls, a runaway recursion
+// ends with an error rather than overflowing the stack.
+const maxCallDepth = 10000
+
+// Hook observes the evaluation of a program, debuggers build on it.
+type Hook interface {
+	// BeforeStatement is called before each statement is evaluated, with
+	// the environment it is evaluated in. Returning an error aborts the
+	// evaluation with that error.
+	BeforeStatement(statement ast.Statement, env *object.Environment) error
+
+	// EnterFunction and LeaveFunction surround each function call, env is
+	// the environment of the function body.
+	EnterFunction(call *ast.CallExpression, function *object.FunctionObject, env *object.Environment)
+	LeaveFunction(call *ast.CallExpression, result object.Object)
+}
+
+// BindingHook is a Hook also notified of each change of a binding, after
+// name is bound or assigned value in env. The identifier is where the
+// change happens.
+type BindingHook interface {
+	Hook
+	Bind(identifier *ast.IdentifierLiteral, value object.Object, env *object.Environment)
+}
+
+func New() *Evaluator {
+	return &Evaluator{}
+}
+
+// Eval evaluates node in env with the default evaluator settings.
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return New().Eval(node, env)
+}
+
+func (evaluator *Evaluator) Eval(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+
+	// Statements
+	case *ast.Program:
+		return evaluator.evalProgram(node, env)
+
+	case *ast.BlockStatement:
+		return evaluator.evalBlockStatement(node, env)
+
+	case *ast.ExpressionStatement:
+		return evaluator.Eval(node.Expression, env)
+
+	case *ast.LetStatement:
+		value := evaluator.Eval(node.Expression, env)
+
+		if isError(value) {
+			return value
+		}
+
+		env.Set(node.Identifier.Value, value)
+		evaluator.bind(node.Identifier, value, env)
+
+		return Null
+
+	case *ast.ReturnStatement:
+		value := evaluator.Eval(node.Expression, env)
+
+		if isError(value) {
+			return value
+		}
+
+		return &object.ReturnValueObject{Value: value}
+
+	// Literals
+	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			if evaluator.StrictIntegers {
+				return newError(node.Token, "integer overflow: literal %s doesn't fit in 64 bits", node.Token.Literal)
+			}
+
+			return &object.BigIntegerObject{Value: node.Big}
+		}
+
+		return &object.IntegerObject{Value: node.Value}
+
+	case *ast.FloatLiteral:
+		return &object.FloatObject{Value: node.Value}
+
+	case *ast.BooleanLiteral:
+		return nativeBoolToBooleanObject(node.Value)
+
+	case *ast.StringLiteral:
+		return &object.StringObject{Value: node.Value}
+
+	case *ast.ArrayLiteral:
+		elements := []object.Object{}
+
+		for _, element := range node.Elements {
+			value := evaluator.Eval(element, env)
+
+			if isError(value) {
+				return value
+			}
+
+			elements = append(elements, value)
+		}
+
+		return &object.ArrayObject{Elements: elements}
+
+	case *ast.HashLiteral:
+		return evaluator.evalHashLiteral(node, env)
+
+	case *ast.IdentifierLiteral:
+		return evaluator.evalIdentifier(node, env)
+
+	case *ast.FunctionLiteral:
+		return &object.FunctionObject{Parameters: node.Parameters, Body: node.Body, Environment: env}
+
+	// Expressions
+	case *ast.PrefixOperatorExpression:
+		if node.Token.Type == token.Increment || node.Token.Type == token.Decrement {
+			return evaluator.evalUpdateExpression(node.Token, node.Right, true, env)
+		}
+
+		right := evaluator.Eval(node.Right, env)
+
+		if isError(right) {
+			return right
+		}
+
+		return evaluator.evalPrefixOperatorExpression(node.Token, right)
+
+	case *ast.PostfixOperatorExpression:
+		return evaluator.evalUpdateExpression(node.Token, node.Left, false, env)
+
+	case *ast.InfixOperatorExpression:
+		if node.Token.Type == token.And || node.Token.Type == token.Or {
+			return evaluator.evalLogicExpression(node, env)
+		}
+
+		left := evaluator.Eval(node.Left, env)
+
+		if isError(left) {
+			return left
+		}
+
+		right := evaluator.Eval(node.Right, env)
+
+		if isError(right) {
+			return right
+		}
+
+		return evaluator.evalInfixOperatorExpression(node.Token, left, right)
+
+	case *ast.IfExpression:
+		return evaluator.evalIfExpression(node, env)
+
+	case *ast.WhileExpression:
+		return evaluator.evalWhileExpression(node, env)
+
+	case *ast.CallExpression:
+		return evaluator.evalCallExpression(node, env)
+
+	case *ast.IndexExpression:
+		left := evaluator.Eval(node.Left, env)
+
+		if isError(left) {
+			return left
+		}
+
+		index := evaluator.Eval(node.Index, env)
+
+		if isError(index) {
+			return index
+		}
+
+		return evalIndexExpression(node.Token, left, index)
+	}
+
+	return Null
+}
+
+func newError(tok token.Token, format string, args ...interface{}) *object.ErrorObject {
+	msg := fmt.Sprintf(format, args...)
+	return &object.ErrorObject{Message: fmt.Sprintf("Ln %d, Col %d: %s", tok.Line, tok.Column, msg)}
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ObjectError
+}
+
+func isTruthy(obj object.Object) bool {
+	switch obj {
+	case Null, False:
+		return false
+	default:
+		return true
+	}
+}
+
+func nativeBoolToBooleanObject(value bool) *object.BooleanObject {
+	if value {
+		return True
+	}
+
+	return False
+}
+
+/* --- Statements ----------------------------------------------------------- */
+
+func (evaluator *Evaluator) evalProgram(program *ast.Program, env *object.Environment) object.Object {
+	var result object.Object = Null
+
+	for _, statement := range program.Statements {
+		result = evaluator.evalStatement(statement, env)
+
+		switch result := result.(type) {
+		case *object.ReturnValueObject:
+			return result.Value
+		case *object.ErrorObject:
+			return result
+		}
+	}
+
+	return result
+}
+
+func (evaluator *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
+	var result object.Object = Null
+
+	for _, statement := range block.Statements {
+		result = evaluator.evalStatement(statement, env)
+
+		if result != nil {
+			if t := result.Type(); t == object.ObjectReturnValue || t == object.ObjectError {
+				return result
+			}
+		}
+	}
+
+	return result
+}
+
+func (evaluator *Evaluator) evalStatement(statement ast.Statement, env *object.Environment) object.Object {
+	if evaluator.Hook != nil {
+		if err := evaluator.Hook.BeforeStatement(statement, env); err != nil {
+			tok := token.Token{}
+
+			if statementToken := ast.TokenOf(statement); statementToken != nil {
+				tok = *statementToken
+			}
+
+			return newError(tok, "%s", err)
+		}
+	}
+
+	return evaluator.Eval(statement, env)
+}
+
+/* --- Expressions ---------------------------------------------------------- */
+
+// bind tells a BindingHook that identifier changed.
+func (evaluator *Evaluator) bind(identifier *ast.IdentifierLiteral, value object.Object, env *object.Environment) {
+	if hook, ok := evaluator.Hook.(BindingHook); ok {
+		hook.Bind(identifier, value, env)
+	}
+}
+
+func (evaluator *Evaluator) evalIdentifier(identifier *ast.IdentifierLiteral, env *object.Environment) object.Object {
+	if value, ok := env.Get(identifier.Value); ok {
+		return value
+	}
+
+	if builtin, ok := builtins[identifier.Value]; ok {
+		return builtin
+	}
+
+	return newError(identifier.Token, "identifier not found: %s", identifier.Value)
+}
+
+func (evaluator *Evaluator) evalPrefixOperatorExpression(operator token.Token, right object.Object) object.Object {
+	switch operator.Type {
+	case token.Not:
+		return nativeBoolToBooleanObject(!isTruthy(right))
+
+	case token.Minus, token.Plus:
+		switch right := right.(type) {
+		case *object.IntegerObject, *object.BigIntegerObject:
+			if operator.Type == token.Minus {
+				return evaluator.evalIntegerInfixOperatorExpression(operator, &object.IntegerObject{Value: 0}, right)
+			}
+
+			return right
+
+		case *object.FloatObject:
+			if operator.Type == token.Minus {
+				return &object.FloatObject{Value: -right.Value}
+			}
+
+			return right
+		}
+
+		return newError(operator, "unknown operator: %s%s", operator.Literal, right.Type())
+
+	default:
+		return newError(operator, "unknown operator: %s%s", operator.Literal, right.Type())
+	}
+}
+
+// evalUpdateExpression implements ++ and --, the target has to be an
+// identifier bound to an integer. The prefix form evaluates to the updated
+// value and the postfix form to the value before the update.
+func (evaluator *Evaluator) evalUpdateExpression(operator token.Token, target ast.Expression, prefix bool, env *object.Environment) object.Object {
+	identifier, ok := target.(*ast.IdentifierLiteral)
+
+	if !ok {
+		return newError(operator, "invalid operand for %s, expected an identifier", operator.Literal)
+	}
+
+	value := evaluator.evalIdentifier(identifier, env)
+
+	if isError(value) {
+		return value
+	}
+
+	if !isInteger(value) {
+		return newError(operator, "unknown operator: %s%s", operator.Literal, value.Type())
+	}
+
+	step := token.Token{Type: token.Plus, Literal: "+", Line: operator.Line, Column: operator.Column}
+
+	if operator.Type == token.Decrement {
+		step.Type, step.Literal = token.Minus, "-"
+	}
+
+	updated := evaluator.evalIntegerInfixOperatorExpression(step, value, &object.IntegerObject{Value: 1})
+
+	if isError(updated) {
+		return updated
+	}
+
+	env.Assign(identifier.Value, updated)
+	evaluator.bind(identifier, updated, env)
+
+	if prefix {
+		return updated
+	}
+
+	return value
+}
+
+func (evaluator *Evaluator) evalLogicExpression(expression *ast.InfixOperatorExpression, env *object.Environment) object.Object {
+	left := evaluator.Eval(expression.Left, env)
+
+	if isError(left) {
+		return left
+	}
+
+	if expression.Token.Type == token.And && !isTruthy(left) {
+		return False
+	}
+
+	if expression.Token.Type == token.Or && isTruthy(left) {
+		return True
+	}
+
+	right := evaluator.Eval(expression.Right, env)
+
+	if isError(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
+func (evaluator *Evaluator) evalInfixOperatorExpression(operator token.Token, left object.Object, right object.Object) object.Object {
+	switch {
+	case isInteger(left) && isInteger(right):
+		return evaluator.evalIntegerInfixOperatorExpression(operator, left, right)
+
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixOperatorExpression(operator, toFloat(left), toFloat(right))
+
+	case left.Type() == object.ObjectString && right.Type() == object.ObjectString:
+		return evalStringInfixOperatorExpression(operator, left.(*object.StringObject).Value, right.(*object.StringObject).Value)
+
+	case operator.Type == token.Equal:
+		return nativeBoolToBooleanObject(left == right)
+
+	case operator.Type == token.NotEqual:
+		return nativeBoolToBooleanObject(left != right)
+
+	case left.Type() != right.Type():
+		return newError(operator, "type mismatch: %s %s %s", left.Type(), operator.Literal, right.Type())
+
+	default:
+		return newError(operator, "unknown operator: %s %s %s", left.Type(), operator.Literal, right.Type())
+	}
+}
+
+func evalStringInfixOperatorExpression(operator token.Token, left string, right string) object.Object {
+	switch operator.Type {
+	case token.Plus:
+		return &object.StringObject{Value: left + right}
+	case token.Equal:
+		return nativeBoolToBooleanObject(left == right)
+	case token.NotEqual:
+		return nativeBoolToBooleanObject(left != right)
+	case token.LessThan:
+		return nativeBoolToBooleanObject(left < right)
+	case token.BiggerThan:
+		return nativeBoolToBooleanObject(left > right)
+	default:
+		return newError(operator, "unknown operator: %s %s %s", object.ObjectString, operator.Literal, object.ObjectString)
+	}
+}
+
+func (evaluator *Evaluator) evalHashLiteral(hash *ast.HashLiteral, env *object.Environment) object.Object {
+	evaluated := object.NewHash()
+
+	for i, keyExpression := range hash.Keys {
+		key := evaluator.Eval(keyExpression, env)
+
+		if isError(key) {
+			return key
+		}
+
+		hashable, ok := key.(object.Hashable)
+
+		if !ok {
+			tok := hash.Token
+
+			if keyToken := ast.TokenOf(keyExpression); keyToken != nil {
+				tok = *keyToken
+			}
+
+			return newError(tok, "unusable as hash key: %s", key.Type())
+		}
+
+		value := evaluator.Eval(hash.Values[i], env)
+
+		if isError(value) {
+			return value
+		}
+
+		evaluated.Pairs[hashable.HashKey()] = object.HashPair{Key: key, Value: value}
+	}
+
+	return evaluated
+}
+
+// evalIndexExpression indexes arrays by element, strings by character and
+// hashes by key, an index out of range or a missing key gives null.
+func evalIndexExpression(bracket token.Token, left object.Object, index object.Object) object.Object {
+	if hash, ok := left.(*object.HashObject); ok {
+		key, ok := index.(object.Hashable)
+
+		if !ok {
+			return newError(bracket, "unusable as hash key: %s", index.Type())
+		}
+
+		if pair, ok := hash.Pairs[key.HashKey()]; ok {
+			return pair.Value
+		}
+
+		return Null
+	}
+
+	position, ok := index.(*object.IntegerObject)
+
+	if !ok {
+		return newError(bracket, "index must be an Integer, got %s", index.Type())
+	}
+
+	switch left := left.(type) {
+	case *object.ArrayObject:
+		if position.Value < 0 || position.Value >= int64(len(left.Elements)) {
+			return Null
+		}
+
+		return left.Elements[position.Value]
+
+	case *object.StringObject:
+		if position.Value < 0 || position.Value >= int64(utf8.RuneCountInString(left.Value)) {
+			return Null
+		}
+
+		return &object.StringObject{Value: string([]rune(left.Value)[position.Value])}
+
+	default:
+		return newError(bracket, "index operator not supported: %s", left.Type())
+	}
+}
+
+func (evaluator *Evaluator) evalIfExpression(expression *ast.IfExpression, env *object.Environment) object.Object {
+	condition := evaluator.Eval(expression.Condition, env)
+
+	if isError(condition) {
+		return condition
+	}
+
+	if isTruthy(condition) {
+		return evaluator.Eval(expression.Consequence, env)
+	} else if expression.Alternative != nil {
+		return evaluator.Eval(expression.Alternative, env)
+	}
+
+	return Null
+}
+
+func (evaluator *Evaluator) evalWhileExpression(expression *ast.WhileExpression, env *object.Environment) object.Object {
+	var result object.Object = Null
+
+	for {
+		condition := evaluator.Eval(expression.Condition, env)
+
+		if isError(condition) {
+			return condition
+		}
+
+		if !isTruthy(condition) {
+			return result
+		}
+
+		result = evaluator.Eval(expression.Body, env)
+
+		if t := result.Type(); t == object.ObjectReturnValue || t == object.ObjectError {
+			return result
+		}
+	}
+}
+
+func (evaluator *Evaluator) evalCallExpression(call *ast.CallExpression, env *object.Environment) object.Object {
+	value := evaluator.Eval(call.Function, env)
+
+	if isError(value) {
+		return value
+	}
+
+	if builtin, ok := value.(*object.BuiltinObject); ok {
+		return evaluator.callBuiltin(call, builtin, env)
+	}
+
+	function, ok := value.(*object.FunctionObject)
+
+	if !ok {
+		return newError(call.Token, "not a function: %s", value.Type())
+	}
+
+	if len(call.Arguments) != len(function.Parameters) {
+		return newError(call.Token, "wrong number of arguments: expected %d, got %d", len(function.Parameters), len(call.Arguments))
+	}
+
+	if evaluator.depth >= maxCallDepth {
+		return newError(call.Token, "maximum call depth exceeded: %d nested calls", maxCallDepth)
+	}
+
+	evaluator.depth++
+	defer func() { evaluator.depth-- }()
+
+	functionEnv := object.NewEnclosedEnvironment(function.Environment)
+
+	for i, argument := range call.Arguments {
+		value := evaluator.Eval(argument, env)
+
+		if isError(value) {
+			return value
+		}
+
+		functionEnv.Set(function.Parameters[i].Value, value)
+	}
+
+	if evaluator.Hook != nil {
+		evaluator.Hook.EnterFunction(call, function, functionEnv)
+
+		for _, parameter := range function.Parameters {
+			value, _ := functionEnv.Get(parameter.Value)
+			evaluator.bind(parameter, value, functionEnv)
+		}
+	}
+
+	result := evaluator.Eval(function.Body, functionEnv)
+
+	if returnValue, ok := result.(*object.ReturnValueObject); ok {
+		result = returnValue.Value
+	}
+
+	if evaluator.Hook != nil {
+		evaluator.Hook.LeaveFunction(call, result)
+	}
+
+	return result
+}
+
+func (evaluator *Evaluator) callBuiltin(call *ast.CallExpression, builtin *object.BuiltinObject, env *object.Environment) object.Object {
+	args := []object.Object{}
+
+	for _, argument := range call.Arguments {
+		value := evaluator.Eval(argument, env)
+
+		if isError(value) {
+			return value
+		}
+
+		args = append(args, value)
+	}
+
+	var out io.Writer = os.Stdout
+
+	if evaluator.Output != nil {
+		out = evaluator.Output
+	}
+
+	result, err := builtin.Function(out, args)
+
+	if err != nil {
+		return newError(call.Token, "%s: %s", builtin.Name, err)
+	}
+
+	// Builtins such as int don't promote to big integers in strict mode
+	// either.
+	if big, ok := result.(*object.BigIntegerObject); ok && evaluator.StrictIntegers {
+		return newError(call.Token, "%s: integer overflow: %s doesn't fit in 64 bits", builtin.Name, big.Inspect())
+	}
 
+	return result
 }
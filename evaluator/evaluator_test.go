@@ -0,0 +1,305 @@
+package evaluator
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"monkey/ast"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"reflect"
+	"testing"
+)
+
+func TestEvalExpressions(t *testing.T) {
+	testEvalExpect(t, "1 + 2 * 3;", "7")
+	testEvalExpect(t, "(1 + 2) * 3;", "9")
+	testEvalExpect(t, "-5 + 10;", "5")
+	testEvalExpect(t, "1 < 2;", "true")
+	testEvalExpect(t, "1 == 2;", "false")
+	testEvalExpect(t, "not true;", "false")
+	testEvalExpect(t, "false or 1 > 0;", "true")
+	testEvalExpect(t, "true and false;", "false")
+	testEvalExpect(t, "let a = 5; let b = a * 2; b;", "10")
+	testEvalExpect(t, "if (1 > 2) { 10; } else { 20; };", "20")
+	testEvalExpect(t, "return 3; 4;", "3")
+
+	testEvalExpectError(t, "a;")
+	testEvalExpectError(t, "1 + true;")
+	testEvalExpectError(t, "1 / 0;")
+}
+
+func TestEvalNumbers(t *testing.T) {
+	testEvalExpect(t, "0xFF + 0b1;", "256")
+	testEvalExpect(t, "7 / 2;", "3")
+	testEvalExpect(t, "7.0 / 2;", "3.5")
+	testEvalExpect(t, "1 + 0.5;", "1.5")
+	testEvalExpect(t, "0.5 * 4;", "2.0")
+	testEvalExpect(t, "-1.5e3;", "-1500.0")
+	testEvalExpect(t, "1 < 1.5;", "true")
+	testEvalExpect(t, "2 == 2.0;", "true")
+
+	testEvalExpectError(t, "1.0 / 0;")
+	testEvalExpectError(t, "1.5 + true;")
+}
+
+func TestEvalBigIntegers(t *testing.T) {
+	testEvalExpect(t, "9223372036854775807 + 1;", "9223372036854775808")
+	testEvalExpect(t, "-9223372036854775807 - 2;", "-9223372036854775809")
+	testEvalExpect(t, "-(-9223372036854775807 - 1);", "9223372036854775808")
+	testEvalExpect(t, "4294967296 * 4294967296;", "18446744073709551616")
+	testEvalExpect(t, "2 ** 100;", "1267650600228229401496703205376")
+	testEvalExpect(t, "2 ** 10;", "1024")
+	testEvalExpect(t, "2 ** -1;", "0.5")
+	testEvalExpect(t, "(2 ** 64) / (2 ** 32);", "4294967296")
+	testEvalExpect(t, "(2 ** 64) - (2 ** 64) + 1;", "1")
+	testEvalExpect(t, "2 ** 64 > 9223372036854775807;", "true")
+	testEvalExpect(t, "2 ** 64 == 18446744073709551616;", "true")
+	testEvalExpect(t, "2 ** 64 * 0.5;", "9.223372036854776e+18")
+	testEvalExpect(t, "let a = 9223372036854775807; a++; a;", "9223372036854775808")
+
+	result := testEval(t, "(2 ** 64) / 4;")
+	if _, ok := result.(*object.IntegerObject); !ok {
+		t.Errorf("expected results that fit in 64 bits to be normalized, got %T", result)
+	}
+
+	big := testEval(t, "2 ** 64 + 1;").(object.Hashable)
+	same := testEval(t, "18446744073709551617;").(object.Hashable)
+	if big.HashKey() != same.HashKey() {
+		t.Errorf("expected equal big integers to have the same hash key")
+	}
+
+	collision := &object.IntegerObject{Value: int64(big.HashKey().Value)}
+	if big.HashKey() == collision.HashKey() {
+		t.Errorf("expected big integers not to share hash keys with integers")
+	}
+
+	small := testEval(t, "2 ** 64 - 2 ** 63 - 2 ** 63 + 7;").(object.Hashable)
+	if small.HashKey() != (&object.IntegerObject{Value: 7}).HashKey() {
+		t.Errorf("expected normalized big integers to hash like integers")
+	}
+}
+
+func TestEvalStrictIntegers(t *testing.T) {
+	tests := []string{
+		"9223372036854775807 + 1;",
+		"-9223372036854775807 - 2;",
+		"4294967296 * 4294967296;",
+		"2 ** 64;",
+		"9223372036854775808;",
+		"let a = 9223372036854775807; a++;",
+		`int("99999999999999999999");`,
+		"int(1e30);",
+	}
+
+	for _, input := range tests {
+		program := parser.New(tokenizer.New(input)).Parse()
+		evaluator := New()
+		evaluator.StrictIntegers = true
+
+		if result := evaluator.Eval(program, object.NewEnvironment()); !isError(result) {
+			t.Errorf("expected '%s' to overflow in strict mode, got '%s'", input, result.Inspect())
+		}
+	}
+}
+
+func TestEvalUpdateOperators(t *testing.T) {
+	testEvalExpect(t, "let a = 1; a++;", "1")
+	testEvalExpect(t, "let a = 1; a++; a;", "2")
+	testEvalExpect(t, "let a = 1; ++a;", "2")
+	testEvalExpect(t, "let a = 1; a--;", "1")
+	testEvalExpect(t, "let a = 1; --a;", "0")
+	testEvalExpect(t, "let a = 1; a++ + a;", "3")
+	testEvalExpect(t, "let i = 0; while (i < 5) { i++; }; i;", "5")
+
+	testEvalExpectError(t, "a++;")
+	testEvalExpectError(t, "let a = true; a++;")
+}
+
+func TestEvalCalls(t *testing.T) {
+	testEvalExpect(t, "let add = function(a, b) { return a + b; }; add(1, 2);", "3")
+	testEvalExpect(t, "let double = function(x) { x * 2; }; double(double(3));", "12")
+	testEvalExpect(t, "function(x) { x; }(5);", "5")
+	testEvalExpect(t, "let adder = function(x) { function(y) { x + y; }; }; adder(2)(3);", "5")
+	testEvalExpect(t, "let fact = function(n) { if (n < 2) { return 1; }; n * fact(n - 1); }; fact(10);", "3628800")
+	testEvalExpect(t, "let a = 1; let f = function() { a++; }; f(); f(); a;", "3")
+
+	testEvalExpectError(t, "let x = 1; x();")
+	testEvalExpectError(t, "let f = function(a) { a; }; f();")
+	testEvalExpectError(t, "let f = function(a) { a; }; f(b);")
+}
+
+func TestEvalCallDepth(t *testing.T) {
+	testEvalExpect(t, "let count = function(n) { if (n == 0) { return 0; }; 1 + count(n - 1); }; count(5000);", "5000")
+
+	program := parser.New(tokenizer.New("let f = function(n) { f(n + 1); };\nf(0);")).Parse()
+	evaluator := New()
+
+	for range 2 {
+		result := evaluator.Eval(program, object.NewEnvironment())
+
+		if expected := "error: Ln 1, Col 24: maximum call depth exceeded: 10000 nested calls"; result.Inspect() != expected {
+			t.Errorf("expected %q, got %q", expected, result.Inspect())
+		}
+	}
+}
+
+func TestEvalStringsAndArrays(t *testing.T) {
+	testEvalExpect(t, `"a" + "b";`, `"ab"`)
+	testEvalExpect(t, `"a" == "a";`, "true")
+	testEvalExpect(t, `"a" < "b";`, "true")
+	testEvalExpect(t, `[1, "a", [true]];`, `[1, "a", [true]]`)
+	testEvalExpect(t, `let a = [1, 2, 3]; a[0] + a[2];`, "4")
+	testEvalExpect(t, `[1, 2][2];`, "null")
+	testEvalExpect(t, `"héllo"[1];`, `"é"`)
+
+	testEvalExpectError(t, `"a" - "b";`)
+	testEvalExpectError(t, `"a" + 1;`)
+	testEvalExpectError(t, `[1]["a"];`)
+	testEvalExpectError(t, `1[0];`)
+}
+
+func TestEvalHashes(t *testing.T) {
+	testEvalExpect(t, `let h = {"b": 2, "a": 1, 3: true, false: "f"}; h;`, `{false: "f", 3: true, "a": 1, "b": 2}`)
+	testEvalExpect(t, `let h = {"a": {"b": [1, 2]}}; h["a"]["b"][1];`, "2")
+	testEvalExpect(t, `let k = "a"; ({"a" + "b": 1})[k + "b"];`, "1")
+	testEvalExpect(t, `let h = {1: "one", 2 ** 64: "big"}; [h[2], h[18446744073709551616]];`, `[null, "big"]`)
+	testEvalExpect(t, `let h = {"a": 1, "b": 2}; [keys(h), values(h), len(h)];`, `[["a", "b"], [1, 2], 2]`)
+	testEvalExpect(t, `let a = [1]; let b = push(a, 2); [a, b];`, `[[1], [1, 2]]`)
+	testEvalExpect(t, `let input = {"name": "a", "x": 1}; {"name": input["name"]};`, `{"name": "a"}`)
+	testEvalExpect(t, `let a = 1; {"a": a, "b": [1, {}]};`, `{"a": 1, "b": [1, {}]}`)
+	testEvalExpect(t, `{};`, `{}`)
+
+	testEvalExpectError(t, `let h = {[1]: 2};`)
+	testEvalExpectError(t, `let h = {1: 2}; h[[1]];`)
+	testEvalExpectError(t, `keys([1]);`)
+}
+
+func TestEvalBuiltins(t *testing.T) {
+	testEvalExpect(t, `len("héllo") + len([1, 2]);`, "7")
+	testEvalExpect(t, `int(" 42 ") + int(2.9) + int(0x10);`, "60")
+	testEvalExpect(t, `int("123456789012345678901234567890");`, "123456789012345678901234567890")
+	testEvalExpect(t, `[int("0042"), int("010"), int("09"), int("-007"), int("+5")];`, "[42, 10, 9, -7, 5]")
+	testEvalExpect(t, `float("1.5") + float(1);`, "2.5")
+	testEvalExpect(t, `str(1) + str("a") + str([1, "b"]);`, `"1a[1, \"b\"]"`)
+	testEvalExpect(t, `let len = function(x) { 0; }; len("abc");`, "0")
+
+	testEvalExpectError(t, `len(1);`)
+	testEvalExpectError(t, `len("a", "b");`)
+	testEvalExpectError(t, `int("x");`)
+	testEvalExpectError(t, `int("0x10");`)
+
+	var out bytes.Buffer
+	evaluator := New()
+	evaluator.Output = &out
+
+	program := parser.New(tokenizer.New(`puts("a", 1, [2.5, "b"]); puts();`)).Parse()
+
+	if result := evaluator.Eval(program, object.NewEnvironment()); result != Null {
+		t.Errorf("expected puts to return null, got %s", result.Inspect())
+	}
+
+	if expected := "a 1 [2.5, \"b\"]\n\n"; out.String() != expected {
+		t.Errorf("expected puts to write %q, got %q", expected, out.String())
+	}
+}
+
+// recorder is a Hook recording what it is notified of.
+type recorder struct {
+	events []string
+	abort  int
+}
+
+func (recorder *recorder) BeforeStatement(statement ast.Statement, env *object.Environment) error {
+	recorder.events = append(recorder.events, fmt.Sprintf("statement %d", ast.TokenOf(statement).Line))
+
+	if len(recorder.events) == recorder.abort {
+		return errors.New("aborted")
+	}
+
+	return nil
+}
+
+func (recorder *recorder) EnterFunction(call *ast.CallExpression, function *object.FunctionObject, env *object.Environment) {
+	value, _ := env.Get(function.Parameters[0].Value)
+	recorder.events = append(recorder.events, "enter "+value.Inspect())
+}
+
+func (recorder *recorder) LeaveFunction(call *ast.CallExpression, result object.Object) {
+	recorder.events = append(recorder.events, "leave "+result.Inspect())
+}
+
+func TestEvalHook(t *testing.T) {
+	input := "let f = function(x) {\nreturn x + 1;\n};\nf(1);"
+	program := parser.New(tokenizer.New(input)).Parse()
+
+	hook := &recorder{}
+	evaluator := New()
+	evaluator.Hook = hook
+	evaluator.Eval(program, object.NewEnvironment())
+
+	expected := []string{"statement 1", "statement 4", "enter 1", "statement 2", "leave 2"}
+
+	if !reflect.DeepEqual(hook.events, expected) {
+		t.Errorf("expected %v, got %v", expected, hook.events)
+	}
+
+	evaluator.Hook = &recorder{abort: 4}
+
+	if result := evaluator.Eval(program, object.NewEnvironment()); !isError(result) || result.Inspect() != "error: Ln 2, Col 1: aborted" {
+		t.Errorf("expected the hook to abort the program, got %s", result.Inspect())
+	}
+}
+
+// binder is a recorder also recording binding changes.
+type binder struct {
+	recorder
+}
+
+func (binder *binder) Bind(identifier *ast.IdentifierLiteral, value object.Object, env *object.Environment) {
+	binder.events = append(binder.events, fmt.Sprintf("bind %s %s", identifier.Value, value.Inspect()))
+}
+
+func TestEvalBindingHook(t *testing.T) {
+	input := "let f = function(x) {\nreturn x + 1;\n};\nlet y = f(1);\ny++;"
+	program := parser.New(tokenizer.New(input)).Parse()
+
+	hook := &binder{}
+	evaluator := New()
+	evaluator.Hook = hook
+	evaluator.Eval(program, object.NewEnvironment())
+
+	expected := []string{"statement 1", "bind f function(x) {return (x + 1);}", "statement 4", "enter 1", "bind x 1", "statement 2", "leave 2", "bind y 2", "statement 5", "bind y 3"}
+
+	if !reflect.DeepEqual(hook.events, expected) {
+		t.Errorf("expected %v, got %v", expected, hook.events)
+	}
+}
+
+func testEval(t *testing.T, input string) object.Object {
+	p := parser.New(tokenizer.New(input))
+	program := p.Parse()
+
+	if len(p.Errors) != 0 {
+		t.Fatalf("%q has parser errors: %v", input, p.Errors)
+	}
+
+	return Eval(program, object.NewEnvironment())
+}
+
+func testEvalExpect(t *testing.T, input string, output string) {
+	result := testEval(t, input)
+
+	if result.Inspect() != output {
+		t.Errorf("testEvalExpect failled expected '%s' to be '%s' got '%s'", input, output, result.Inspect())
+	}
+}
+
+func testEvalExpectError(t *testing.T, input string) {
+	result := testEval(t, input)
+
+	if !isError(result) {
+		t.Errorf("testEvalExpectError failled expected '%s' to be erroneous, got '%s'", input, result.Inspect())
+	}
+}
@@ -0,0 +1,218 @@
+package evaluator
+
+import (
+	"math"
+	"math/big"
+	"monkey/object"
+	"monkey/token"
+)
+
+func isInteger(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.IntegerObject, *object.BigIntegerObject:
+		return true
+	default:
+		return false
+	}
+}
+
+// Mixing integers and floats in an arithmetic or comparison operation
+// promotes the integer operand, the result is then a float.
+func isNumber(obj object.Object) bool {
+	return isInteger(obj) || obj.Type() == object.ObjectFloat
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.IntegerObject:
+		return float64(obj.Value)
+	case *object.BigIntegerObject:
+		value, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return value
+	case *object.FloatObject:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
+func toBig(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.IntegerObject:
+		return big.NewInt(obj.Value)
+	case *object.BigIntegerObject:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+/* --- Integers ------------------------------------------------------------- */
+
+// evalIntegerInfixOperatorExpression computes on int64 as long as the result
+// fits and falls back to math/big when it doesn't, unless StrictIntegers is
+// set. Results are always normalized back to an IntegerObject when possible.
+func (evaluator *Evaluator) evalIntegerInfixOperatorExpression(operator token.Token, left object.Object, right object.Object) object.Object {
+	if operator.Type == token.Slash && toBig(right).Sign() == 0 {
+		return newError(operator, "division by zero")
+	}
+
+	if operator.Type == token.Power && toBig(right).Sign() < 0 {
+		return evalFloatInfixOperatorExpression(operator, toFloat(left), toFloat(right))
+	}
+
+	leftInteger, leftOk := left.(*object.IntegerObject)
+	rightInteger, rightOk := right.(*object.IntegerObject)
+
+	if leftOk && rightOk {
+		if result, ok := evalInt64InfixOperator(operator, leftInteger.Value, rightInteger.Value); ok {
+			return result
+		}
+
+		if evaluator.StrictIntegers {
+			return newError(operator, "integer overflow: %d %s %d", leftInteger.Value, operator.Literal, rightInteger.Value)
+		}
+	}
+
+	return evalBigInfixOperator(operator, toBig(left), toBig(right))
+}
+
+// evalInt64InfixOperator returns false when the result overflows an int64.
+func evalInt64InfixOperator(operator token.Token, left int64, right int64) (object.Object, bool) {
+	switch operator.Type {
+	case token.Plus:
+		result := left + right
+
+		if (left > 0 && right > 0 && result < 0) || (left < 0 && right < 0 && result >= 0) {
+			return nil, false
+		}
+
+		return &object.IntegerObject{Value: result}, true
+	case token.Minus:
+		result := left - right
+
+		if (right > 0 && result > left) || (right < 0 && result < left) {
+			return nil, false
+		}
+
+		return &object.IntegerObject{Value: result}, true
+	case token.Asterisk:
+		result, ok := multiplyInt64(left, right)
+
+		if !ok {
+			return nil, false
+		}
+
+		return &object.IntegerObject{Value: result}, true
+	case token.Slash:
+		if left == math.MinInt64 && right == -1 {
+			return nil, false
+		}
+
+		return &object.IntegerObject{Value: left / right}, true
+	case token.Power:
+		result := int64(1)
+
+		for base, exponent := left, right; exponent > 0; exponent >>= 1 {
+			var ok bool
+
+			if exponent&1 == 1 {
+				if result, ok = multiplyInt64(result, base); !ok {
+					return nil, false
+				}
+			}
+
+			if exponent > 1 {
+				if base, ok = multiplyInt64(base, base); !ok {
+					return nil, false
+				}
+			}
+		}
+
+		return &object.IntegerObject{Value: result}, true
+	case token.LessThan:
+		return nativeBoolToBooleanObject(left < right), true
+	case token.BiggerThan:
+		return nativeBoolToBooleanObject(left > right), true
+	case token.Equal:
+		return nativeBoolToBooleanObject(left == right), true
+	case token.NotEqual:
+		return nativeBoolToBooleanObject(left != right), true
+	default:
+		return newError(operator, "unknown operator: %s %s %s", object.ObjectInteger, operator.Literal, object.ObjectInteger), true
+	}
+}
+
+func multiplyInt64(left int64, right int64) (int64, bool) {
+	if left == 0 || right == 0 {
+		return 0, true
+	}
+
+	result := left * right
+
+	if result/right != left || (left == -1 && right == math.MinInt64) || (right == -1 && left == math.MinInt64) {
+		return 0, false
+	}
+
+	return result, true
+}
+
+func evalBigInfixOperator(operator token.Token, left *big.Int, right *big.Int) object.Object {
+	switch operator.Type {
+	case token.Plus:
+		return object.NewInteger(new(big.Int).Add(left, right))
+	case token.Minus:
+		return object.NewInteger(new(big.Int).Sub(left, right))
+	case token.Asterisk:
+		return object.NewInteger(new(big.Int).Mul(left, right))
+	case token.Slash:
+		return object.NewInteger(new(big.Int).Quo(left, right))
+	case token.Power:
+		if !right.IsInt64() || right.BitLen() > 32 {
+			return newError(operator, "exponent %s is too large", right.String())
+		}
+
+		return object.NewInteger(new(big.Int).Exp(left, right, nil))
+	case token.LessThan:
+		return nativeBoolToBooleanObject(left.Cmp(right) < 0)
+	case token.BiggerThan:
+		return nativeBoolToBooleanObject(left.Cmp(right) > 0)
+	case token.Equal:
+		return nativeBoolToBooleanObject(left.Cmp(right) == 0)
+	case token.NotEqual:
+		return nativeBoolToBooleanObject(left.Cmp(right) != 0)
+	default:
+		return newError(operator, "unknown operator: %s %s %s", object.ObjectInteger, operator.Literal, object.ObjectInteger)
+	}
+}
+
+/* --- Floats --------------------------------------------------------------- */
+
+func evalFloatInfixOperatorExpression(operator token.Token, left float64, right float64) object.Object {
+	switch operator.Type {
+	case token.Plus:
+		return &object.FloatObject{Value: left + right}
+	case token.Minus:
+		return &object.FloatObject{Value: left - right}
+	case token.Asterisk:
+		return &object.FloatObject{Value: left * right}
+	case token.Slash:
+		if right == 0 {
+			return newError(operator, "division by zero")
+		}
+
+		return &object.FloatObject{Value: left / right}
+	case token.Power:
+		return &object.FloatObject{Value: math.Pow(left, right)}
+	case token.LessThan:
+		return nativeBoolToBooleanObject(left < right)
+	case token.BiggerThan:
+		return nativeBoolToBooleanObject(left > right)
+	case token.Equal:
+		return nativeBoolToBooleanObject(left == right)
+	case token.NotEqual:
+		return nativeBoolToBooleanObject(left != right)
+	default:
+		return newError(operator, "unknown operator: %s %s %s", object.ObjectFloat, operator.Literal, object.ObjectFloat)
+	}
+}
@@ -0,0 +1,211 @@
+// Package format lays out monkey source code in its canonical style: one
+// statement per line, blocks indented with tabs and single spaces around
+// binary operators. Comments are kept, and so are single blank lines between
+// statements.
+package format
+
+import (
+	"errors"
+	"monkey/cst"
+	"monkey/token"
+	"monkey/tokenizer"
+	"strings"
+)
+
+// Source formats source code, it fails when the code has syntax errors.
+func Source(source string) (string, error) {
+	parser := cst.New(tokenizer.New(source))
+	tree := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		return "", errors.New(parser.Errors[0])
+	}
+
+	return Node(tree), nil
+}
+
+// Node formats a concrete syntax tree, which must be free of errors.
+func Node(node *cst.Node) string {
+	printer := &printer{}
+
+	printer.element(node)
+
+	return printer.out.String()
+}
+
+type printer struct {
+	out strings.Builder
+
+	indent    int
+	lineStart bool
+	last      string
+
+	// The next token goes on a new line, or after a space.
+	breakLine    bool
+	pendingSpace bool
+}
+
+func (printer *printer) write(text string) {
+	if printer.lineStart {
+		printer.out.WriteString(strings.Repeat("\t", printer.indent))
+	} else if printer.pendingSpace && printer.out.Len() > 0 {
+		printer.out.WriteByte(' ')
+	}
+
+	printer.out.WriteString(text)
+	printer.lineStart = false
+	printer.pendingSpace = false
+	printer.last = text
+}
+
+func (printer *printer) newline() {
+	printer.out.WriteByte('\n')
+	printer.lineStart = true
+	printer.pendingSpace = false
+}
+
+// blankLineAllowed tells whether a blank line of the source may be kept here,
+// blocks don't start or end with one.
+func (printer *printer) blankLineAllowed(tok *cst.Token) bool {
+	return printer.out.Len() > 0 && printer.last != "{" && tok.Type != token.ClosingBrace
+}
+
+// comments writes the comments before tok. A comment on the line of the
+// previous token stays at its end, the others go on lines of their own.
+func (printer *printer) comments(tok *cst.Token, ownLine bool) {
+	newlines := 0
+
+	for _, trivia := range tok.Leading {
+		if trivia.Type == token.Whitespace {
+			newlines += strings.Count(trivia.Literal, "\n")
+			continue
+		}
+
+		comment := strings.TrimRight(trivia.Literal, " \t\r\v\f")
+
+		if newlines > 0 || printer.lineStart || printer.out.Len() == 0 {
+			if !printer.lineStart && printer.out.Len() > 0 {
+				printer.newline()
+			}
+
+			if newlines > 1 && printer.blankLineAllowed(tok) {
+				printer.newline()
+			}
+		} else {
+			printer.pendingSpace = true
+		}
+
+		printer.write(comment)
+		printer.newline()
+
+		newlines = 0
+	}
+
+	if !ownLine {
+		return
+	}
+
+	if !printer.lineStart && printer.out.Len() > 0 {
+		printer.newline()
+	}
+
+	if newlines > 1 && tok.Type != token.EOF && printer.blankLineAllowed(tok) {
+		printer.newline()
+	}
+}
+
+func (printer *printer) token(tok *cst.Token) {
+	printer.comments(tok, printer.breakLine)
+	printer.breakLine = false
+
+	printer.write(tok.Literal)
+}
+
+func (printer *printer) element(element cst.Element) {
+	switch element := element.(type) {
+	case *cst.Token:
+		printer.token(element)
+	case *cst.Node:
+		printer.node(element)
+	}
+}
+
+func (printer *printer) node(node *cst.Node) {
+	switch node.Kind {
+	case cst.Program:
+		for _, child := range node.Children {
+			if tok, ok := child.(*cst.Token); ok && tok.Type == token.EOF {
+				printer.comments(tok, false)
+				break
+			}
+
+			printer.breakLine = true
+			printer.element(child)
+		}
+
+		if !printer.lineStart && printer.out.Len() > 0 {
+			printer.newline()
+		}
+
+	case cst.BlockStatement:
+		last := len(node.Children) - 1
+		closing := node.Children[last].(*cst.Token)
+
+		printer.element(node.Children[0])
+		printer.indent++
+
+		for _, child := range node.Children[1:last] {
+			printer.breakLine = true
+			printer.element(child)
+		}
+
+		printer.comments(closing, last > 1)
+		printer.indent--
+		printer.write(closing.Literal)
+
+	case cst.PrefixExpression:
+		operator := node.Children[0].(*cst.Token)
+		printer.token(operator)
+
+		if len(node.Children) > 1 {
+			// not is a word, and - -a must not become --a.
+			operand := node.Children[1].(*cst.Node).FirstToken().Literal
+			printer.pendingSpace = operator.Type == token.Not || (operator.Literal == "-" || operator.Literal == "+") && strings.HasPrefix(operand, operator.Literal)
+
+			printer.element(node.Children[1])
+		}
+
+	default:
+		for i, child := range node.Children {
+			if i > 0 && spaceBefore(node.Kind, node.Children[i-1], child) {
+				printer.pendingSpace = true
+			}
+
+			printer.element(child)
+		}
+	}
+}
+
+// spaceBefore tells whether child is written after a space in a node of kind,
+// previous is the child before it.
+func spaceBefore(kind cst.Kind, previous cst.Element, child cst.Element) bool {
+	childToken, isToken := child.(*cst.Token)
+	childNode, isNode := child.(*cst.Node)
+	previousToken, _ := previous.(*cst.Token)
+
+	switch kind {
+	case cst.LetStatement, cst.ReturnStatement:
+		return !isToken || childToken.Type != token.Semicolon
+	case cst.InfixExpression:
+		return true
+	case cst.IfExpression, cst.WhileExpression, cst.ElseClause, cst.FunctionLiteral:
+		return isToken && childToken.Type == token.OpeningParenthesis && kind != cst.FunctionLiteral ||
+			isNode && (childNode.Kind == cst.BlockStatement || childNode.Kind == cst.ElseClause)
+	case cst.ParameterList, cst.ArgumentList, cst.ArrayLiteral:
+		return previousToken != nil && previousToken.Type == token.Comma
+	case cst.HashLiteral:
+		return previousToken != nil && (previousToken.Type == token.Comma || previousToken.Type == token.Colon)
+	default:
+		return false
+	}
+}
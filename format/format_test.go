@@ -0,0 +1,89 @@
+package format
+
+import (
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let   a=1 ;", "let a = 1;\n"},
+		{"let f=function(a,b){return a+b;};", "let f = function(a, b) {\n\treturn a + b;\n};\n"},
+		{"if(a){b;}else{c;};while(x<1){x++;};", "if (a) {\n\tb;\n} else {\n\tc;\n};\nwhile (x < 1) {\n\tx++;\n};\n"},
+		{"let g = function() {};", "let g = function() {};\n"},
+		{"f( a ,g ( ) );", "f(a, g());\n"},
+		{"let a=[ 1 ,\"b\" ,[ ] ] ;a [0];", "let a = [1, \"b\", []];\na[0];\n"},
+		{"let h={ \"a\" :1,\"b\":{ } };", "let h = {\"a\": 1, \"b\": {}};\n"},
+		{"- -a; - --a; not(a) ; +-b;", "- -a;\n- --a;\nnot (a);\n+-b;\n"},
+		{"return;\n\n\n\nreturn 1;", "return;\n\nreturn 1;\n"},
+		{"a; // trailing\n// own line\nb;", "a; // trailing\n// own line\nb;\n"},
+		{"if (a) {\n\n\tb;   // why\n\n} ;", "if (a) {\n\tb; // why\n};\n"},
+		{"let f = function() {\n// nothing yet\n};", "let f = function() {\n\t// nothing yet\n};\n"},
+		{"a;\n\n// the end\n", "a;\n\n// the end\n"},
+		{"let x = 1 + // one\n2;", "let x = 1 + // one\n2;\n"},
+		{"", ""},
+	}
+
+	for _, test := range tests {
+		actual, err := Source(test.input)
+
+		if err != nil {
+			t.Errorf("%q - unexpected error %s", test.input, err)
+			continue
+		}
+
+		if actual != test.expected {
+			t.Errorf("%q - expected\n%q\ngot\n%q", test.input, test.expected, actual)
+		}
+	}
+}
+
+func TestSourceWithErrors(t *testing.T) {
+	if _, err := Source("let = 1;"); err == nil {
+		t.Errorf("expected an error for invalid code")
+	}
+}
+
+// Formatting must not change what a program means, and formatting twice
+// changes nothing more.
+func TestSourceKeepsMeaning(t *testing.T) {
+	files, _ := filepath.Glob(filepath.Join("..", "parser", "testdata", "*.monkey"))
+
+	for _, file := range files {
+		source, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		formatted, err := Source(string(source))
+		if err != nil {
+			t.Fatalf("%s - %s", file, err)
+		}
+
+		if expected, actual := ast.SExpr(parse(t, string(source))), ast.SExpr(parse(t, formatted)); expected != actual {
+			t.Errorf("%s - formatting changed the program\n%s\n%s", file, expected, actual)
+		}
+
+		if again, _ := Source(formatted); again != formatted {
+			t.Errorf("%s - formatting is not idempotent\n%s\n%s", file, formatted, again)
+		}
+	}
+}
+
+func parse(t *testing.T, source string) *ast.Program {
+	parser := parser.New(tokenizer.New(source))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		t.Fatalf("parser errors %v", parser.Errors)
+	}
+
+	return program
+}
@@ -0,0 +1,10 @@
+module monkey
+
+go 1.25.0
+
+require (
+	golang.org/x/term v0.45.0
+	golang.org/x/text v0.40.0
+)
+
+require golang.org/x/sys v0.47.0 // indirect
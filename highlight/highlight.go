@@ -0,0 +1,173 @@
+// Package highlight classifies the tokens of monkey source code and renders
+// the source with them coloured, for terminals or web pages.
+package highlight
+
+import (
+	"fmt"
+	"html"
+	"monkey/token"
+	"monkey/tokenizer"
+	"strings"
+)
+
+// Class is the kind of a piece of source code, it decides its colour.
+type Class string
+
+const (
+	Plain      Class = "plain"
+	Keyword    Class = "keyword"
+	Literal    Class = "literal"
+	Operator   Class = "operator"
+	Identifier Class = "identifier"
+	Comment    Class = "comment"
+	Error      Class = "error"
+)
+
+// Span is a piece of source code of a single class.
+type Span struct {
+	Class Class
+	Text  string
+}
+
+// Classify returns the class of a token, whitespace is Plain.
+func Classify(tok token.Token) Class {
+	switch tok.Type {
+	case token.Whitespace, token.EOF:
+		return Plain
+	case token.Comment:
+		return Comment
+	case token.Identifier:
+		return Identifier
+	case token.Integer, token.Float, token.String, token.True, token.False:
+		return Literal
+	case token.Illegal:
+		return Error
+	}
+
+	if _, ok := token.Keywords[tok.Literal]; ok {
+		return Keyword
+	}
+
+	return Operator
+}
+
+// Spans splits source into spans, their texts put back together give the
+// source.
+func Spans(source string) []Span {
+	tok := tokenizer.New(source)
+	spans := []Span{}
+
+	for t := tok.Scan(); t.Type != token.EOF; t = tok.Scan() {
+		class := Classify(t)
+
+		// Neighbours of the same class are merged, ++ or a run of spaces.
+		if last := len(spans) - 1; last >= 0 && spans[last].Class == class && class != Identifier && class != Keyword && class != Literal {
+			spans[last].Text += t.Literal
+			continue
+		}
+
+		spans = append(spans, Span{Class: class, Text: t.Literal})
+	}
+
+	return spans
+}
+
+/* --- ANSI ----------------------------------------------------------------- */
+
+var colours = map[Class]string{
+	Keyword:  "\033[35m",
+	Literal:  "\033[36m",
+	Operator: "\033[33m",
+	Comment:  "\033[90m",
+	Error:    "\033[31;4m",
+}
+
+// ANSI renders source with the escape codes colouring text in terminals.
+// It is as wide as source once displayed.
+func ANSI(source string) string {
+	var out strings.Builder
+
+	for _, span := range Spans(source) {
+		colour, ok := colours[span.Class]
+
+		if !ok {
+			out.WriteString(span.Text)
+			continue
+		}
+
+		out.WriteString(colour + span.Text + "\033[0m")
+	}
+
+	return out.String()
+}
+
+/* --- HTML ----------------------------------------------------------------- */
+
+// HTML renders source as HTML, each line is a span with the id L<number>
+// starting with a link to itself, and the spans of code have the class of
+// their Class. The result goes in a pre element.
+func HTML(source string) string {
+	var out strings.Builder
+
+	// The last line ends with the source rather than start an empty one.
+	source = strings.TrimSuffix(source, "\n")
+
+	number := 1
+	startLine := func() {
+		fmt.Fprintf(&out, `<span class="line" id="L%d"><a class="number" href="#L%d">%d</a>`, number, number, number)
+	}
+
+	startLine()
+
+	for _, span := range Spans(source) {
+		for i, text := range strings.Split(span.Text, "\n") {
+			if i > 0 {
+				out.WriteString("</span>\n")
+				number++
+				startLine()
+			}
+
+			if text == "" {
+				continue
+			}
+
+			if span.Class == Plain {
+				out.WriteString(html.EscapeString(text))
+			} else {
+				fmt.Fprintf(&out, `<span class="%s">%s</span>`, span.Class, html.EscapeString(text))
+			}
+		}
+	}
+
+	out.WriteString("</span>")
+
+	return out.String()
+}
+
+const style = `body { margin: 0; background: #fdfdfd; color: #24292e; }
+pre { margin: 0; padding: 1em 0; font: 14px/1.5 monospace; }
+.line:target { background: #fff8c5; }
+.number { display: inline-block; width: 4em; margin-right: 1em; padding-right: 0.5em; text-align: right; color: #959da5; text-decoration: none; user-select: none; }
+.keyword { color: #a626a4; font-weight: bold; }
+.literal { color: #0184bc; }
+.operator { color: #986801; }
+.comment { color: #a0a1a7; font-style: italic; }
+.error { color: #e45649; text-decoration: wavy underline; }`
+
+// Page renders source as a standalone HTML page titled title.
+func Page(title string, source string) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+%s
+</style>
+</head>
+<body>
+<pre><code>%s</code></pre>
+</body>
+</html>
+`, html.EscapeString(title), style, HTML(source))
+}
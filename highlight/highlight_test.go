@@ -0,0 +1,59 @@
+package highlight
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSpans(t *testing.T) {
+	source := "let x = 1.5; // half\nx++ and true;\n@"
+
+	expected := []Span{
+		{Keyword, "let"}, {Plain, " "}, {Identifier, "x"}, {Plain, " "}, {Operator, "="}, {Plain, " "},
+		{Literal, "1.5"}, {Operator, ";"}, {Plain, " "}, {Comment, "// half"}, {Plain, "\n"},
+		{Identifier, "x"}, {Operator, "++"}, {Plain, " "}, {Keyword, "and"}, {Plain, " "},
+		{Literal, "true"}, {Operator, ";"}, {Plain, "\n"}, {Error, "@"},
+	}
+
+	spans := Spans(source)
+
+	if !reflect.DeepEqual(spans, expected) {
+		t.Fatalf("expected %v, got %v", expected, spans)
+	}
+
+	text := ""
+
+	for _, span := range spans {
+		text += span.Text
+	}
+
+	if text != source {
+		t.Errorf("expected the spans to give back the source, got %q", text)
+	}
+}
+
+func TestANSI(t *testing.T) {
+	expected := "\033[35mlet\033[0m x \033[33m=\033[0m \033[36m1\033[0m\033[33m;\033[0m"
+
+	if output := ANSI("let x = 1;"); output != expected {
+		t.Errorf("expected %q, got %q", expected, output)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	expected := `<span class="line" id="L1"><a class="number" href="#L1">1</a><span class="keyword">if</span> <span class="operator">(</span><span class="identifier">a</span> <span class="operator">&lt;</span> <span class="identifier">b</span><span class="operator">)</span> <span class="operator">{</span></span>
+<span class="line" id="L2"><a class="number" href="#L2">2</a></span>
+<span class="line" id="L3"><a class="number" href="#L3">3</a>  <span class="comment">// &lt;b&gt;</span></span>
+<span class="line" id="L4"><a class="number" href="#L4">4</a><span class="operator">};</span></span>`
+
+	if output := HTML("if (a < b) {\n\n  // <b>\n};\n"); output != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, output)
+	}
+
+	page := Page("a & b.monkey", "1;")
+
+	if !strings.HasPrefix(page, "<!DOCTYPE html>") || !strings.Contains(page, "<title>a &amp; b.monkey</title>") || !strings.Contains(page, `id="L1"`) {
+		t.Errorf("unexpected page\n%s", page)
+	}
+}
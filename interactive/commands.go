@@ -0,0 +1,123 @@
+package interactive
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/token"
+	"monkey/tokenizer"
+	"os"
+	"strings"
+	"time"
+)
+
+const commandsHelp = `:tokens code   show the tokens of code
+:ast code      show the syntax tree of code
+:env           list the bindings of the session with their types
+:type expr     show the type of the value of expr, without keeping its bindings
+:load file     run file in the session
+:save file     write the inputs of the session to file
+:reset         start a new session
+:time code     run code in the session and show how long it took
+:help          show this help
+`
+
+var commands = []string{":tokens", ":ast", ":env", ":type", ":load", ":save", ":reset", ":time", ":help"}
+
+// command runs a colon command line.
+func (session *session) command(line string) {
+	name, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+
+	switch name {
+	case ":tokens":
+		tok := tokenizer.New(argument)
+
+		for t := tok.NextToken(); t.Type != token.EOF; t = tok.NextToken() {
+			fmt.Fprintf(session.out, "%+v\n", t)
+		}
+
+	case ":ast":
+		if prog, ok := session.parse(statement(argument)); ok {
+			fmt.Fprintln(session.out, ast.SExpr(prog))
+		}
+
+	case ":env":
+		session.listEnvironment()
+
+	case ":type":
+		if prog, ok := session.parse(statement(argument)); ok {
+			result := session.evaluator.Eval(prog, object.NewEnclosedEnvironment(session.env))
+
+			if result == nil {
+				result = evaluator.Null
+			}
+
+			if _, ok := result.(*object.ErrorObject); ok {
+				session.print(result)
+				return
+			}
+
+			fmt.Fprintln(session.out, result.Type())
+		}
+
+	case ":load":
+		source, err := os.ReadFile(argument)
+
+		if err != nil {
+			session.fail(err)
+			return
+		}
+
+		if !strings.HasSuffix(string(source), "\n") {
+			source = append(source, '\n')
+		}
+
+		session.evaluate(string(source))
+
+	case ":save":
+		if err := os.WriteFile(argument, []byte(strings.Join(session.inputs, "")), 0644); err != nil {
+			session.fail(err)
+			return
+		}
+
+		fmt.Fprintf(session.out, "Saved %d inputs to %s\n", len(session.inputs), argument)
+
+	case ":reset":
+		session.env = object.NewEnvironment()
+		session.inputs = nil
+
+	case ":time":
+		start := time.Now()
+		session.evaluate(statement(argument) + "\n")
+		fmt.Fprintf(session.out, "Took %s\n", time.Since(start))
+
+	case ":help":
+		fmt.Fprint(session.out, commandsHelp)
+
+	default:
+		fmt.Fprintf(session.out, "Unknown command %s, see :help\n", name)
+	}
+}
+
+func (session *session) listEnvironment() {
+	for _, name := range session.env.Names() {
+		value, _ := session.env.Get(name)
+		fmt.Fprintf(session.out, "%s: %s = %s\n", name, value.Type(), value.Inspect())
+	}
+}
+
+func (session *session) fail(err error) {
+	fmt.Fprintf(session.out, "\033[31mError: %s\033[0m\n", err)
+}
+
+// statement completes code given to a command with its final semicolon,
+// which may be left out.
+func statement(code string) string {
+	if !strings.HasSuffix(strings.TrimSpace(code), ";") {
+		code += ";"
+	}
+
+	return code
+}
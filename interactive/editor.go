@@ -0,0 +1,498 @@
+package interactive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/highlight"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"golang.org/x/term"
+)
+
+// errInterrupted is returned by readLine when the user presses Ctrl-C, the
+// input read so far is dropped.
+var errInterrupted = errors.New("interrupted")
+
+const historySize = 1000
+
+// lineReader reads the lines of the user's input.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// newLineReader edits lines in the terminal when in is one, and reads them
+// as they come otherwise.
+func newLineReader(in io.Reader, out io.Writer, complete func(string) []string) lineReader {
+	if file, ok := in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		editor := newEditor(file, out, complete)
+		editor.highlight = highlight.ANSI
+
+		if home, err := os.UserHomeDir(); err == nil {
+			editor.historyPath = filepath.Join(home, ".monkey_history")
+			editor.loadHistory()
+		}
+
+		return &terminal{fd: int(file.Fd()), editor: editor}
+	}
+
+	return &plainReader{scanner: bufio.NewScanner(in), out: out}
+}
+
+// plainReader reads lines without editing them, for input that isn't typed
+// in a terminal.
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (reader *plainReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(reader.out, prompt)
+
+	if !reader.scanner.Scan() {
+		if err := reader.scanner.Err(); err != nil {
+			return "", err
+		}
+
+		return "", io.EOF
+	}
+
+	return reader.scanner.Text(), nil
+}
+
+// terminal puts the terminal in raw mode while the editor reads a line, so
+// the output of the program is left alone.
+type terminal struct {
+	fd     int
+	editor *editor
+}
+
+func (terminal *terminal) readLine(prompt string) (string, error) {
+	state, err := term.MakeRaw(terminal.fd)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer term.Restore(terminal.fd, state)
+
+	return terminal.editor.readLine(prompt)
+}
+
+/* --- Editor --------------------------------------------------------------- */
+
+const (
+	keyCtrlA     = 1
+	keyCtrlB     = 2
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlE     = 5
+	keyCtrlF     = 6
+	keyCtrlG     = 7
+	keyBackspace = 8
+	keyTab       = 9
+	keyLineFeed  = 10
+	keyCtrlK     = 11
+	keyEnter     = 13
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlR     = 18
+	keyCtrlU     = 21
+	keyCtrlW     = 23
+	keyEscape    = 27
+	keyDelete    = 127
+)
+
+// Escape sequences are read as keys past the Unicode range.
+const (
+	keyUp rune = unicode.MaxRune + 1 + iota
+	keyDown
+	keyRight
+	keyLeft
+	keyHome
+	keyEnd
+	keyDeleteForward
+	keyUnknown
+)
+
+// editor edits lines typed in a raw terminal: cursor movement, history with
+// reverse search, and completion of the word before the cursor.
+type editor struct {
+	reader *bufio.Reader
+	out    io.Writer
+
+	// complete returns the candidates to complete a word with.
+	complete func(word string) []string
+
+	// highlight, when set, colours the line as it is typed. The line must
+	// keep its width.
+	highlight func(line string) string
+
+	history     []string
+	historyPath string
+
+	prompt string
+	line   []rune
+	cursor int
+}
+
+func newEditor(in io.Reader, out io.Writer, complete func(string) []string) *editor {
+	return &editor{reader: bufio.NewReader(in), out: out, complete: complete}
+}
+
+func (editor *editor) loadHistory() {
+	data, err := os.ReadFile(editor.historyPath)
+
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			editor.history = append(editor.history, line)
+		}
+	}
+
+	if len(editor.history) > historySize {
+		editor.history = editor.history[len(editor.history)-historySize:]
+	}
+}
+
+// addHistory keeps line in the history, and in the history file if there's
+// one, unless it repeats the previous line.
+func (editor *editor) addHistory(line string) {
+	if strings.TrimSpace(line) == "" || len(editor.history) > 0 && editor.history[len(editor.history)-1] == line {
+		return
+	}
+
+	editor.history = append(editor.history, line)
+
+	if editor.historyPath == "" {
+		return
+	}
+
+	if file, err := os.OpenFile(editor.historyPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err == nil {
+		fmt.Fprintln(file, line)
+		file.Close()
+	}
+}
+
+// readKey reads a key, decoding the escape sequences of the special keys.
+func (editor *editor) readKey() (rune, error) {
+	key, _, err := editor.reader.ReadRune()
+
+	if err != nil || key != keyEscape {
+		return key, err
+	}
+
+	next, _, err := editor.reader.ReadRune()
+
+	if err != nil {
+		return 0, err
+	}
+
+	if next != '[' && next != 'O' {
+		return keyUnknown, nil
+	}
+
+	parameters := ""
+
+	for {
+		final, _, err := editor.reader.ReadRune()
+
+		if err != nil {
+			return 0, err
+		}
+
+		if final < 0x40 || final > 0x7e {
+			parameters += string(final)
+			continue
+		}
+
+		switch {
+		case final == 'A':
+			return keyUp, nil
+		case final == 'B':
+			return keyDown, nil
+		case final == 'C':
+			return keyRight, nil
+		case final == 'D':
+			return keyLeft, nil
+		case final == 'H', final == '~' && (parameters == "1" || parameters == "7"):
+			return keyHome, nil
+		case final == 'F', final == '~' && (parameters == "4" || parameters == "8"):
+			return keyEnd, nil
+		case final == '~' && parameters == "3":
+			return keyDeleteForward, nil
+		default:
+			return keyUnknown, nil
+		}
+	}
+}
+
+// refresh redraws the line with the cursor in place.
+func (editor *editor) refresh() {
+	line := string(editor.line)
+
+	if editor.highlight != nil {
+		line = editor.highlight(line)
+	}
+
+	fmt.Fprintf(editor.out, "\r%s%s\x1b[K", editor.prompt, line)
+
+	if back := len(editor.line) - editor.cursor; back > 0 {
+		fmt.Fprintf(editor.out, "\x1b[%dD", back)
+	}
+}
+
+func (editor *editor) set(line string) {
+	editor.line = []rune(line)
+	editor.cursor = len(editor.line)
+}
+
+func (editor *editor) insert(text []rune) {
+	line := append([]rune{}, editor.line[:editor.cursor]...)
+	line = append(line, text...)
+	editor.line = append(line, editor.line[editor.cursor:]...)
+	editor.cursor += len(text)
+}
+
+// erase removes the runes between from and the cursor, before it.
+func (editor *editor) erase(from int) {
+	editor.line = append(editor.line[:from], editor.line[editor.cursor:]...)
+	editor.cursor = from
+}
+
+// readLine reads a line, io.EOF is returned for Ctrl-D on an empty line.
+func (editor *editor) readLine(prompt string) (string, error) {
+	editor.prompt = prompt
+	editor.line, editor.cursor = nil, 0
+
+	// position in the history, len(history) being the line being edited,
+	// which is kept in edited while browsing.
+	position := len(editor.history)
+	edited := ""
+
+	editor.refresh()
+
+	for {
+		key, err := editor.readKey()
+
+		if err != nil {
+			return "", err
+		}
+
+		if key == keyCtrlR {
+			if key, err = editor.search(); err != nil {
+				return "", err
+			}
+
+			position = len(editor.history)
+		}
+
+		switch key {
+		case keyEnter, keyLineFeed:
+			line := string(editor.line)
+			fmt.Fprint(editor.out, "\r\n")
+			editor.addHistory(line)
+
+			return line, nil
+
+		case keyCtrlC:
+			fmt.Fprint(editor.out, "^C\r\n")
+			return "", errInterrupted
+
+		case keyCtrlD:
+			if len(editor.line) == 0 {
+				return "", io.EOF
+			}
+
+			if editor.cursor < len(editor.line) {
+				editor.cursor++
+				editor.erase(editor.cursor - 1)
+			}
+
+		case keyCtrlA, keyHome:
+			editor.cursor = 0
+
+		case keyCtrlE, keyEnd:
+			editor.cursor = len(editor.line)
+
+		case keyCtrlB, keyLeft:
+			if editor.cursor > 0 {
+				editor.cursor--
+			}
+
+		case keyCtrlF, keyRight:
+			if editor.cursor < len(editor.line) {
+				editor.cursor++
+			}
+
+		case keyBackspace, keyDelete:
+			if editor.cursor > 0 {
+				editor.erase(editor.cursor - 1)
+			}
+
+		case keyDeleteForward:
+			if editor.cursor < len(editor.line) {
+				editor.cursor++
+				editor.erase(editor.cursor - 1)
+			}
+
+		case keyCtrlK:
+			editor.line = editor.line[:editor.cursor]
+
+		case keyCtrlU:
+			editor.erase(0)
+
+		case keyCtrlW:
+			from := editor.cursor
+
+			for from > 0 && unicode.IsSpace(editor.line[from-1]) {
+				from--
+			}
+
+			for from > 0 && !unicode.IsSpace(editor.line[from-1]) {
+				from--
+			}
+
+			editor.erase(from)
+
+		case keyCtrlP, keyUp:
+			if position > 0 {
+				if position == len(editor.history) {
+					edited = string(editor.line)
+				}
+
+				position--
+				editor.set(editor.history[position])
+			}
+
+		case keyCtrlN, keyDown:
+			if position < len(editor.history) {
+				position++
+
+				if position == len(editor.history) {
+					editor.set(edited)
+				} else {
+					editor.set(editor.history[position])
+				}
+			}
+
+		case keyTab:
+			editor.completeWord()
+
+		case keyCtrlG, keyCtrlR, keyEscape, keyUnknown:
+
+		default:
+			if unicode.IsPrint(key) {
+				editor.insert([]rune{key})
+			}
+		}
+
+		editor.refresh()
+	}
+}
+
+// search searches the history backward for the text typed, Ctrl-R looking
+// further back. The match found is put in the line and the key ending the
+// search is returned to be handled as usual, Ctrl-G cancels the search.
+func (editor *editor) search() (rune, error) {
+	original := string(editor.line)
+	query := []rune{}
+	match := len(editor.history)
+
+	find := func(from int) {
+		for i := from; i >= 0; i-- {
+			if strings.Contains(editor.history[i], string(query)) {
+				match = i
+				editor.set(editor.history[i])
+
+				return
+			}
+		}
+	}
+
+	for {
+		fmt.Fprintf(editor.out, "\r(reverse-i-search)`%s': %s\x1b[K", string(query), string(editor.line))
+
+		key, err := editor.readKey()
+
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case key == keyCtrlR:
+			find(match - 1)
+
+		case key == keyBackspace || key == keyDelete:
+			if len(query) > 0 {
+				query = query[:len(query)-1]
+				find(len(editor.history) - 1)
+			}
+
+		case key == keyCtrlG:
+			editor.set(original)
+			return keyCtrlG, nil
+
+		case key != keyTab && unicode.IsPrint(key):
+			query = append(query, key)
+
+			if match == len(editor.history) {
+				match--
+			}
+
+			find(match)
+
+		default:
+			return key, nil
+		}
+	}
+}
+
+// completeWord completes the word before the cursor with the common prefix
+// of its candidates, listing them when that doesn't complete anything.
+func (editor *editor) completeWord() {
+	if editor.complete == nil {
+		return
+	}
+
+	start := editor.cursor
+
+	for start > 0 && isWordRune(editor.line[start-1]) {
+		start--
+	}
+
+	word := string(editor.line[start:editor.cursor])
+	candidates := editor.complete(word)
+
+	if len(candidates) == 0 {
+		return
+	}
+
+	prefix := []rune(candidates[0])
+
+	for _, candidate := range candidates[1:] {
+		for !strings.HasPrefix(candidate, string(prefix)) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+
+	if word := []rune(word); len(prefix) > len(word) {
+		editor.insert(prefix[len(word):])
+		return
+	}
+
+	if len(candidates) > 1 {
+		fmt.Fprintf(editor.out, "\r\n%s\r\n", strings.Join(candidates, "  "))
+	}
+}
+
+func isWordRune(char rune) bool {
+	return char == '_' || char == ':' || unicode.IsLetter(char) || unicode.IsDigit(char)
+}
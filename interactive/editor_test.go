@@ -0,0 +1,97 @@
+package interactive
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEditor(t *testing.T) {
+	history := []string{"let answer = 42;", "let add = function(a, b) { return a + b; };", "add(1, 2);"}
+
+	complete := func(word string) []string {
+		candidates := []string{}
+
+		for _, name := range []string{"add", "answer", "function", "false"} {
+			if strings.HasPrefix(name, word) && name != word {
+				candidates = append(candidates, name)
+			}
+		}
+
+		return candidates
+	}
+
+	tests := []struct {
+		keys     string
+		expected string
+	}{
+		{"let x = 1;\r", "let x = 1;"},
+		{"x = 1;\x01let \r", "let x = 1;"},
+		{"let x = 1\x05;\r", "let x = 1;"},
+		{"let y\x1b[D\x1b[D\x7fx\x1b[C\x1b[Cz\r", "lex yz"},
+		{"abc\x1b[H\x1b[3~\x1b[F!\r", "bc!"},
+		{"let x = one two\x17\x17three\r", "let x = three"},
+		{"hello world\x02\x02\x02\x02\x02\x0b\x01\x06\x04\r", "hllo "},
+		{"drop\x15keep\r", "keep"},
+		{"\x1b[A\r", "add(1, 2);"},
+		{"\x10\x10\x10\x0e\r", "let add = function(a, b) { return a + b; };"},
+		{"typed\x1b[A\x1b[B\r", "typed"},
+		{"\x12answer\r", "let answer = 42;"},
+		{"\x12a\x12\x12\x05 // more\r", "let answer = 42; // more"},
+		{"kept\x12zzz\x07\r", "kept"},
+		{"an\t\r", "answer"},
+		{"a\t(\r", "a("},
+		{"fu\t\r", "function"},
+	}
+
+	for _, test := range tests {
+		var out strings.Builder
+
+		editor := newEditor(strings.NewReader(test.keys), &out, complete)
+		editor.history = append([]string{}, history...)
+
+		line, err := editor.readLine(InteractivePrompt)
+
+		if err != nil || line != test.expected {
+			t.Errorf("%q - expected %q, got %q %v", test.keys, test.expected, line, err)
+		}
+	}
+}
+
+func TestEditorEndings(t *testing.T) {
+	var out strings.Builder
+
+	editor := newEditor(strings.NewReader("x\x03\x04"), &out, nil)
+
+	if _, err := editor.readLine(InteractivePrompt); err != errInterrupted {
+		t.Errorf("expected Ctrl-C to interrupt the line, got %v", err)
+	}
+
+	if _, err := editor.readLine(InteractivePrompt); err != io.EOF {
+		t.Errorf("expected Ctrl-D on an empty line to end the input, got %v", err)
+	}
+}
+
+func TestEditorHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+
+	if err := os.WriteFile(path, []byte("let a = 1;\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+
+	editor := newEditor(strings.NewReader("let b = 2;\rlet b = 2;\r\r"), &out, nil)
+	editor.historyPath = path
+	editor.loadHistory()
+
+	for i := 0; i < 3; i++ {
+		editor.readLine(InteractivePrompt)
+	}
+
+	if data, _ := os.ReadFile(path); string(data) != "let a = 1;\nlet b = 2;\n" {
+		t.Errorf("expected new lines to be appended once to the history, got %q", data)
+	}
+}
@@ -1,42 +1,186 @@
 package interactive
 
 import (
-	"bufio"
 	"fmt"
 	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
 	"monkey/parser"
+	"monkey/token"
 	"monkey/tokenizer"
+	"sort"
+	"strings"
 )
 
-const InteractivePrompt = " -> "
+const (
+	InteractivePrompt  = " -> "
+	ContinuationPrompt = " .. "
+)
+
+// session is the state kept across inputs: the environment and the inputs
+// it was built from.
+type session struct {
+	env       *object.Environment
+	inputs    []string
+	out       io.Writer
+	evaluator *evaluator.Evaluator
+}
 
+// Start reads programs from in and evaluates them in one environment, so
+// bindings outlive the input they're made in. Input continues on the next
+// line while it is incomplete, and lines starting with a colon are
+// commands, see :help.
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	StartWith(in, out, evaluator.New())
+}
+
+// StartWith is Start with the programs evaluated by evaluator.
+func StartWith(in io.Reader, out io.Writer, evaluator *evaluator.Evaluator) {
+	session := &session{env: object.NewEnvironment(), out: out, evaluator: evaluator}
+	lines := newLineReader(in, out, session.complete)
+	input := ""
 
 	for {
-		fmt.Print(InteractivePrompt)
+		prompt := InteractivePrompt
+
+		if input != "" {
+			prompt = ContinuationPrompt
+		}
 
-		scanned := scanner.Scan()
+		line, err := lines.readLine(prompt)
 
-		if !scanned {
+		if err == errInterrupted {
+			input = ""
+			continue
+		}
+
+		if err != nil {
+			fmt.Fprintln(out)
 			return
 		}
 
-		line := scanner.Text()
-		tok := tokenizer.New(line)
-		pars := parser.New(tok)
-		prog := pars.Parse()
+		if input == "" && strings.HasPrefix(strings.TrimSpace(line), ":") {
+			session.command(strings.TrimSpace(line))
+			continue
+		}
+
+		input += line + "\n"
+
+		if incomplete(input) {
+			continue
+		}
+
+		session.evaluate(input)
+		input = ""
+	}
+}
+
+// parse parses input, printing its syntax errors.
+func (session *session) parse(input string) (*ast.Program, bool) {
+	pars := parser.New(tokenizer.New(input))
+	prog := pars.Parse()
+
+	if errors := pars.Errors; len(errors) != 0 {
+		fmt.Fprintf(session.out, "\033[31mParser has %d errors\033[0m\n", len(errors))
+
+		for _, msg := range errors {
+			fmt.Fprintf(session.out, "- %s\n", msg)
+		}
+
+		return nil, false
+	}
+
+	return prog, true
+}
+
+// evaluate runs input in the session and prints its value, statements
+// without a value print nothing. Input that parses is kept for :save.
+func (session *session) evaluate(input string) {
+	prog, ok := session.parse(input)
+
+	if !ok {
+		return
+	}
+
+	session.inputs = append(session.inputs, input)
+	session.print(session.evaluator.Eval(prog, session.env))
+}
+
+// complete returns the keywords, commands and names of the session starting
+// with word, sorted.
+func (session *session) complete(word string) []string {
+	candidates := []string{}
+
+	add := func(name string) {
+		if strings.HasPrefix(name, word) && name != word {
+			candidates = append(candidates, name)
+		}
+	}
+
+	for keyword := range token.Keywords {
+		add(keyword)
+	}
 
-		errors := pars.Errors
-		if len(errors) != 0 {
-			fmt.Printf("\033[31mParser has %d errors\033[0m\n", len(errors))
+	for _, name := range session.env.Names() {
+		add(name)
+	}
 
-			for _, msg := range errors {
-				fmt.Printf("- %s\n", msg)
-			}
+	for _, name := range evaluator.BuiltinNames() {
+		add(name)
+	}
 
+	for _, command := range commands {
+		add(command)
+	}
+
+	sort.Strings(candidates)
+
+	return candidates
+}
+
+func (session *session) print(result object.Object) {
+	if result == nil || result == evaluator.Null {
+		return
+	}
+
+	if _, ok := result.(*object.ErrorObject); ok {
+		fmt.Fprintf(session.out, "\033[31m%s\033[0m\n", result.Inspect())
+		return
+	}
+
+	fmt.Fprintln(session.out, result.Inspect())
+}
+
+// incomplete tells whether input needs more lines: a parenthesis or a brace
+// is left open, or it ends with a token that can't end a statement.
+func incomplete(input string) bool {
+	tok := tokenizer.New(input)
+	depth := 0
+	last := token.Token{Type: token.EOF}
+
+	for t := tok.NextToken(); t.Type != token.EOF; t = tok.NextToken() {
+		switch t.Type {
+		case token.OpeningParenthesis, token.OpeningBrace, token.OpeningBracket:
+			depth++
+		case token.ClosingParenthesis, token.ClosingBrace, token.ClosingBracket:
+			depth--
 		}
 
-		fmt.Printf("%v\n", prog)
+		last = t
+	}
+
+	if depth > 0 {
+		return true
 	}
+
+	switch last.Type {
+	case token.Assign, token.Plus, token.Minus, token.Bang, token.Asterisk, token.Power, token.Slash,
+		token.LessThan, token.BiggerThan, token.Equal, token.NotEqual, token.Comma,
+		token.And, token.Or, token.Not,
+		token.Let, token.Function, token.If, token.Else, token.While, token.Return:
+		return true
+	}
+
+	return false
 }
@@ -0,0 +1,158 @@
+package interactive
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	input := strings.Join([]string{
+		"let x = 1;",
+		"let add = function(a, b) {",
+		"  return a +",
+		"    b;",
+		"};",
+		"add(x, 2);",
+		"x + true;",
+		"let y = x * (",
+		"  3",
+		");",
+		"y;",
+	}, "\n")
+
+	var out strings.Builder
+	Start(strings.NewReader(input), &out)
+
+	expected := strings.Join([]string{
+		InteractivePrompt + InteractivePrompt + ContinuationPrompt + ContinuationPrompt + ContinuationPrompt + InteractivePrompt + "3",
+		InteractivePrompt + "\033[31merror: Ln 1, Col 3: type mismatch: Integer + Boolean\033[0m",
+		InteractivePrompt + ContinuationPrompt + ContinuationPrompt + InteractivePrompt + "3",
+		InteractivePrompt,
+		"",
+	}, "\n")
+
+	if out.String() != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, out.String())
+	}
+
+	out.Reset()
+	Start(strings.NewReader("let = 1;\nlet y = 2;\ny;\n"), &out)
+
+	if output := out.String(); !strings.Contains(output, "Parser has 4 errors") || !strings.HasSuffix(output, InteractivePrompt+"2\n"+InteractivePrompt+"\n") {
+		t.Errorf("expected the syntax errors to be reported and the session to go on, got %q", output)
+	}
+}
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		input      string
+		incomplete bool
+	}{
+		{"let x = 1;", false},
+		{"let x =", true},
+		{"f(1,", true},
+		{"function(a) {", true},
+		{"if (a) { 1; } else", true},
+		{"x++", false},
+		{"1 + // a comment", true},
+		{"}", false},
+	}
+
+	for _, test := range tests {
+		if incomplete(test.input) != test.incomplete {
+			t.Errorf("%q - expected incomplete to be %t", test.input, test.incomplete)
+		}
+	}
+}
+
+func TestCommands(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib.monkey")
+	saved := filepath.Join(dir, "saved.monkey")
+
+	if err := os.WriteFile(lib, []byte("let double = function(x) { return x * 2; };"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		command  string
+		expected string
+	}{
+		{":tokens let x", "{Type:Let Literal:let Line:1 Column:1}\n{Type:Identifier Literal:x Line:1 Column:5}\n"},
+		{":ast 1 + 2 * 3", "(program\n  (+ 1 (* 2 3)))\n"},
+		{":load " + lib, ""},
+		{"let y = double(2);", ""},
+		{":type y > 1", "Boolean\n"},
+		{":type let z = 1", "Null\n"},
+		{":type z", "\033[31merror: Ln 1, Col 1: identifier not found: z\033[0m\n"},
+		{":env", "double: Function = function(x) {return (x * 2);}\ny: Integer = 4\n"},
+		{":save " + saved, "Saved 2 inputs to " + saved + "\n"},
+		{":reset", ""},
+		{":env", ""},
+		{":load " + filepath.Join(dir, "missing.monkey"), "\033[31mError: open " + filepath.Join(dir, "missing.monkey") + ": no such file or directory\033[0m\n"},
+		{":nope", "Unknown command :nope, see :help\n"},
+	}
+
+	input := []string{}
+	expected := ""
+
+	for _, test := range tests {
+		input = append(input, test.command)
+		expected += InteractivePrompt + test.expected
+	}
+
+	var out strings.Builder
+	Start(strings.NewReader(strings.Join(input, "\n")+"\n"), &out)
+
+	if expected += InteractivePrompt + "\n"; out.String() != expected {
+		t.Errorf("expected\n%q\ngot\n%q", expected, out.String())
+	}
+
+	if source, _ := os.ReadFile(saved); string(source) != "let double = function(x) { return x * 2; };\nlet y = double(2);\n" {
+		t.Errorf("unexpected saved session %q", source)
+	}
+
+	out.Reset()
+	Start(strings.NewReader(":time 1 + 1\n"), &out)
+
+	if !strings.Contains(out.String(), "2\nTook ") {
+		t.Errorf("expected :time to show the result and the time, got %q", out.String())
+	}
+}
+
+func TestStartWith(t *testing.T) {
+	evaluator := evaluator.New()
+	evaluator.StrictIntegers = true
+
+	var out strings.Builder
+	StartWith(strings.NewReader("9223372036854775807 + 1;\n"), &out, evaluator)
+
+	if !strings.Contains(out.String(), "integer overflow") {
+		t.Errorf("expected the session to use the evaluator, got %q", out.String())
+	}
+}
+
+func TestComplete(t *testing.T) {
+	session := &session{env: object.NewEnvironment()}
+	session.env.Set("fib", evaluator.Null)
+	session.env.Set("total", evaluator.Null)
+
+	tests := map[string][]string{
+		"f":   {"false", "fib", "float", "function"},
+		"put": {"puts"},
+		"t":   {"total", "true"},
+		":re": {":reset"},
+		"fib": {},
+	}
+
+	for word, expected := range tests {
+		if candidates := session.complete(word); !reflect.DeepEqual(candidates, expected) {
+			t.Errorf("%q - expected %v, got %v", word, expected, candidates)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+// Package literate finds the monkey code blocks of Markdown documents.
+//
+// Code blocks are fenced with ``` or ~~~ and have monkey for info string.
+// The fenced block following one, with output for info string, is the output
+// expected from running it.
+package literate
+
+import (
+	"strings"
+)
+
+// Block is a monkey code block, Line is the line of its first line of code
+// in the document. Indent is the indentation of its fence, removed from the
+// lines of Source. ExpectedLine is 0 when it has no output block.
+type Block struct {
+	Line   int
+	Indent int
+	Source string
+
+	Expected     string
+	ExpectedLine int
+}
+
+// fence is a fenced code block.
+type fence struct {
+	info    string
+	line    int
+	indent  int
+	content string
+}
+
+// Blocks returns the monkey code blocks of the Markdown document source.
+func Blocks(source string) []Block {
+	blocks := []Block{}
+	fences := fences(source)
+
+	for i, fence := range fences {
+		if fence.info != "monkey" {
+			continue
+		}
+
+		block := Block{Line: fence.line, Indent: fence.indent, Source: fence.content}
+
+		if i+1 < len(fences) && fences[i+1].info == "output" {
+			block.Expected = fences[i+1].content
+			block.ExpectedLine = fences[i+1].line
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// Indented returns Source with the indentation of the fence put back in
+// front of its lines, so that its columns are the columns of the document.
+func (block Block) Indented() string {
+	if block.Indent == 0 || block.Source == "" {
+		return block.Source
+	}
+
+	indent := strings.Repeat(" ", block.Indent)
+
+	return indent + strings.ReplaceAll(strings.TrimSuffix(block.Source, "\n"), "\n", "\n"+indent) + "\n"
+}
+
+// opening returns the fence characters and the info string of line if it
+// opens a fenced block, indented by at most 3 spaces.
+func opening(line string) (indent int, marker string, info string, ok bool) {
+	indent = len(line) - len(strings.TrimLeft(line, " "))
+
+	if indent > 3 {
+		return 0, "", "", false
+	}
+
+	rest := line[indent:]
+
+	for _, char := range []string{"`", "~"} {
+		length := len(rest) - len(strings.TrimLeft(rest, char))
+
+		if length < 3 {
+			continue
+		}
+
+		info = strings.TrimSpace(rest[length:])
+
+		if char == "`" && strings.Contains(info, "`") {
+			return 0, "", "", false
+		}
+
+		if fields := strings.Fields(info); len(fields) != 0 {
+			info = fields[0]
+		}
+
+		return indent, rest[:length], info, true
+	}
+
+	return 0, "", "", false
+}
+
+// closes tells whether line closes a block opened with marker.
+func closes(line string, marker string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+
+	if len(line)-len(trimmed) > 3 || !strings.HasPrefix(trimmed, marker) {
+		return false
+	}
+
+	return strings.TrimSpace(strings.TrimLeft(trimmed, marker[:1])) == ""
+}
+
+func fences(source string) []fence {
+	lines := strings.Split(strings.ReplaceAll(source, "\r\n", "\n"), "\n")
+	fences := []fence{}
+
+	for i := 0; i < len(lines); i++ {
+		indent, marker, info, ok := opening(lines[i])
+
+		if !ok {
+			continue
+		}
+
+		fence := fence{info: info, line: i + 2, indent: indent}
+		content := []string{}
+
+		// Blocks left open run to the end of the document.
+		for i++; i < len(lines) && !closes(lines[i], marker); i++ {
+			line := lines[i]
+
+			// The indentation of the fence is removed from the lines.
+			for j := 0; j < indent && strings.HasPrefix(line, " "); j++ {
+				line = line[1:]
+			}
+
+			content = append(content, line)
+		}
+
+		if len(content) != 0 {
+			fence.content = strings.Join(content, "\n") + "\n"
+		}
+
+		fences = append(fences, fence)
+	}
+
+	return fences
+}
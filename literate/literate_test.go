@@ -0,0 +1,57 @@
+package literate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBlocks(t *testing.T) {
+	source := "# Title\n" +
+		"\n" +
+		"```monkey\n" +
+		"puts(1);\n" +
+		"```\n" +
+		"\n" +
+		"It prints:\n" +
+		"\n" +
+		"```output\n" +
+		"1\n" +
+		"```\n" +
+		"```go\n" +
+		"fmt.Println()\n" +
+		"```\n" +
+		"  ~~~~ monkey title\n" +
+		"    let a = 1;\n" +
+		"  ```\n" +
+		"  a;\n" +
+		"  ~~~~\n" +
+		"```monkey\n" +
+		"```\n" +
+		"```output\n" +
+		"```\n" +
+		"```monkey\n" +
+		"unclosed;"
+
+	expected := []Block{
+		{Line: 4, Source: "puts(1);\n", Expected: "1\n", ExpectedLine: 10},
+		{Line: 16, Indent: 2, Source: "  let a = 1;\n```\na;\n"},
+		{Line: 21, ExpectedLine: 23},
+		{Line: 25, Source: "unclosed;\n"},
+	}
+
+	if actual := Blocks(source); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %+v, got %+v", expected, actual)
+	}
+}
+
+func TestIndented(t *testing.T) {
+	blocks := Blocks("- item\n\n  ```monkey\n  let x = 1;\n\n  puts(y);\n  ```\n")
+
+	if len(blocks) != 1 || blocks[0].Indented() != "  let x = 1;\n  \n  puts(y);\n" {
+		t.Errorf("expected the indentation of the fence back, got %+v", blocks)
+	}
+
+	if block := (Block{Source: "a;\n"}); block.Indented() != "a;\n" {
+		t.Errorf("expected a block without indentation to be left alone, got %q", block.Indented())
+	}
+}
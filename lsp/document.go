@@ -0,0 +1,506 @@
+package lsp
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/cst"
+	"monkey/evaluator"
+	"monkey/scope"
+	"monkey/token"
+	"monkey/tokenizer"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open text document, kept parsed as it is edited.
+type document struct {
+	uri     string
+	version int
+	text    string
+
+	tree        *cst.Node
+	program     *ast.Program
+	info        *scope.Info
+	lowerErrors []string
+	lineStarts  []int
+}
+
+func newDocument(uri string, version int, text string) *document {
+	document := &document{uri: uri, version: version, text: text}
+
+	document.tree = cst.New(tokenizer.New(text)).Parse()
+	document.analyze()
+
+	return document
+}
+
+// apply updates the document with a change, reparsing only the statements
+// it touches.
+func (document *document) apply(change TextDocumentContentChangeEvent) error {
+	if change.Range == nil {
+		document.text = change.Text
+		document.tree = cst.New(tokenizer.New(change.Text)).Parse()
+		document.analyze()
+
+		return nil
+	}
+
+	edit := cst.Edit{Start: document.offset(change.Range.Start), End: document.offset(change.Range.End), Text: change.Text}
+
+	if edit.Start > edit.End {
+		return fmt.Errorf("invalid range %+v", *change.Range)
+	}
+
+	tree, err := cst.Reparse(document.tree, edit)
+
+	if err != nil {
+		return err
+	}
+
+	document.text = document.text[:edit.Start] + edit.Text + document.text[edit.End:]
+	document.tree = tree
+	document.analyze()
+
+	return nil
+}
+
+func (document *document) analyze() {
+	document.program, document.lowerErrors = cst.Lower(document.tree)
+	document.info = scope.Resolve(document.program)
+
+	document.lineStarts = []int{0}
+
+	for i := 0; i < len(document.text); i++ {
+		if document.text[i] == '\n' {
+			document.lineStarts = append(document.lineStarts, i+1)
+		}
+	}
+}
+
+func (document *document) line(line int) string {
+	if line < 0 || line >= len(document.lineStarts) {
+		return ""
+	}
+
+	end := len(document.text)
+
+	if line+1 < len(document.lineStarts) {
+		end = document.lineStarts[line+1]
+	}
+
+	return document.text[document.lineStarts[line]:end]
+}
+
+/* --- Positions ------------------------------------------------------------ */
+
+// The tokens count lines from 1 and columns in runes from 1, the protocol
+// counts both from 0 and characters in UTF-16 code units.
+
+// position converts a line and column of a token.
+func (document *document) position(line int, column int) Position {
+	text := document.line(line - 1)
+	character := 0
+
+	for _, char := range text {
+		if column <= 1 || char == '\n' {
+			break
+		}
+
+		character += utf16.RuneLen(char)
+		column--
+	}
+
+	// Past the end of the line, like the EOF token.
+	return Position{Line: line - 1, Character: character + max(column-1, 0)}
+}
+
+// lineColumn converts a position to the line and column of a token.
+func (document *document) lineColumn(position Position) (int, int) {
+	text := document.line(position.Line)
+	column := 1
+
+	for _, char := range text {
+		if position.Character <= 0 || char == '\n' {
+			break
+		}
+
+		position.Character -= utf16.RuneLen(char)
+		column++
+	}
+
+	return position.Line + 1, column
+}
+
+// offset converts a position to a byte offset in the text.
+func (document *document) offset(position Position) int {
+	if position.Line >= len(document.lineStarts) {
+		return len(document.text)
+	}
+
+	start := document.lineStarts[max(position.Line, 0)]
+	text := document.line(position.Line)
+
+	for i, char := range text {
+		if position.Character <= 0 || char == '\n' {
+			return start + i
+		}
+
+		position.Character -= utf16.RuneLen(char)
+	}
+
+	return start + len(text)
+}
+
+func (document *document) tokenRange(tok *cst.Token) Range {
+	return Range{
+		Start: document.position(tok.Line, tok.Column),
+		End:   document.position(tok.Line, tok.Column+utf8.RuneCountInString(tok.Literal)),
+	}
+}
+
+func (document *document) nodeRange(node *cst.Node) Range {
+	tokens := node.Tokens()
+
+	return Range{
+		Start: document.tokenRange(tokens[0]).Start,
+		End:   document.tokenRange(tokens[len(tokens)-1]).End,
+	}
+}
+
+// tokenAt returns the token under position, a token ending right at it
+// counts unless another one starts there.
+func (document *document) tokenAt(position Position) *cst.Token {
+	line, column := document.lineColumn(position)
+
+	var found *cst.Token
+
+	for _, tok := range document.tree.Tokens() {
+		if tok.Line != line || column < tok.Column || column > tok.Column+utf8.RuneCountInString(tok.Literal) {
+			continue
+		}
+
+		if found == nil || tok.Column == column {
+			found = tok
+		}
+	}
+
+	return found
+}
+
+// leafAt returns the token starting at line and column.
+func (document *document) leafAt(line int, column int) *cst.Token {
+	for _, tok := range document.tree.Tokens() {
+		if tok.Line == line && tok.Column == column {
+			return tok
+		}
+	}
+
+	return nil
+}
+
+func (document *document) identifierRange(identifier *ast.IdentifierLiteral) Range {
+	if tok := document.leafAt(identifier.Token.Line, identifier.Token.Column); tok != nil {
+		return document.tokenRange(tok)
+	}
+
+	return document.tokenRange(&cst.Token{Token: identifier.Token})
+}
+
+// bindingAt returns the identifier under position and its binding.
+func (document *document) bindingAt(position Position) (*ast.IdentifierLiteral, *scope.Binding) {
+	tok := document.tokenAt(position)
+
+	if tok == nil {
+		return nil, nil
+	}
+
+	identifier := document.info.IdentifierAt(tok.Line, tok.Column)
+
+	if identifier == nil {
+		return nil, nil
+	}
+
+	return identifier, document.info.Uses[identifier]
+}
+
+/* --- Features ------------------------------------------------------------- */
+
+func (document *document) diagnostics() []Diagnostic {
+	diagnostics := []Diagnostic{}
+
+	for _, msg := range append(document.tree.Errors(), document.lowerErrors...) {
+		line, column := 1, 1
+		message := msg
+
+		if _, err := fmt.Sscanf(msg, "Ln %d, Col %d:", &line, &column); err == nil {
+			message = msg[strings.Index(msg, ": ")+2:]
+		}
+
+		start := document.position(line, column)
+		end := document.position(line, column+1)
+
+		if tok := document.leafAt(line, column); tok != nil && tok.Literal != "" {
+			end = document.tokenRange(tok).End
+		}
+
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: start, End: end},
+			Severity: SeverityError,
+			Source:   "monkey",
+			Message:  message,
+		})
+	}
+
+	return diagnostics
+}
+
+// signature describes a binding the way it was declared.
+func signature(binding *scope.Binding) string {
+	function, _ := binding.Node.(*ast.FunctionLiteral)
+
+	if let, ok := binding.Node.(*ast.LetStatement); ok {
+		function, _ = let.Expression.(*ast.FunctionLiteral)
+
+		if function == nil {
+			if let.Expression == nil {
+				return "let " + binding.Name
+			}
+
+			return fmt.Sprintf("let %s = %s", binding.Name, let.Expression.String())
+		}
+	}
+
+	parameters := []string{}
+
+	for _, parameter := range function.Parameters {
+		parameters = append(parameters, parameter.Value)
+	}
+
+	header := fmt.Sprintf("function(%s)", strings.Join(parameters, ", "))
+
+	if binding.Kind == scope.Parameter {
+		return fmt.Sprintf("parameter %s of %s", binding.Name, header)
+	}
+
+	return fmt.Sprintf("let %s = %s", binding.Name, header)
+}
+
+func (document *document) hover(position Position) *Hover {
+	identifier, binding := document.bindingAt(position)
+
+	if binding == nil {
+		return nil
+	}
+
+	value := fmt.Sprintf("```monkey\n%s\n```\nBound on line %d", signature(binding), binding.Declaration.Token.Line)
+	identifierRange := document.identifierRange(identifier)
+
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &identifierRange}
+}
+
+func (document *document) definition(position Position) []Location {
+	_, binding := document.bindingAt(position)
+
+	if binding == nil {
+		return []Location{}
+	}
+
+	return []Location{{URI: document.uri, Range: document.identifierRange(binding.Declaration)}}
+}
+
+func (document *document) references(position Position, includeDeclaration bool) []Location {
+	_, binding := document.bindingAt(position)
+	locations := []Location{}
+
+	if binding == nil {
+		return locations
+	}
+
+	if includeDeclaration {
+		locations = append(locations, Location{URI: document.uri, Range: document.identifierRange(binding.Declaration)})
+	}
+
+	for _, reference := range binding.References {
+		locations = append(locations, Location{URI: document.uri, Range: document.identifierRange(reference)})
+	}
+
+	return locations
+}
+
+// scopeAt returns the scope of the innermost function literal around
+// position.
+func (document *document) scopeAt(line int, column int) *scope.Scope {
+	current := document.info.Global
+
+	var visit func(*cst.Node)
+	visit = func(node *cst.Node) {
+		for _, child := range node.Nodes() {
+			tokens := child.Tokens()
+
+			if len(tokens) == 0 {
+				continue
+			}
+
+			first, last := tokens[0], tokens[len(tokens)-1]
+
+			if line < first.Line || line == first.Line && column <= first.Column || line > last.Line || line == last.Line && column > last.Column {
+				continue
+			}
+
+			if child.Kind == cst.FunctionLiteral {
+				for function, scope := range document.info.Scopes {
+					if function.Token.Line == first.Line && function.Token.Column == first.Column {
+						current = scope
+					}
+				}
+			}
+
+			visit(child)
+		}
+	}
+
+	visit(document.tree)
+
+	return current
+}
+
+func (document *document) completion(position Position, keywords []string) []CompletionItem {
+	items := []CompletionItem{}
+
+	for _, keyword := range keywords {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword, Detail: "keyword"})
+	}
+
+	for _, name := range evaluator.BuiltinNames() {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionFunction, Detail: "builtin"})
+	}
+
+	line, column := document.lineColumn(position)
+
+	for _, binding := range document.scopeAt(line, column).Visible(line, column) {
+		kind := CompletionVariable
+
+		if let, ok := binding.Node.(*ast.LetStatement); ok {
+			if _, ok := let.Expression.(*ast.FunctionLiteral); ok {
+				kind = CompletionFunction
+			}
+		}
+
+		items = append(items, CompletionItem{Label: binding.Name, Kind: kind, Detail: signature(binding)})
+	}
+
+	return items
+}
+
+func (document *document) symbols(node *cst.Node) []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+
+	for _, child := range node.Nodes() {
+		if child.Kind != cst.LetStatement {
+			symbols = append(symbols, document.symbols(child)...)
+			continue
+		}
+
+		nodes := child.Nodes()
+
+		if len(nodes) == 0 || nodes[0].Kind != cst.Identifier {
+			continue
+		}
+
+		name := nodes[0].FirstToken()
+		symbol := DocumentSymbol{
+			Name:           name.Literal,
+			Kind:           SymbolVariable,
+			Range:          document.nodeRange(child),
+			SelectionRange: document.tokenRange(name),
+			Children:       document.symbols(child),
+		}
+
+		if len(nodes) > 1 && nodes[1].Kind == cst.FunctionLiteral {
+			symbol.Kind = SymbolFunction
+		}
+
+		symbols = append(symbols, symbol)
+	}
+
+	return symbols
+}
+
+// semanticTokens encodes the tokens of the document, each as the line and
+// start relative to the previous one, its length, type and modifiers.
+func (document *document) semanticTokens() SemanticTokens {
+	data := []int{}
+	previous := Position{}
+
+	add := func(tok token.Token, kind int, modifiers int) {
+		start := document.position(tok.Line, tok.Column)
+		length := len(utf16.Encode([]rune(tok.Literal)))
+
+		if start.Line != previous.Line {
+			previous.Character = 0
+		}
+
+		data = append(data, start.Line-previous.Line, start.Character-previous.Character, length, kind, modifiers)
+		previous = start
+	}
+
+	for _, tok := range document.tree.Tokens() {
+		for _, trivia := range tok.Leading {
+			if trivia.Type == token.Comment {
+				add(trivia, semanticComment, 0)
+			}
+		}
+
+		if _, ok := token.Keywords[tok.Literal]; ok {
+			add(tok.Token, semanticKeyword, 0)
+			continue
+		}
+
+		switch tok.Type {
+		case token.Integer, token.Float:
+			add(tok.Token, semanticNumber, 0)
+
+		case token.String:
+			add(tok.Token, semanticString, 0)
+
+		case token.Identifier:
+			kind, modifiers := semanticVariable, 0
+			identifier := document.info.IdentifierAt(tok.Line, tok.Column)
+
+			if binding := document.info.Uses[identifier]; binding != nil {
+				if binding.Kind == scope.Parameter {
+					kind = semanticParameter
+				} else if let, ok := binding.Node.(*ast.LetStatement); ok {
+					if _, ok := let.Expression.(*ast.FunctionLiteral); ok {
+						kind = semanticFunction
+					}
+				}
+
+				if binding.Declaration == identifier {
+					modifiers = 1
+				}
+			}
+
+			add(tok.Token, kind, modifiers)
+
+		default:
+			if _, ok := token.Operators[tok.Literal]; ok {
+				add(tok.Token, semanticOperator, 0)
+			}
+		}
+	}
+
+	return SemanticTokens{Data: data}
+}
+
+func (document *document) formatting(formatted string) []TextEdit {
+	if formatted == document.text {
+		return []TextEdit{}
+	}
+
+	last := len(document.lineStarts) - 1
+	end := Position{Line: last, Character: len(utf16.Encode([]rune(document.line(last))))}
+
+	return []TextEdit{{Range: Range{End: end}, NewText: formatted}}
+}
@@ -0,0 +1,359 @@
+// Package lsp implements a language server for monkey speaking the Language
+// Server Protocol over JSON-RPC. It keeps open documents parsed with the
+// concrete syntax tree, reparsing only the statements an edit touches, and
+// answers with diagnostics, hovers, definitions, references, completions,
+// document symbols, semantic tokens and formatting.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/format"
+	"monkey/token"
+	"sort"
+)
+
+// The semantic token types and modifiers, in the order of the legend.
+var (
+	semanticTypes     = []string{"keyword", "variable", "parameter", "function", "number", "operator", "comment", "string"}
+	semanticModifiers = []string{"declaration"}
+)
+
+const (
+	semanticKeyword = iota
+	semanticVariable
+	semanticParameter
+	semanticFunction
+	semanticNumber
+	semanticOperator
+	semanticComment
+	semanticString
+)
+
+// Server is a language server reading requests from one stream and writing
+// responses and notifications to another.
+type Server struct {
+	reader *bufio.Reader
+	writer io.Writer
+
+	documents map[string]*document
+	keywords  []string
+	shutdown  bool
+}
+
+// NewServer creates a server talking over in and out.
+func NewServer(in io.Reader, out io.Writer) *Server {
+	keywords := []string{}
+
+	for keyword := range token.Keywords {
+		keywords = append(keywords, keyword)
+	}
+
+	sort.Strings(keywords)
+
+	return &Server{reader: bufio.NewReader(in), writer: out, documents: map[string]*document{}, keywords: keywords}
+}
+
+// Serve handles messages until the client sends exit or closes the stream.
+// It returns nil only for an exit after a shutdown request.
+func (server *Server) Serve() error {
+	for {
+		msg, err := readMessage(server.reader)
+
+		if errors.Is(err, io.EOF) {
+			return errors.New("connection closed before exit")
+		}
+
+		var responseErr *responseError
+
+		if errors.As(err, &responseErr) {
+			if err := writeMessage(server.writer, errorResponse{JSONRPC: "2.0", ID: nil, Error: responseErr}); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if msg.Method == "exit" {
+			if !server.shutdown {
+				return errors.New("exit before shutdown")
+			}
+
+			return nil
+		}
+
+		if err := server.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+func (server *Server) handle(msg *message) error {
+	result, err := server.dispatch(msg)
+
+	// Notifications get no response, even when they fail.
+	if msg.ID == nil {
+		return nil
+	}
+
+	if err != nil {
+		var responseErr *responseError
+
+		if !errors.As(err, &responseErr) {
+			responseErr = &responseError{Code: codeInternalError, Message: err.Error()}
+		}
+
+		return writeMessage(server.writer, errorResponse{JSONRPC: "2.0", ID: msg.ID, Error: responseErr})
+	}
+
+	return writeMessage(server.writer, response{JSONRPC: "2.0", ID: msg.ID, Result: result})
+}
+
+func (server *Server) notify(method string, params interface{}) error {
+	return writeMessage(server.writer, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+// params decodes the parameters of msg into params.
+func params(msg *message, params interface{}) error {
+	if err := json.Unmarshal(msg.Params, params); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+
+	return nil
+}
+
+func (server *Server) document(uri string) (*document, error) {
+	document, ok := server.documents[uri]
+
+	if !ok {
+		return nil, &responseError{Code: codeInvalidParams, Message: fmt.Sprintf("unknown document %s", uri)}
+	}
+
+	return document, nil
+}
+
+// positionDocument decodes position params and returns their document.
+func (server *Server) positionDocument(msg *message, positionParams *TextDocumentPositionParams) (*document, error) {
+	if err := params(msg, positionParams); err != nil {
+		return nil, err
+	}
+
+	return server.document(positionParams.TextDocument.URI)
+}
+
+func (server *Server) dispatch(msg *message) (interface{}, error) {
+	if server.shutdown && msg.ID != nil {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "server is shut down"}
+	}
+
+	switch msg.Method {
+	case "initialize":
+		return server.initialize(), nil
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		server.shutdown = true
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var didOpen DidOpenTextDocumentParams
+
+		if err := params(msg, &didOpen); err != nil {
+			return nil, err
+		}
+
+		item := didOpen.TextDocument
+		server.documents[item.URI] = newDocument(item.URI, item.Version, item.Text)
+
+		return nil, server.publishDiagnostics(server.documents[item.URI])
+
+	case "textDocument/didChange":
+		var didChange DidChangeTextDocumentParams
+
+		if err := params(msg, &didChange); err != nil {
+			return nil, err
+		}
+
+		document, err := server.document(didChange.TextDocument.URI)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, change := range didChange.ContentChanges {
+			if err := document.apply(change); err != nil {
+				return nil, err
+			}
+		}
+
+		document.version = didChange.TextDocument.Version
+
+		return nil, server.publishDiagnostics(document)
+
+	case "textDocument/didClose":
+		var didClose DidCloseTextDocumentParams
+
+		if err := params(msg, &didClose); err != nil {
+			return nil, err
+		}
+
+		delete(server.documents, didClose.TextDocument.URI)
+
+		return nil, server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: didClose.TextDocument.URI, Diagnostics: []Diagnostic{}})
+
+	case "textDocument/hover":
+		var hover TextDocumentPositionParams
+		document, err := server.positionDocument(msg, &hover)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if result := document.hover(hover.Position); result != nil {
+			return result, nil
+		}
+
+		return nil, nil
+
+	case "textDocument/definition":
+		var definition TextDocumentPositionParams
+		document, err := server.positionDocument(msg, &definition)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return document.definition(definition.Position), nil
+
+	case "textDocument/references":
+		var references ReferenceParams
+
+		if err := params(msg, &references); err != nil {
+			return nil, err
+		}
+
+		document, err := server.document(references.TextDocument.URI)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return document.references(references.Position, references.Context.IncludeDeclaration), nil
+
+	case "textDocument/completion":
+		var completion TextDocumentPositionParams
+		document, err := server.positionDocument(msg, &completion)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return document.completion(completion.Position, server.keywords), nil
+
+	case "textDocument/documentSymbol":
+		var symbols DocumentSymbolParams
+
+		if err := params(msg, &symbols); err != nil {
+			return nil, err
+		}
+
+		document, err := server.document(symbols.TextDocument.URI)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return document.symbols(document.tree), nil
+
+	case "textDocument/semanticTokens/full":
+		var semanticTokens SemanticTokensParams
+
+		if err := params(msg, &semanticTokens); err != nil {
+			return nil, err
+		}
+
+		document, err := server.document(semanticTokens.TextDocument.URI)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return document.semanticTokens(), nil
+
+	case "textDocument/formatting":
+		var formatting DocumentFormattingParams
+
+		if err := params(msg, &formatting); err != nil {
+			return nil, err
+		}
+
+		document, err := server.document(formatting.TextDocument.URI)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(document.tree.Errors()) != 0 {
+			return []TextEdit{}, nil
+		}
+
+		return document.formatting(format.Node(document.tree)), nil
+
+	default:
+		return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method %q not found", msg.Method)}
+	}
+}
+
+func (server *Server) initialize() InitializeResult {
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           2,
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			CompletionProvider:         map[string]interface{}{},
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+			SemanticTokensProvider: map[string]interface{}{
+				"legend": SemanticTokensLegend{TokenTypes: semanticTypes, TokenModifiers: semanticModifiers},
+				"full":   true,
+			},
+		},
+		ServerInfo: ServerInfo{Name: "monkey"},
+	}
+}
+
+func (server *Server) publishDiagnostics(document *document) error {
+	return server.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         document.uri,
+		Version:     document.version,
+		Diagnostics: document.diagnostics(),
+	})
+}
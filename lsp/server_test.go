@@ -0,0 +1,339 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// client talks to a server running in the background. What it sends goes
+// through outgoing to one goroutine writing it in order.
+type client struct {
+	t        *testing.T
+	writer   *io.PipeWriter
+	reader   *bufio.Reader
+	outgoing chan []byte
+	id       int
+	done     chan error
+}
+
+func newClient(t *testing.T) *client {
+	serverIn, clientOut := io.Pipe()
+	clientIn, serverOut := io.Pipe()
+
+	client := &client{t: t, writer: clientOut, reader: bufio.NewReader(clientIn), outgoing: make(chan []byte, 16), done: make(chan error, 1)}
+
+	go func() {
+		client.done <- NewServer(serverIn, serverOut).Serve()
+		serverOut.Close()
+	}()
+
+	go func() {
+		for data := range client.outgoing {
+			if _, err := client.writer.Write(data); err != nil {
+				client.t.Error(err)
+			}
+		}
+	}()
+
+	client.request("initialize", map[string]interface{}{}, nil)
+	client.notify("initialized", map[string]interface{}{})
+
+	return client
+}
+
+func (client *client) send(msg interface{}) {
+	var data bytes.Buffer
+
+	if err := writeMessage(&data, msg); err != nil {
+		client.t.Fatal(err)
+	}
+
+	client.outgoing <- data.Bytes()
+}
+
+func (client *client) read() *message {
+	msg, err := readMessage(client.reader)
+
+	if err != nil {
+		client.t.Fatalf("reading from the server: %s", err)
+	}
+
+	return msg
+}
+
+// request sends a request and decodes the result of its response into
+// result, it fails the test on an error response.
+func (client *client) request(method string, params interface{}, result interface{}) {
+	client.id++
+	id := json.RawMessage(fmt.Sprint(client.id))
+
+	client.send(map[string]interface{}{"jsonrpc": "2.0", "id": &id, "method": method, "params": params})
+
+	msg := client.read()
+
+	if msg.Error != nil {
+		client.t.Fatalf("%s - error response %d %s", method, msg.Error.Code, msg.Error.Message)
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			client.t.Fatalf("%s - %s in %s", method, err, msg.Result)
+		}
+	}
+}
+
+func (client *client) notify(method string, params interface{}) {
+	client.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// diagnostics reads the next diagnostics published by the server.
+func (client *client) diagnostics() PublishDiagnosticsParams {
+	msg := client.read()
+
+	if msg.Method != "textDocument/publishDiagnostics" {
+		client.t.Fatalf("expected diagnostics, got %s", msg.Method)
+	}
+
+	var diagnostics PublishDiagnosticsParams
+	json.Unmarshal(msg.Params, &diagnostics)
+
+	return diagnostics
+}
+
+func (client *client) open(uri string, text string) PublishDiagnosticsParams {
+	client.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text}})
+
+	return client.diagnostics()
+}
+
+func (client *client) exit() {
+	client.request("shutdown", nil, nil)
+	client.notify("exit", nil)
+
+	if err := <-client.done; err != nil {
+		client.t.Errorf("server failed: %s", err)
+	}
+
+	close(client.outgoing)
+}
+
+func at(line int, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.monkey"}, Position: Position{Line: line, Character: character}}
+}
+
+func span(line int, start int, end int) Range {
+	return Range{Start: Position{Line: line, Character: start}, End: Position{Line: line, Character: end}}
+}
+
+const source = `let add = function(a, b) {
+	return a + b;
+};
+let x = 1; // one
+add;
+x + x;
+`
+
+func TestDiagnostics(t *testing.T) {
+	client := newClient(t)
+
+	if published := client.open("file:///a.monkey", source); len(published.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %+v", published.Diagnostics)
+	}
+
+	// Break the second statement, then fix it again.
+	client.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: "file:///a.monkey", Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: Position{Line: 3, Character: 4}, End: Position{Line: 3, Character: 5}}, Text: ""}},
+	})
+
+	published := client.diagnostics()
+
+	if published.Version != 2 || len(published.Diagnostics) == 0 {
+		t.Fatalf("expected diagnostics for version 2, got %+v", published)
+	}
+
+	if diagnostic := published.Diagnostics[0]; diagnostic.Range.Start.Line != 3 || diagnostic.Severity != SeverityError {
+		t.Errorf("unexpected diagnostic %+v", diagnostic)
+	}
+
+	client.notify("textDocument/didChange", DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: "file:///a.monkey", Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Range: &Range{Start: Position{Line: 3, Character: 4}, End: Position{Line: 3, Character: 4}}, Text: "x"}},
+	})
+
+	if published := client.diagnostics(); len(published.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics %+v", published.Diagnostics)
+	}
+
+	var symbols []DocumentSymbol
+	client.request("textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.monkey"}}, &symbols)
+
+	if len(symbols) != 2 || symbols[0].Name != "add" || symbols[0].Kind != SymbolFunction || symbols[1].Name != "x" || symbols[1].Kind != SymbolVariable {
+		t.Errorf("unexpected symbols %+v", symbols)
+	}
+
+	client.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.monkey"}})
+
+	if published := client.diagnostics(); len(published.Diagnostics) != 0 {
+		t.Errorf("expected the diagnostics to be cleared, got %+v", published.Diagnostics)
+	}
+
+	client.exit()
+}
+
+func TestNavigation(t *testing.T) {
+	client := newClient(t)
+	client.open("file:///a.monkey", source)
+
+	var hover Hover
+	client.request("textDocument/hover", at(4, 1), &hover)
+
+	if expected := "```monkey\nlet add = function(a, b)\n```\nBound on line 1"; hover.Contents.Value != expected {
+		t.Errorf("expected hover %q, got %q", expected, hover.Contents.Value)
+	}
+
+	var definition []Location
+	client.request("textDocument/definition", at(1, 9), &definition)
+
+	if len(definition) != 1 || definition[0].Range != span(0, 19, 20) {
+		t.Errorf("unexpected definition %+v", definition)
+	}
+
+	references := ReferenceParams{TextDocumentPositionParams: at(5, 0)}
+	references.Context.IncludeDeclaration = true
+
+	var locations []Location
+	client.request("textDocument/references", references, &locations)
+
+	expected := []Range{span(3, 4, 5), span(5, 0, 1), span(5, 4, 5)}
+	actual := []Range{}
+
+	for _, location := range locations {
+		actual = append(actual, location.Range)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected references %+v, got %+v", expected, actual)
+	}
+
+	var completions []CompletionItem
+	client.request("textDocument/completion", at(1, 8), &completions)
+
+	labels := map[string]int{}
+
+	for _, completion := range completions {
+		labels[completion.Label] = completion.Kind
+	}
+
+	if labels["a"] != CompletionVariable || labels["add"] != CompletionFunction || labels["let"] != CompletionKeyword || labels["puts"] != CompletionFunction {
+		t.Errorf("unexpected completions %+v", completions)
+	}
+
+	if _, ok := labels["x"]; ok {
+		t.Errorf("x is not declared yet, got %+v", completions)
+	}
+
+	client.exit()
+}
+
+func TestSemanticTokens(t *testing.T) {
+	client := newClient(t)
+	client.open("file:///a.monkey", "let x = 1; // one\nx;")
+
+	var tokens SemanticTokens
+	client.request("textDocument/semanticTokens/full", SemanticTokensParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.monkey"}}, &tokens)
+
+	expected := []int{
+		0, 0, 3, semanticKeyword, 0,
+		0, 4, 1, semanticVariable, 1,
+		0, 2, 1, semanticOperator, 0,
+		0, 2, 1, semanticNumber, 0,
+		0, 3, 6, semanticComment, 0,
+		1, 0, 1, semanticVariable, 0,
+	}
+
+	if !reflect.DeepEqual(expected, tokens.Data) {
+		t.Errorf("expected %v, got %v", expected, tokens.Data)
+	}
+
+	client.exit()
+}
+
+func TestFormatting(t *testing.T) {
+	client := newClient(t)
+	client.open("file:///a.monkey", "let  x=1;\nx;")
+
+	var edits []TextEdit
+	client.request("textDocument/formatting", DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: "file:///a.monkey"}}, &edits)
+
+	if expected := []TextEdit{{Range: span(0, 0, 0), NewText: "let x = 1;\nx;\n"}}; len(edits) != 1 || edits[0].NewText != expected[0].NewText || edits[0].Range.End != (Position{Line: 1, Character: 2}) {
+		t.Errorf("unexpected edits %+v", edits)
+	}
+
+	client.exit()
+}
+
+func TestUnknownMethod(t *testing.T) {
+	client := newClient(t)
+
+	client.send(map[string]interface{}{"jsonrpc": "2.0", "id": 7, "method": "textDocument/unknown"})
+
+	if msg := client.read(); msg.Error == nil || msg.Error.Code != codeMethodNotFound {
+		t.Errorf("expected a method not found error, got %+v", msg)
+	}
+
+	client.exit()
+}
+
+func TestMessageTooLarge(t *testing.T) {
+	client := newClient(t)
+
+	client.outgoing <- []byte(fmt.Sprintf("Content-Length: %d\r\n\r\n", maxContentLength+1))
+
+	for left := maxContentLength + 1; left > 0; left -= 1 << 20 {
+		client.outgoing <- make([]byte, min(left, 1<<20))
+	}
+
+	if msg := client.read(); msg.Error == nil || msg.Error.Code != codeInvalidRequest {
+		t.Errorf("expected an invalid request error, got %+v", msg)
+	}
+
+	client.exit()
+}
+
+func TestPositions(t *testing.T) {
+	document := newDocument("file:///a.monkey", 1, "let é = 1;\nlet 𝒳 = é;")
+
+	tests := []struct {
+		line      int
+		column    int
+		character int
+	}{
+		{1, 5, 4},
+		{1, 7, 6},
+		{2, 5, 4},
+		{2, 6, 6},
+		{2, 10, 10},
+	}
+
+	for _, test := range tests {
+		position := document.position(test.line, test.column)
+
+		if position != (Position{Line: test.line - 1, Character: test.character}) {
+			t.Errorf("%d:%d - expected character %d, got %+v", test.line, test.column, test.character, position)
+		}
+
+		if line, column := document.lineColumn(position); line != test.line || column != test.column {
+			t.Errorf("%+v - expected %d:%d, got %d:%d", position, test.line, test.column, line, column)
+		}
+	}
+
+	if offset := document.offset(Position{Line: 1, Character: 6}); offset != len("let é = 1;\nlet 𝒳") {
+		t.Errorf("unexpected offset %d", offset)
+	}
+}
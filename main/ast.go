@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/tokenizer"
+	"os"
+)
+
+// runAST implements `monkey ast [--format=dump|json|dot|sexpr] file`, the
+// tree is printed even when the file has syntax errors.
+func runAST(args []string) {
+	flags := newFlagSet("ast", "[--format=dump|json|dot|sexpr] [--trace-parse] file|-")
+	format := flags.String("format", "dump", "output format, one of dump, json, dot or sexpr")
+	traceParse := flags.Bool("trace-parse", false, "trace the parser rules to stderr")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	source := openSource(flags.Arg(0))
+	defer source.Close()
+
+	pars := newParser(tokenizer.NewFromReader(source), *traceParse)
+	prog := pars.Parse()
+
+	switch *format {
+	case "dump":
+		fmt.Printf("ASTDUMP:%+v\n", prog)
+
+	case "json":
+		data, err := ast.MarshalJSON(prog)
+
+		if err != nil {
+			fail(err)
+		}
+
+		fmt.Println(string(data))
+
+	case "dot":
+		fmt.Print(ast.Dot(prog))
+
+	case "sexpr":
+		fmt.Println(ast.SExpr(prog))
+
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n", *format)
+		os.Exit(2)
+	}
+
+	if len(pars.Errors) != 0 {
+		reportErrors(flags.Arg(0), pars.Errors)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/parser"
+	"monkey/scope"
+	"monkey/tokenizer"
+	"os"
+)
+
+// runCheck implements `monkey check file...`, it reports the syntax errors
+// and the undefined names of the files without running them.
+func runCheck(args []string) {
+	flags := newFlagSet("check", "file|-...")
+	flags.Parse(args)
+	expectFiles(flags, 1, -1)
+
+	failed := false
+
+	for _, path := range flags.Args() {
+		source := openSource(path)
+		parser := parser.New(tokenizer.NewFromReader(source))
+		program := parser.Parse()
+		source.Close()
+
+		errors := parser.Errors
+
+		if len(errors) == 0 {
+			for _, identifier := range scope.Resolve(program).Unresolved {
+				if evaluator.IsBuiltin(identifier.Value) {
+					continue
+				}
+
+				errors = append(errors, fmt.Sprintf("Ln %d, Col %d: undefined: %s", identifier.Token.Line, identifier.Token.Column, identifier.Value))
+			}
+		}
+
+		if len(errors) != 0 {
+			reportErrors(path, errors)
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"monkey/dap"
+	"os"
+)
+
+// runDAP implements `monkey dap`, a debug adapter on stdin and stdout.
+func runDAP(args []string) {
+	flags := newFlagSet("dap", "")
+	flags.Parse(args)
+	expectFiles(flags, 0, 0)
+
+	if err := dap.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fail(err)
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"monkey/debugger"
+	"monkey/tokenizer"
+	"os"
+)
+
+// runDebug implements `monkey debug file`, a command line debugger reading
+// its commands from stdin, so the program can't be read from it.
+func runDebug(args []string) {
+	flags := newFlagSet("debug", "file")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	if flags.Arg(0) == "-" {
+		fmt.Fprintf(os.Stderr, "Error: the debugger reads its commands from stdin, the program must be a file\n\n")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	source := readSource(flags.Arg(0))
+	program := parse(flags.Arg(0), tokenizer.New(source))
+
+	debugger.NewConsole(flags.Arg(0), source, program, os.Stdout).Run(os.Stdin)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"monkey/format"
+	"os"
+)
+
+// runFmt implements `monkey fmt [-w|-l] file...`, it prints the formatted
+// files, rewrites them or lists the ones that aren't formatted.
+func runFmt(args []string) {
+	flags := newFlagSet("fmt", "[-w|-l] file|-...")
+	write := flags.Bool("w", false, "write the formatted source back to the files")
+	list := flags.Bool("l", false, "list the files that aren't formatted, exiting with 1 if there are some")
+	flags.Parse(args)
+	expectFiles(flags, 1, -1)
+
+	failed := false
+
+	for _, path := range flags.Args() {
+		source := readSource(path)
+		formatted, err := format.Source(source)
+
+		if err != nil {
+			reportErrors(path, []string{err.Error()})
+			failed = true
+
+			continue
+		}
+
+		switch {
+		case *list:
+			if formatted != source {
+				fmt.Println(displayName(path))
+				failed = true
+			}
+
+		case *write && path != "-":
+			if formatted != source {
+				if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+					fail(err)
+				}
+			}
+
+		default:
+			fmt.Print(formatted)
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"fmt"
+	"monkey/highlight"
+	"path/filepath"
+)
+
+// runHighlight implements `monkey highlight [--html] file`, it prints the
+// file coloured for the terminal, or as a standalone HTML page.
+func runHighlight(args []string) {
+	flags := newFlagSet("highlight", "[--html] file|-")
+	page := flags.Bool("html", false, "print a standalone HTML page with line anchors")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	source := readSource(flags.Arg(0))
+
+	if *page {
+		fmt.Print(highlight.Page(filepath.Base(displayName(flags.Arg(0))), source))
+		return
+	}
+
+	fmt.Print(highlight.ANSI(source))
+}
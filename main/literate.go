@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/evaluator"
+	"monkey/literate"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func isMarkdown(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return true
+	default:
+		return false
+	}
+}
+
+// runLiterate runs the monkey code blocks of the Markdown file at path in
+// order, in one environment. With check, the output of the blocks followed
+// by an output block is compared with it instead of being written.
+func runLiterate(path string, evaluator *evaluator.Evaluator, check bool) {
+	env := object.NewEnvironment()
+	failed := false
+
+	for _, block := range literate.Blocks(readSource(path)) {
+		parser := parser.New(tokenizer.NewFromReaderAt(strings.NewReader(block.Indented()), block.Line, 1))
+		program := parser.Parse()
+
+		if len(parser.Errors) != 0 {
+			reportErrors(path, parser.Errors)
+			os.Exit(1)
+		}
+
+		var out bytes.Buffer
+		evaluator.Output = os.Stdout
+
+		if check && block.ExpectedLine != 0 {
+			evaluator.Output = &out
+		}
+
+		if result, ok := evaluator.Eval(program, env).(*object.ErrorObject); ok {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(path), result.Message)
+			os.Exit(1)
+		}
+
+		if check && block.ExpectedLine != 0 && out.String() != block.Expected {
+			fmt.Fprintf(os.Stderr, "%s: Ln %d: the output of the block at Ln %d differs, expected:\n%sgot:\n%s", displayName(path), block.ExpectedLine, block.Line, block.Expected, out.String())
+			failed = true
+		}
+	}
+
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,17 @@
+package main
+
+import (
+	"monkey/lsp"
+	"os"
+)
+
+// runLSP implements `monkey lsp`, a language server on stdin and stdout.
+func runLSP(args []string) {
+	flags := newFlagSet("lsp", "")
+	flags.Parse(args)
+	expectFiles(flags, 0, 0)
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fail(err)
+	}
+}
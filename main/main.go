@@ -1,53 +1,215 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"monkey/interactive"
+	"io"
+	"monkey/ast"
 	"monkey/parser"
-	"monkey/token"
 	"monkey/tokenizer"
 	"os"
+	"strings"
 )
 
-func main() {
-	if len(os.Args) > 2 {
-		data, err := ioutil.ReadFile(os.Args[2])
+// command is a subcommand of the monkey binary. Commands exit with 1 when
+// the program fails, to read, parse or run, and with 2 when they're misused.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string)
+}
+
+var commands = []command{
+	{"run", "run a program", runRun},
+	{"repl", "start an interactive session", runREPL},
+	{"tokens", "print the tokens of a program", runTokens},
+	{"ast", "print the syntax tree of a program", runAST},
+	{"fmt", "format a program", runFmt},
+	{"check", "report the syntax errors and undefined names of programs", runCheck},
+	{"compile", "compile a program", runCompile},
+	{"query", "run an expression on the JSON read from stdin", runQuery},
+	{"render", "print the value of a program as JSON or YAML", runRender},
+	{"template", "render a template with embedded code", runTemplate},
+	{"version", "print the version", runVersion},
+	{"highlight", "print a program with syntax highlighting", runHighlight},
+	{"debug", "debug a program from the command line", runDebug},
+	{"record", "run a program and record its execution", runRecord},
+	{"replay", "replay a recorded execution", runReplay},
+	{"lsp", "start a language server on stdin and stdout", runLSP},
+	{"dap", "start a debug adapter on stdin and stdout", runDAP},
+}
 
-		if err == nil {
-			if os.Args[1] == "-t" {
-				tok := tokenizer.New(string(data))
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: monkey <command> [arguments]\n       monkey file\n       monkey [-n [-F sep]] -e program [file...]\n\nCommands:\n")
+
+	for _, command := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", command.name, command.summary)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nWithout a command monkey starts a session, with a file it runs it.\nRun `monkey -n --help` for one-liners.\nRun `monkey <command> --help` for the arguments of a command.\n")
+}
 
-				for t := tok.NextToken(); t.Type != token.EOF; t = tok.NextToken() {
-					fmt.Printf("%+v\n", t)
-				}
-			} else if os.Args[1] == "-p" {
-				tok := tokenizer.New(string(data))
+func main() {
+	args := os.Args[1:]
 
-				pars := parser.New(tok)
-				prog := pars.Parse()
+	if len(args) == 0 {
+		runREPL(nil)
+		return
+	}
 
-				fmt.Printf("ASTDUMP:%+v\n", prog)
-			} else if os.Args[1] == "-c" {
+	switch {
+	case args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help":
+		usage()
+		return
+	case strings.HasPrefix(args[0], "-e") || strings.HasPrefix(args[0], "-n") || strings.HasPrefix(args[0], "-F"):
+		runOneLiner(args)
+		return
+	}
 
-			}
-		} else {
-			fmt.Printf("Error: %s", err.Error())
+	for _, command := range commands {
+		if command.name == args[0] {
+			command.run(args[1:])
+			return
 		}
 	}
 
-	if len(os.Args) > 1 {
-		data, err := ioutil.ReadFile(os.Args[1])
+	if strings.HasPrefix(args[0], "-") || len(args) > 1 {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n\n", args[0])
+		usage()
+		os.Exit(2)
+	}
+
+	runRun(args)
+}
 
-		if err == nil {
-			tok := tokenizer.New(string(data))
+/* --- Helpers -------------------------------------------------------------- */
 
-			pars := parser.New(tok)
-			prog := pars.Parse()
+// newFlagSet creates the flags of a command, --help shows its usage.
+func newFlagSet(name string, arguments string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
 
-			fmt.Printf("ASTDUMP:%+v\n", prog)
-		}
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey %s %s\n", name, arguments)
+		flags.PrintDefaults()
+	}
+
+	return flags
+}
+
+// parseFlags parses args into flags, the flags may also follow the files as
+// in monkey render config.monkey --format yaml. The files are left in
+// flags.Args().
+func parseFlags(flags *flag.FlagSet, args []string) {
+	flags.Parse(args)
+	files := []string{}
+
+	for flags.NArg() > 0 {
+		files = append(files, flags.Arg(0))
+		flags.Parse(flags.Args()[1:])
+	}
+
+	flags.Parse(append([]string{"--"}, files...))
+}
+
+// strictIntegersFlag adds --strict-integers to the flags of a command that
+// runs code.
+func strictIntegersFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("strict-integers", false, "make integer overflows an error instead of promoting to big integers")
+}
+
+// expectFiles exits with the usage of the command unless it got between min
+// and max files, max being -1 for no limit.
+func expectFiles(flags *flag.FlagSet, min int, max int) {
+	if flags.NArg() < min || max >= 0 && flags.NArg() > max {
+		flags.Usage()
+		os.Exit(2)
+	}
+}
+
+// fail reports err and exits.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+	os.Exit(1)
+}
+
+// openSource opens the file at path, or stdin when path is -, for reading it
+// as it is tokenized.
+func openSource(path string) io.ReadCloser {
+	if path == "-" {
+		return io.NopCloser(os.Stdin)
+	}
+
+	file, err := os.Open(path)
+
+	if err != nil {
+		fail(err)
+	}
+
+	return file
+}
+
+// readSource reads the whole file at path, or stdin when path is -, for the
+// commands that need its text.
+func readSource(path string) string {
+	var source []byte
+	var err error
+
+	if path == "-" {
+		source, err = io.ReadAll(os.Stdin)
 	} else {
-		interactive.Start(os.Stdin, os.Stdout)
+		source, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		fail(err)
+	}
+
+	return string(source)
+}
+
+// displayName is how the file at path is named in messages.
+func displayName(path string) string {
+	if path == "-" {
+		return "<stdin>"
+	}
+
+	return path
+}
+
+// reportErrors prints the errors found in the file at path.
+func reportErrors(path string, errors []string) {
+	for _, msg := range errors {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(path), msg)
+	}
+}
+
+func newParser(tok *tokenizer.Tokenizer, trace bool) *parser.Parser {
+	if trace {
+		return parser.NewWithTracer(tok, parser.NewWriterTracer(os.Stderr))
 	}
+
+	return parser.New(tok)
+}
+
+// parseFile parses the file at path as it is read, it exits when it has
+// syntax errors.
+func parseFile(path string) *ast.Program {
+	source := openSource(path)
+	defer source.Close()
+
+	return parse(path, tokenizer.NewFromReader(source))
+}
+
+// parse parses the tokens of the file at path, it exits when it has syntax
+// errors.
+func parse(path string, tok *tokenizer.Tokenizer) *ast.Program {
+	parser := parser.New(tok)
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		reportErrors(path, parser.Errors)
+		os.Exit(1)
+	}
+
+	return program
 }
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/awk"
+	"monkey/object"
+	"os"
+	"strings"
+)
+
+// runOneLiner implements `monkey -e program` and `monkey -n [-F sep] -e
+// program [file...]`. The program runs once and its value is printed, or
+// with -n it runs on each line of the files, or of stdin, like awk.
+func runOneLiner(args []string) {
+	flags := flag.NewFlagSet("monkey", flag.ExitOnError)
+	source := flags.String("e", "", "the program to run")
+	eachLine := flags.Bool("n", false, "run the program on each line of the input, see below")
+	separator := flags.String("F", "", "the separator of the fields, runs of whitespace by default")
+	strictIntegers := strictIntegersFlag(flags)
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: monkey -e program\n       monkey -n [-F sep] -e program [file...]\n")
+		flags.PrintDefaults()
+		fmt.Fprintf(flags.Output(), "\nWith -n, line, fields, NF and NR are bound for each line, fields[1] being\nthe first field. BEGIN { ... } and END { ... } run before and after the lines.\n")
+	}
+
+	flags.Parse(splitSeparator(args))
+
+	if *source == "" || !*eachLine && flags.NArg() != 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	program, errors := awk.Parse(*source)
+
+	if len(errors) != 0 {
+		reportErrors("-e", errors)
+		os.Exit(1)
+	}
+
+	runner := awk.NewRunner(program, os.Stdout)
+	runner.Separator = strings.ReplaceAll(*separator, `\t`, "\t")
+	runner.Evaluator.StrictIntegers = *strictIntegers
+
+	if err := runner.Begin(); err != nil {
+		fail(err)
+	}
+
+	if !*eachLine {
+		result, err := runner.Eval()
+
+		if err != nil {
+			fail(err)
+		}
+
+		switch result := result.(type) {
+		case *object.StringObject:
+			fmt.Println(result.Value)
+		case *object.NullObject:
+		default:
+			fmt.Println(result.Inspect())
+		}
+	} else if program.ReadsInput() {
+		paths := flags.Args()
+
+		if len(paths) == 0 {
+			paths = []string{"-"}
+		}
+
+		for _, path := range paths {
+			processFile(runner, path)
+		}
+	}
+
+	if err := runner.End(); err != nil {
+		fail(err)
+	}
+}
+
+func processFile(runner *awk.Runner, path string) {
+	in := os.Stdin
+
+	if path != "-" {
+		file, err := os.Open(path)
+
+		if err != nil {
+			fail(err)
+		}
+
+		defer file.Close()
+		in = file
+	}
+
+	if err := runner.Process(in); err != nil {
+		fail(fmt.Errorf("%s: %s", displayName(path), err))
+	}
+}
+
+// splitSeparator splits -F, into -F and , as awk users write it.
+func splitSeparator(args []string) []string {
+	split := []string{}
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-F") && len(arg) > 2 && arg[2] != '=' {
+			split = append(split, "-F", arg[2:])
+			continue
+		}
+
+		split = append(split, arg)
+	}
+
+	return split
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/marshal"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"os"
+	"strings"
+)
+
+// runQuery implements `monkey query expr`, it runs expr with the JSON read
+// from stdin bound to input and prints its value as JSON. With --stream
+// stdin holds a JSON document per line, and a line is printed for each.
+func runQuery(args []string) {
+	flags := newFlagSet("query", "[--stream] [--strict-integers] expr")
+	stream := flags.Bool("stream", false, "read a JSON document per line")
+	strictIntegers := strictIntegersFlag(flags)
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	source := flags.Arg(0)
+
+	if trimmed := strings.TrimSpace(source); trimmed != "" && !strings.HasSuffix(trimmed, ";") {
+		source += ";"
+	}
+
+	parser := parser.New(tokenizer.New(source))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		reportErrors("query", parser.Errors)
+		os.Exit(1)
+	}
+
+	evaluator := evaluator.New()
+	evaluator.StrictIntegers = *strictIntegers
+
+	if !*stream {
+		data, err := io.ReadAll(os.Stdin)
+
+		if err != nil {
+			fail(err)
+		}
+
+		output, err := query(evaluator, program, data, "  ")
+
+		if err != nil {
+			fail(err)
+		}
+
+		fmt.Printf("%s\n", output)
+		return
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(nil, 1<<24)
+
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+
+		output, err := query(evaluator, program, scanner.Bytes(), "")
+
+		if err != nil {
+			fail(fmt.Errorf("line %d: %s", line, err))
+		}
+
+		fmt.Printf("%s\n", output)
+	}
+
+	if err := scanner.Err(); err != nil {
+		fail(err)
+	}
+}
+
+// query runs program on the JSON document data and encodes its value.
+func query(evaluator *evaluator.Evaluator, program *ast.Program, data []byte, indent string) ([]byte, error) {
+	input, err := marshal.ParseJSON(data, evaluator.StrictIntegers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	env := object.NewEnvironment()
+	env.Set("input", input)
+
+	result := evaluator.Eval(program, env)
+
+	if err, ok := result.(*object.ErrorObject); ok {
+		return nil, errors.New(err.Message)
+	}
+
+	return marshal.JSON(result, indent)
+}
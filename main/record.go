@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"monkey/object"
+	"monkey/trace"
+	"os"
+	"strings"
+)
+
+// runRecord implements `monkey record [-o trace] file`, it runs the file and
+// writes its trace, file.trace by default. A program read from stdin needs
+// -o.
+func runRecord(args []string) {
+	flags := newFlagSet("record", "[-o trace] file|-")
+	output := flags.String("o", "", "the trace file to write, the program file with a .trace extension by default")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	path := flags.Arg(0)
+
+	if path == "-" && *output == "" {
+		fmt.Fprintf(os.Stderr, "Error: -o is needed to record stdin\n\n")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	program := parseFile(path)
+	recording, result := trace.Record(path, program)
+
+	if *output == "" {
+		*output = strings.TrimSuffix(path, ".monkey") + ".trace"
+	}
+
+	file, err := os.Create(*output)
+
+	if err == nil {
+		err = trace.Write(file, recording)
+
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+
+	if err != nil {
+		fail(err)
+	}
+
+	fmt.Printf("Recorded %d entries to %s\n", len(recording.Entries), *output)
+
+	if errorObject, ok := result.(*object.ErrorObject); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(path), errorObject.Message)
+		os.Exit(1)
+	}
+}
+
+// runReplay implements `monkey replay trace`, a command line debugger moving
+// through a recorded trace.
+func runReplay(args []string) {
+	flags := newFlagSet("replay", "trace")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	file, err := os.Open(flags.Arg(0))
+
+	if err != nil {
+		fail(err)
+	}
+
+	recording, err := trace.Read(file)
+	file.Close()
+
+	if err != nil {
+		fail(fmt.Errorf("%s: %s", flags.Arg(0), err))
+	}
+
+	// The source only shows the recorded lines, the replay works without it.
+	source, _ := os.ReadFile(recording.Path)
+
+	trace.NewConsole(recording, string(source), os.Stdout).Run(os.Stdin)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"monkey/evaluator"
+	"monkey/marshal"
+	"monkey/object"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var argumentName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// runRender implements `monkey render [--format json|yaml] [--arg
+// name=value]... file`, it runs a program and prints the value of its last
+// statement as JSON or YAML. The arguments are bound as strings.
+func runRender(args []string) {
+	flags := newFlagSet("render", "[--format json|yaml] [--arg name=value]... [--strict-integers] file|-")
+	format := flags.String("format", "json", "the output format, json or yaml")
+	strictIntegers := strictIntegersFlag(flags)
+	env := object.NewEnvironment()
+	argumentFlag(flags, env)
+	parseFlags(flags, args)
+	expectFiles(flags, 1, 1)
+
+	if *format != "json" && *format != "yaml" {
+		fmt.Fprintf(os.Stderr, "Error: unknown format %q\n\n", *format)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	path := flags.Arg(0)
+	program := parseFile(path)
+
+	evaluator := evaluator.New()
+	evaluator.StrictIntegers = *strictIntegers
+
+	result := evaluator.Eval(program, env)
+
+	if err, ok := result.(*object.ErrorObject); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(path), err.Message)
+		os.Exit(1)
+	}
+
+	var output []byte
+	var err error
+
+	if *format == "json" {
+		output, err = marshal.JSON(result, "  ")
+	} else {
+		output, err = marshal.YAML(result)
+	}
+
+	if err != nil {
+		fail(fmt.Errorf("%s: %s", displayName(path), err))
+	}
+
+	fmt.Printf("%s\n", output)
+}
+
+// argumentFlag adds the --arg name=value flag to flags, binding name to the
+// string value in env.
+func argumentFlag(flags *flag.FlagSet, env *object.Environment) {
+	flags.Func("arg", "bind name to the string value, can be repeated", func(arg string) error {
+		name, value, ok := strings.Cut(arg, "=")
+
+		if !ok || !argumentName.MatchString(name) {
+			return fmt.Errorf("expected name=value with name an identifier")
+		}
+
+		env.Set(name, &object.StringObject{Value: value})
+		return nil
+	})
+}
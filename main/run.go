@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"monkey/evaluator"
+	"monkey/interactive"
+	"monkey/object"
+	"os"
+)
+
+// runRun implements `monkey run [--strict-integers] [--check] file`, it exits
+// with 1 when the program ends with an error. Markdown files run their monkey
+// code blocks.
+func runRun(args []string) {
+	flags := newFlagSet("run", "[--strict-integers] [--check] file|-")
+	strictIntegers := strictIntegersFlag(flags)
+	check := flags.Bool("check", false, "compare the output of the code blocks of a Markdown file with their output blocks")
+	parseFlags(flags, args)
+	expectFiles(flags, 1, 1)
+
+	evaluator := evaluator.New()
+	evaluator.StrictIntegers = *strictIntegers
+
+	if isMarkdown(flags.Arg(0)) {
+		runLiterate(flags.Arg(0), evaluator, *check)
+		return
+	}
+
+	if *check {
+		fmt.Fprintf(os.Stderr, "Error: --check is for Markdown files\n\n")
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	program := parseFile(flags.Arg(0))
+
+	if result, ok := evaluator.Eval(program, object.NewEnvironment()).(*object.ErrorObject); ok {
+		fmt.Fprintf(os.Stderr, "%s: %s\n", displayName(flags.Arg(0)), result.Message)
+		os.Exit(1)
+	}
+}
+
+// runREPL implements `monkey repl [--strict-integers]`, an interactive
+// session on stdin.
+func runREPL(args []string) {
+	flags := newFlagSet("repl", "[--strict-integers]")
+	strictIntegers := strictIntegersFlag(flags)
+	flags.Parse(args)
+	expectFiles(flags, 0, 0)
+
+	evaluator := evaluator.New()
+	evaluator.StrictIntegers = *strictIntegers
+
+	interactive.StartWith(os.Stdin, os.Stdout, evaluator)
+}
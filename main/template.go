@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/marshal"
+	"monkey/object"
+	"monkey/template"
+	"os"
+	"path/filepath"
+)
+
+// runTemplate implements `monkey template [--html] [--data file.json] [--arg
+// name=value]... file`, it renders a template to stdout. The keys of the
+// JSON object read from --data and the arguments are bound for its code.
+func runTemplate(args []string) {
+	flags := newFlagSet("template", "[--html] [--data file.json] [--arg name=value]... [--strict-integers] file|-")
+	escape := flags.Bool("html", false, "escape the interpolated values for HTML")
+	data := flags.String("data", "", "bind the keys of the JSON object in this file")
+	strictIntegers := strictIntegersFlag(flags)
+	env := object.NewEnvironment()
+	argumentFlag(flags, env)
+	parseFlags(flags, args)
+	expectFiles(flags, 1, 1)
+
+	if *data != "" {
+		value, err := marshal.ParseJSON([]byte(readSource(*data)), *strictIntegers)
+
+		if err != nil {
+			fail(fmt.Errorf("%s: %s", *data, err))
+		}
+
+		hash, ok := value.(*object.HashObject)
+
+		if !ok {
+			fail(fmt.Errorf("%s: expected a JSON object, got %s", *data, value.Type()))
+		}
+
+		for _, pair := range hash.Pairs {
+			// Arguments win over data.
+			if _, ok := env.Get(pair.Key.(*object.StringObject).Value); !ok {
+				env.Set(pair.Key.(*object.StringObject).Value, pair.Value)
+			}
+		}
+	}
+
+	path := flags.Arg(0)
+	name := displayName(path)
+
+	if path != "-" {
+		name = filepath.Clean(path)
+	}
+
+	parsed, errors := template.Parse(name, readSource(path))
+
+	if len(errors) != 0 {
+		reportErrors(path, errors)
+		os.Exit(1)
+	}
+
+	// The output is only written once the template rendered.
+	var out bytes.Buffer
+	renderer := template.NewRenderer(&out)
+	renderer.HTML = *escape
+	renderer.Evaluator.StrictIntegers = *strictIntegers
+	renderer.Load = func(name string) (string, error) {
+		source, err := os.ReadFile(name)
+		return string(source), err
+	}
+
+	if err := renderer.Render(parsed, env); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	os.Stdout.Write(out.Bytes())
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"monkey/token"
+	"monkey/tokenizer"
+	"os"
+)
+
+// runTokens implements `monkey tokens [--trivia] file`.
+func runTokens(args []string) {
+	flags := newFlagSet("tokens", "[--trivia] file|-")
+	trivia := flags.Bool("trivia", false, "print whitespace and comments too")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	source := openSource(flags.Arg(0))
+	defer source.Close()
+
+	tok := tokenizer.NewFromReader(source)
+	next := tok.NextToken
+
+	if *trivia {
+		next = tok.Scan
+	}
+
+	for t := next(); t.Type != token.EOF; t = next() {
+		fmt.Printf("%+v\n", t)
+	}
+
+	if len(tok.Errors) != 0 {
+		reportErrors(flags.Arg(0), tok.Errors)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+)
+
+// version is set when building releases, with
+// -ldflags "-X main.version=1.2.3".
+var version = "devel"
+
+// runVersion implements `monkey version`.
+func runVersion(args []string) {
+	flags := newFlagSet("version", "")
+	flags.Parse(args)
+	expectFiles(flags, 0, 0)
+
+	fmt.Printf("monkey %s %s/%s %s\n", version, runtime.GOOS, runtime.GOARCH, runtime.Version())
+}
+
+// runCompile implements `monkey compile file`. Programs are only
+// interpreted for now, so it checks the syntax of the file and fails as
+// not implemented.
+func runCompile(args []string) {
+	flags := newFlagSet("compile", "[-o output] file|-")
+	flags.String("o", "", "the file to write the compiled program to")
+	flags.Parse(args)
+	expectFiles(flags, 1, 1)
+
+	parseFile(flags.Arg(0))
+	fail(errors.New("compile is not implemented yet, use monkey run"))
+}
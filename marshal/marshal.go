@@ -0,0 +1,265 @@
+// Package marshal converts monkey values to data formats and back.
+//
+// Hashes become objects with their keys sorted, arrays become lists, and
+// integers, floats, strings, booleans and null are kept as they are. Other
+// values such as functions can't be converted, the error tells where they
+// are in the value, as in .services[2].handler.
+package marshal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"monkey/evaluator"
+	"monkey/object"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Error is a value that can't be converted, Path is where it is from the
+// value converted, . being the value itself.
+type Error struct {
+	Path string
+	Type object.ObjectType
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%s: %s values can't be serialised", err.Path, err.Type)
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// childPath is the path of the value at key in the hash at path.
+func childPath(path string, key string) string {
+	if identifier.MatchString(key) {
+		return path + "." + key
+	}
+
+	return path + fmt.Sprintf("[%q]", key)
+}
+
+// rootPath is how path is shown, paths starting with an index are written
+// after a dot as in .[0].
+func rootPath(path string) string {
+	if !strings.HasPrefix(path, ".") {
+		return "." + path
+	}
+
+	return path
+}
+
+// ToGo converts obj to the Go values encoding/json works with: nil, bool,
+// json.Number, float64, string, []interface{} and map[string]interface{}.
+// The keys of hashes are converted to strings.
+func ToGo(obj object.Object) (interface{}, error) {
+	return toGo(obj, "")
+}
+
+func toGo(obj object.Object, path string) (interface{}, error) {
+	switch obj := obj.(type) {
+	case *object.NullObject:
+		return nil, nil
+
+	case *object.BooleanObject:
+		return obj.Value, nil
+
+	case *object.IntegerObject, *object.BigIntegerObject:
+		return json.Number(obj.Inspect()), nil
+
+	case *object.FloatObject:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return nil, &Error{Path: rootPath(path), Type: object.ObjectType(obj.Inspect())}
+		}
+
+		return obj.Value, nil
+
+	case *object.StringObject:
+		return obj.Value, nil
+
+	case *object.ArrayObject:
+		elements := []interface{}{}
+
+		for i, element := range obj.Elements {
+			converted, err := toGo(element, fmt.Sprintf("%s[%d]", path, i))
+
+			if err != nil {
+				return nil, err
+			}
+
+			elements = append(elements, converted)
+		}
+
+		return elements, nil
+
+	case *object.HashObject:
+		pairs := map[string]interface{}{}
+
+		for _, pair := range obj.Sorted() {
+			key := pair.Key.Inspect()
+
+			if str, ok := pair.Key.(*object.StringObject); ok {
+				key = str.Value
+			}
+
+			if _, ok := pairs[key]; ok {
+				return nil, fmt.Errorf("%s: duplicate key %q", rootPath(path), key)
+			}
+
+			converted, err := toGo(pair.Value, childPath(path, key))
+
+			if err != nil {
+				return nil, err
+			}
+
+			pairs[key] = converted
+		}
+
+		return pairs, nil
+
+	default:
+		return nil, &Error{Path: rootPath(path), Type: obj.Type()}
+	}
+}
+
+// FromGo converts values decoded by encoding/json, with numbers decoded as
+// json.Number, to monkey values.
+func FromGo(value interface{}) object.Object {
+	switch value := value.(type) {
+	case bool:
+		if value {
+			return evaluator.True
+		}
+
+		return evaluator.False
+
+	case json.Number:
+		if integer, ok := new(big.Int).SetString(string(value), 10); ok {
+			return object.NewInteger(integer)
+		}
+
+		float, _ := value.Float64()
+
+		return &object.FloatObject{Value: float}
+
+	case float64:
+		return &object.FloatObject{Value: value}
+
+	case string:
+		return &object.StringObject{Value: value}
+
+	case []interface{}:
+		elements := []object.Object{}
+
+		for _, element := range value {
+			elements = append(elements, FromGo(element))
+		}
+
+		return &object.ArrayObject{Elements: elements}
+
+	case map[string]interface{}:
+		hash := object.NewHash()
+
+		for key, element := range value {
+			str := &object.StringObject{Value: key}
+			hash.Pairs[str.HashKey()] = object.HashPair{Key: str, Value: FromGo(element)}
+		}
+
+		return hash
+
+	default:
+		return evaluator.Null
+	}
+}
+
+/* --- JSON ----------------------------------------------------------------- */
+
+// JSON encodes obj, indented with indent unless it's empty.
+func JSON(obj object.Object, indent string) ([]byte, error) {
+	value, err := ToGo(obj)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", indent)
+
+	if err := encoder.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// ParseJSON decodes data, which must hold a single JSON document. With
+// strictIntegers, integers that don't fit in 64 bits are an error instead of
+// big integers, as in the strict mode of the evaluator.
+func ParseJSON(data []byte, strictIntegers bool) (object.Object, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+
+	if err := decoder.Decode(&value); err == io.EOF {
+		return nil, fmt.Errorf("no JSON document")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var rest interface{}
+
+	if err := decoder.Decode(&rest); err == nil {
+		return nil, fmt.Errorf("more than one JSON document")
+	} else if err != io.EOF {
+		return nil, err
+	}
+
+	if strictIntegers {
+		if err := checkIntegers(value, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	return FromGo(value), nil
+}
+
+// checkIntegers reports the first integer of the decoded value that doesn't
+// fit in 64 bits, path being where value is.
+func checkIntegers(value interface{}, path string) error {
+	switch value := value.(type) {
+	case json.Number:
+		if integer, ok := new(big.Int).SetString(string(value), 10); ok && !integer.IsInt64() {
+			return fmt.Errorf("%s: integer overflow: %s doesn't fit in 64 bits", rootPath(path), value)
+		}
+
+	case []interface{}:
+		for i, element := range value {
+			if err := checkIntegers(element, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+
+	case map[string]interface{}:
+		keys := []string{}
+
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if err := checkIntegers(value[key], childPath(path, key)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package marshal
+
+import (
+	"monkey/evaluator"
+	"monkey/object"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"testing"
+)
+
+func eval(t *testing.T, input string) object.Object {
+	parser := parser.New(tokenizer.New(input))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		t.Fatalf("%q has parser errors: %v", input, parser.Errors)
+	}
+
+	return evaluator.Eval(program, object.NewEnvironment())
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = {"b": [1, 2.5, "<a>"], "a": {1: true, "c": false}}; x;`, `{"a":{"1":true,"c":false},"b":[1,2.5,"<a>"]}`},
+		{`2 ** 70;`, `1180591620717411303424`},
+		{`if (false) { 1; };`, `null`},
+		{`[];`, `[]`},
+		{`let x = {}; x;`, `{}`},
+	}
+
+	for _, test := range tests {
+		actual, err := JSON(eval(t, test.input), "")
+
+		if err != nil || string(actual) != test.expected {
+			t.Errorf("%q - expected %s, got %s %v", test.input, test.expected, actual, err)
+		}
+	}
+}
+
+func TestJSONErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = {"services": [1, 2, {"handler": function() {}}]}; x;`, ".services[2].handler: Function values can't be serialised"},
+		{`[puts];`, ".[0]: Builtin values can't be serialised"},
+		{`let x = {"a b": {"c": len}}; x;`, `.["a b"].c: Builtin values can't be serialised`},
+		{`let x = {1: 1, "1": 2}; x;`, `.: duplicate key "1"`},
+	}
+
+	for _, test := range tests {
+		if _, err := JSON(eval(t, test.input), ""); err == nil || err.Error() != test.expected {
+			t.Errorf("%q - expected the error %q, got %v", test.input, test.expected, err)
+		}
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": [1, 2.5, -3e2, null, true], "b": {"c": "d"}}`, `{"a": [1, 2.5, -300.0, null, true], "b": {"c": "d"}}`},
+		{`123456789012345678901234567890`, `123456789012345678901234567890`},
+		{` "x" `, `"x"`},
+	}
+
+	for _, test := range tests {
+		value, err := ParseJSON([]byte(test.input), false)
+
+		if err != nil || value.Inspect() != test.expected {
+			t.Errorf("%q - expected %s, got %v %v", test.input, test.expected, value, err)
+		}
+	}
+
+	if value, _ := ParseJSON([]byte("null"), false); value != evaluator.Null {
+		t.Errorf("expected null to decode as evaluator.Null, got %v", value)
+	}
+
+	for _, input := range []string{"", "{", "1 2", "[1,]"} {
+		if _, err := ParseJSON([]byte(input), false); err == nil {
+			t.Errorf("%q - expected an error", input)
+		}
+	}
+
+	strict := []struct {
+		input    string
+		expected string
+	}{
+		{`12345678901234567890123`, ".: integer overflow: 12345678901234567890123 doesn't fit in 64 bits"},
+		{`{"b": [1, 9223372036854775808], "a": 2}`, ".b[1]: integer overflow: 9223372036854775808 doesn't fit in 64 bits"},
+	}
+
+	for _, test := range strict {
+		if _, err := ParseJSON([]byte(test.input), true); err == nil || err.Error() != test.expected {
+			t.Errorf("%q - expected the error %q, got %v", test.input, test.expected, err)
+		}
+	}
+
+	if value, err := ParseJSON([]byte(`[9223372036854775807, 1e30]`), true); err != nil {
+		t.Errorf("expected integers that fit and floats to decode in strict mode, got %v %v", value, err)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"text";`, `text`},
+		{`let x = {}; [x, [], 1.5, true];`, "- {}\n- []\n- 1.5\n- true"},
+		{
+			`let x = {"name": "api", "ports": [80, 443], "env": {"DEBUG": "yes", "a b": "", "9": "1.0"}, "jobs": [{"id": 1, "args": ["-v"]}, [1, [2]]]}; x;`,
+			"env:\n  \"9\": \"1.0\"\n  DEBUG: \"yes\"\n  a b: \"\"\njobs:\n  - args:\n      - \"-v\"\n    id: 1\n  - - 1\n    - - 2\nname: api\nports:\n  - 80\n  - 443",
+		},
+	}
+
+	for _, test := range tests {
+		actual, err := YAML(eval(t, test.input))
+
+		if err != nil || string(actual) != test.expected {
+			t.Errorf("%q - expected\n%s\ngot\n%s %v", test.input, test.expected, actual, err)
+		}
+	}
+
+	if _, err := YAML(eval(t, `[1, len];`)); err == nil || err.Error() != ".[1]: Builtin values can't be serialised" {
+		t.Errorf("expected a path error, got %v", err)
+	}
+}
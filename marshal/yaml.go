@@ -0,0 +1,114 @@
+package marshal
+
+import (
+	"encoding/json"
+	"monkey/object"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// plain matches the strings written in YAML without quotes, others are
+// written as JSON strings, which YAML reads the same way.
+var plain = regexp.MustCompile(`^[A-Za-z_/][A-Za-z0-9_ ./-]*$`)
+
+// reserved are the plain strings YAML reads as other values.
+var reserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"y": true, "n": true, "null": true,
+}
+
+// YAML encodes obj as a YAML document, with the keys of hashes sorted.
+func YAML(obj object.Object) ([]byte, error) {
+	value, err := ToGo(obj)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+
+	if scalar, ok := yamlScalar(value); ok {
+		lines = append(lines, scalar)
+	} else {
+		lines = yamlBlock(value)
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
+
+// yamlScalar returns how value is written on one line, it isn't for arrays
+// and hashes with elements.
+func yamlScalar(value interface{}) (string, bool) {
+	switch value := value.(type) {
+	case nil:
+		return "null", true
+
+	case string:
+		if plain.MatchString(value) && !strings.HasSuffix(value, " ") && !reserved[strings.ToLower(value)] {
+			return value, true
+		}
+
+		quoted, _ := json.Marshal(value)
+		return string(quoted), true
+
+	case []interface{}:
+		return "[]", len(value) == 0
+
+	case map[string]interface{}:
+		return "{}", len(value) == 0
+
+	default:
+		encoded, _ := json.Marshal(value)
+		return string(encoded), true
+	}
+}
+
+// yamlBlock returns the lines of an array or a hash with elements, unindented.
+func yamlBlock(value interface{}) []string {
+	lines := []string{}
+
+	switch value := value.(type) {
+	case []interface{}:
+		for _, element := range value {
+			if scalar, ok := yamlScalar(element); ok {
+				lines = append(lines, "- "+scalar)
+				continue
+			}
+
+			for i, line := range yamlBlock(element) {
+				if i == 0 {
+					lines = append(lines, "- "+line)
+				} else {
+					lines = append(lines, "  "+line)
+				}
+			}
+		}
+
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			name, _ := yamlScalar(key)
+
+			if scalar, ok := yamlScalar(value[key]); ok {
+				lines = append(lines, name+": "+scalar)
+				continue
+			}
+
+			lines = append(lines, name+":")
+
+			for _, line := range yamlBlock(value[key]) {
+				lines = append(lines, "  "+line)
+			}
+		}
+	}
+
+	return lines
+}
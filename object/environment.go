@@ -0,0 +1,71 @@
+package object
+
+import "sort"
+
+type Environment struct {
+	store map[string]Object
+	outer *Environment
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+
+	return env
+}
+
+// Get looks name up in this environment and then in the enclosing ones.
+func (env *Environment) Get(name string) (Object, bool) {
+	obj, ok := env.store[name]
+
+	if !ok && env.outer != nil {
+		obj, ok = env.outer.Get(name)
+	}
+
+	return obj, ok
+}
+
+// Set binds name in this environment, shadowing any outer binding.
+func (env *Environment) Set(name string, value Object) Object {
+	env.store[name] = value
+
+	return value
+}
+
+// Assign updates an existing binding in the environment where it was
+// defined, it returns false when name isn't bound anywhere.
+func (env *Environment) Assign(name string, value Object) bool {
+	if _, ok := env.store[name]; ok {
+		env.store[name] = value
+		return true
+	}
+
+	if env.outer != nil {
+		return env.outer.Assign(name, value)
+	}
+
+	return false
+}
+
+// Outer returns the enclosing environment, nil for the outermost one.
+func (env *Environment) Outer() *Environment {
+	return env.outer
+}
+
+// Names returns the names bound in this environment, sorted, without the
+// ones of the enclosing environments.
+func (env *Environment) Names() []string {
+	names := make([]string, 0, len(env.store))
+
+	for name := range env.store {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -1,12 +1,31 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/big"
+	"monkey/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
 
 type ObjectType string
 
 const (
-	ObjectInteger = "Integer"
-	ObjectBoolean = "Boolean"
+	ObjectInteger     = "Integer"
+	ObjectFloat       = "Float"
+	ObjectBoolean     = "Boolean"
+	ObjectNull        = "Null"
+	ObjectReturnValue = "ReturnValue"
+	ObjectError       = "Error"
+	ObjectFunction    = "Function"
+	ObjectString      = "String"
+	ObjectArray       = "Array"
+	ObjectBuiltin     = "Builtin"
+	ObjectHash        = "Hash"
 )
 
 type Object interface {
@@ -14,6 +33,20 @@ type Object interface {
 	Inspect() string
 }
 
+// HashKey identifies the value of a Hashable object, objects that are equal
+// have the same HashKey. Big integers are keyed by their decimal digits in
+// Text so that they can't collide with the integers that fit in Value.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+	Text  string
+}
+
+type Hashable interface {
+	Object
+	HashKey() HashKey
+}
+
 /* --- Integer Object ------------------------------------------------------- */
 
 type IntegerObject struct {
@@ -28,6 +61,62 @@ func (obj *IntegerObject) Inspect() string {
 	return fmt.Sprintf("%d", obj.Value)
 }
 
+func (obj *IntegerObject) HashKey() HashKey {
+	return HashKey{Type: obj.Type(), Value: uint64(obj.Value)}
+}
+
+/* --- Big Integer Object --------------------------------------------------- */
+
+// BigIntegerObject holds integers that don't fit in an int64. It is the same
+// type as IntegerObject from the language point of view, use NewInteger to
+// build one so values that fit are always represented by an IntegerObject.
+type BigIntegerObject struct {
+	Value *big.Int
+}
+
+// NewInteger returns value as an IntegerObject if it fits in an int64 and as
+// a BigIntegerObject otherwise.
+func NewInteger(value *big.Int) Object {
+	if value.IsInt64() {
+		return &IntegerObject{Value: value.Int64()}
+	}
+
+	return &BigIntegerObject{Value: value}
+}
+
+func (obj *BigIntegerObject) Type() ObjectType {
+	return ObjectInteger
+}
+
+func (obj *BigIntegerObject) Inspect() string {
+	return obj.Value.String()
+}
+
+func (obj *BigIntegerObject) HashKey() HashKey {
+	return HashKey{Type: obj.Type(), Text: obj.Value.String()}
+}
+
+/* --- Float Object --------------------------------------------------------- */
+
+type FloatObject struct {
+	Value float64
+}
+
+func (obj *FloatObject) Type() ObjectType {
+	return ObjectFloat
+}
+
+func (obj *FloatObject) Inspect() string {
+	str := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+
+	// Keep floats distinguishable from integers once printed.
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+
+	return str
+}
+
 /* --- Boolean Object ------------------------------------------------------- */
 
 type BooleanObject struct {
@@ -45,3 +134,212 @@ func (obj *BooleanObject) Inspect() string {
 		return "false"
 	}
 }
+
+func (obj *BooleanObject) HashKey() HashKey {
+	if obj.Value {
+		return HashKey{Type: obj.Type(), Value: 1}
+	}
+
+	return HashKey{Type: obj.Type(), Value: 0}
+}
+
+/* --- Null Object ---------------------------------------------------------- */
+
+type NullObject struct{}
+
+func (obj *NullObject) Type() ObjectType {
+	return ObjectNull
+}
+
+func (obj *NullObject) Inspect() string {
+	return "null"
+}
+
+/* --- Return Value Object -------------------------------------------------- */
+
+type ReturnValueObject struct {
+	Value Object
+}
+
+func (obj *ReturnValueObject) Type() ObjectType {
+	return ObjectReturnValue
+}
+
+func (obj *ReturnValueObject) Inspect() string {
+	return obj.Value.Inspect()
+}
+
+/* --- Error Object --------------------------------------------------------- */
+
+type ErrorObject struct {
+	Message string
+}
+
+func (obj *ErrorObject) Type() ObjectType {
+	return ObjectError
+}
+
+func (obj *ErrorObject) Inspect() string {
+	return "error: " + obj.Message
+}
+
+/* --- Function Object ------------------------------------------------------ */
+
+type FunctionObject struct {
+	Parameters  []*ast.IdentifierLiteral
+	Body        *ast.BlockStatement
+	Environment *Environment
+}
+
+func (obj *FunctionObject) Type() ObjectType {
+	return ObjectFunction
+}
+
+func (obj *FunctionObject) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, param := range obj.Parameters {
+		params = append(params, param.String())
+	}
+
+	out.WriteString("function(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(obj.Body.String())
+
+	return out.String()
+}
+
+/* --- String Object -------------------------------------------------------- */
+
+type StringObject struct {
+	Value string
+}
+
+func (obj *StringObject) Type() ObjectType {
+	return ObjectString
+}
+
+// Inspect quotes the string the way it is written in the source, puts
+// writes its raw value.
+func (obj *StringObject) Inspect() string {
+	return strconv.Quote(obj.Value)
+}
+
+func (obj *StringObject) HashKey() HashKey {
+	hash := fnv.New64a()
+	hash.Write([]byte(obj.Value))
+
+	return HashKey{Type: obj.Type(), Value: hash.Sum64()}
+}
+
+/* --- Array Object --------------------------------------------------------- */
+
+type ArrayObject struct {
+	Elements []Object
+}
+
+func (obj *ArrayObject) Type() ObjectType {
+	return ObjectArray
+}
+
+func (obj *ArrayObject) Inspect() string {
+	elements := []string{}
+
+	for _, element := range obj.Elements {
+		elements = append(elements, element.Inspect())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+/* --- Hash Object --------------------------------------------------------- */
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type HashObject struct {
+	Pairs map[HashKey]HashPair
+}
+
+func NewHash() *HashObject {
+	return &HashObject{Pairs: map[HashKey]HashPair{}}
+}
+
+func (obj *HashObject) Type() ObjectType {
+	return ObjectHash
+}
+
+// Sorted returns the pairs ordered by key, so hashes print the same way
+// every time: keys of the same type by value and then by type.
+func (obj *HashObject) Sorted() []HashPair {
+	pairs := make([]HashPair, 0, len(obj.Pairs))
+
+	for _, pair := range obj.Pairs {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return lessKey(pairs[i].Key, pairs[j].Key)
+	})
+
+	return pairs
+}
+
+func lessKey(a Object, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	switch a := a.(type) {
+	case *StringObject:
+		return a.Value < b.(*StringObject).Value
+	case *BooleanObject:
+		return !a.Value && b.(*BooleanObject).Value
+	}
+
+	return toBigInt(a).Cmp(toBigInt(b)) < 0
+}
+
+func toBigInt(obj Object) *big.Int {
+	switch obj := obj.(type) {
+	case *IntegerObject:
+		return big.NewInt(obj.Value)
+	case *BigIntegerObject:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+func (obj *HashObject) Inspect() string {
+	pairs := []string{}
+
+	for _, pair := range obj.Sorted() {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+/* --- Builtin Object ------------------------------------------------------- */
+
+// BuiltinFunction implements a function provided by the interpreter, out is
+// where the program writes its output. The error is reported at the call.
+type BuiltinFunction func(out io.Writer, args []Object) (Object, error)
+
+type BuiltinObject struct {
+	Name     string
+	Function BuiltinFunction
+}
+
+func (obj *BuiltinObject) Type() ObjectType {
+	return ObjectBuiltin
+}
+
+func (obj *BuiltinObject) Inspect() string {
+	return "builtin " + obj.Name
+}
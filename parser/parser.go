@@ -2,45 +2,45 @@ package parser
 
 import (
 	"fmt"
+	"math/big"
 	"monkey/ast"
 	"monkey/token"
 	"monkey/tokenizer"
 	"strconv"
-	"testing"
 )
 
 type Parser struct {
-	depth int
-	t     *testing.T
+	tracer Tracer
 
-	tokenizer    *tokenizer.Tokenizer
-	Errors       []string
-	currentToken token.Token
-	peekToken    token.Token
+	tokenizer       *tokenizer.Tokenizer
+	tokenizerErrors int
+	tokens          *tokenizer.Buffer
+	Errors          []string
+	currentToken    token.Token
+	peekToken       token.Token
 }
 
-func New(tokenizer *tokenizer.Tokenizer) *Parser {
-	parser := &Parser{tokenizer: tokenizer}
+func New(source *tokenizer.Tokenizer) *Parser {
+	parser := &Parser{tokenizer: source, tokens: tokenizer.NewBuffer(source)}
 
 	parser.nextToken()
 	parser.nextToken()
 
-	parser.depth = 0
-	parser.t = nil
-
 	return parser
 }
 
-func NewWithTest(tokenizer *tokenizer.Tokenizer, t *testing.T) *Parser {
+// NewWithTracer creates a parser reporting each grammar rule it goes through
+// to tracer.
+func NewWithTracer(tokenizer *tokenizer.Tokenizer, tracer Tracer) *Parser {
 	parser := New(tokenizer)
 
-	parser.t = t
+	parser.tracer = tracer
 
 	return parser
 }
 
 func (parser *Parser) Parse() *ast.Program {
-	parser.trace("Parsing")
+	parser.trace("Parse")
 
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
@@ -62,7 +62,22 @@ func (parser *Parser) Parse() *ast.Program {
 
 func (parser *Parser) nextToken() {
 	parser.currentToken = parser.peekToken
-	parser.peekToken = parser.tokenizer.NextToken()
+	parser.peekToken = parser.tokens.Next()
+
+	// Surface encoding errors found by the tokenizer along with ours.
+	for ; parser.tokenizerErrors < len(parser.tokenizer.Errors); parser.tokenizerErrors++ {
+		parser.Errors = append(parser.Errors, parser.tokenizer.Errors[parser.tokenizerErrors])
+	}
+}
+
+// peekTokenAt looks n tokens past the current one, peekTokenAt(1) is the
+// peek token.
+func (parser *Parser) peekTokenAt(n int) token.Token {
+	if n <= 1 {
+		return parser.peekToken
+	}
+
+	return parser.tokens.Peek(n - 2)
 }
 
 func (parser *Parser) expectPeek(t token.TokenType) bool {
@@ -112,7 +127,7 @@ func (parser *Parser) parseStatement() ast.Statement {
 		statement = parser.parseLetStatement()
 	} else if parser.currentTokenIs(token.Return) {
 		statement = parser.parseReturnStatement()
-	} else if parser.currentTokenIs(token.OpeningBrace) {
+	} else if parser.currentTokenIs(token.OpeningBrace) && !StartsHashLiteral(parser.peekTokenAt) {
 		statement = parser.parseBlockStatement()
 	} else {
 		statement = parser.parseExpressionStatement()
@@ -127,6 +142,41 @@ func (parser *Parser) parseStatement() ast.Statement {
 	return statement
 }
 
+// StartsHashLiteral tells whether the { opening a statement starts a hash
+// rather than a block, peek(n) being the nth token after it. It does when it
+// is followed by } or by an expression and a colon, the statements of a block
+// having no colon outside of brackets.
+func StartsHashLiteral(peek func(n int) token.Token) bool {
+	if peek(1).Type == token.ClosingBrace {
+		return true
+	}
+
+	depth := 0
+
+	for n := 1; ; n++ {
+		switch peek(n).Type {
+		case token.OpeningBrace, token.OpeningParenthesis, token.OpeningBracket:
+			depth++
+		case token.ClosingBrace, token.ClosingParenthesis, token.ClosingBracket:
+			if depth == 0 {
+				return false
+			}
+
+			depth--
+		case token.Colon:
+			if depth == 0 {
+				return true
+			}
+		case token.Semicolon:
+			if depth == 0 {
+				return false
+			}
+		case token.EOF:
+			return false
+		}
+	}
+}
+
 func (parser *Parser) parseLetStatement() *ast.LetStatement {
 	parser.trace("parseLetStatement")
 
@@ -150,7 +200,7 @@ func (parser *Parser) parseLetStatement() *ast.LetStatement {
 		parser.nextToken()
 	}
 
-	statement.Expression = parser.parseExpression(PrecedenceLowest)
+	statement.Expression = parser.parseRequiredExpression(PrecedenceLowest)
 
 	if parser.peekTokenIs(token.Semicolon) {
 		parser.nextToken()
@@ -209,7 +259,8 @@ func (parser *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: parser.currentToken}
 	stmt.Expression = parser.parseExpression(PrecedenceLowest)
 
-	if parser.peekTokenIs(token.Semicolon) {
+	// An empty statement is its own semicolon, it doesn't take the next one.
+	if !parser.currentTokenIs(token.Semicolon) && parser.peekTokenIs(token.Semicolon) {
 		parser.nextToken()
 	}
 
@@ -228,6 +279,9 @@ const (
 	PrecedenceSum
 	PrecedenceProduct
 	PrecedencePrefix
+	PrecedencePower
+	PrecedencePostfix
+	PrecedenceCall
 )
 
 type (
@@ -257,11 +311,21 @@ func init() {
 		token.Slash:    PrecedenceProduct,
 
 		token.Bang: PrecedencePrefix,
+
+		token.Power: PrecedencePower,
+
+		token.Increment: PrecedencePostfix,
+		token.Decrement: PrecedencePostfix,
+
+		token.OpeningParenthesis: PrecedenceCall,
+		token.OpeningBracket:     PrecedenceCall,
 	}
 
 	prefixParseFunctions = map[token.TokenType]prefixParseFunction{
 		token.Identifier: parseIdentifierLiteral,
 		token.Integer:    parseIntergerLiteral,
+		token.Float:      parseFloatLiteral,
+		token.String:     parseStringLiteral,
 		token.True:       parseBoolLiteral,
 		token.False:      parseBoolLiteral,
 		token.Function:   parseFunctionLiteral,
@@ -270,14 +334,19 @@ func init() {
 		token.Plus:  parsePrefixOperatorExpression,
 		token.Minus: parsePrefixOperatorExpression,
 
+		token.Increment: parsePrefixOperatorExpression,
+		token.Decrement: parsePrefixOperatorExpression,
+
 		token.OpeningParenthesis: parseGroupedExpression,
+		token.OpeningBracket:     parseArrayLiteral,
+		token.OpeningBrace:       parseHashLiteral,
 		token.If:                 parseIfExpression,
 		token.While:              parseWhileExpression,
 	}
 
 	infixParseFunctions = map[token.TokenType]infixParseFunction{
 		token.And: parseInfixOperatorExpression,
-		token.Not: parseInfixOperatorExpression,
+		token.Or:  parseInfixOperatorExpression,
 
 		token.Equal:    parseInfixOperatorExpression,
 		token.NotEqual: parseInfixOperatorExpression,
@@ -290,7 +359,26 @@ func init() {
 
 		token.Asterisk: parseInfixOperatorExpression,
 		token.Slash:    parseInfixOperatorExpression,
+
+		token.Power: parseInfixOperatorExpression,
+
+		token.Increment: parsePostfixOperatorExpression,
+		token.Decrement: parsePostfixOperatorExpression,
+
+		token.OpeningParenthesis: parseCallExpression,
+		token.OpeningBracket:     parseIndexExpression,
+	}
+}
+
+// InfixPrecedence returns the precedence of a token following an expression,
+// and whether it continues the expression as an infix or postfix operator.
+func InfixPrecedence(t token.TokenType) (int, bool) {
+	precedence, ok := precedences[t]
+	if !ok {
+		return PrecedenceLowest, false
 	}
+
+	return precedence, infixParseFunctions[t] != nil
 }
 
 func (parser *Parser) peekPrecedence() int {
@@ -348,12 +436,23 @@ func (parser *Parser) parseExpression(precedences int) ast.Expression {
 	return leftExp
 }
 
+// parseRequiredExpression parses an expression that can't be left out,
+// parseExpression takes a semicolon for an empty expression without an error.
+func (parser *Parser) parseRequiredExpression(precedences int) ast.Expression {
+	if parser.currentTokenIs(token.Semicolon) {
+		parser.errorf(parser.currentToken, "expected an expression")
+		return nil
+	}
+
+	return parser.parseExpression(precedences)
+}
+
 func parseGroupedExpression(parser *Parser) ast.Expression {
 	parser.trace("parseGroupedExpression")
 
 	parser.nextToken()
 
-	exp := parser.parseExpression(PrecedenceLowest)
+	exp := parser.parseRequiredExpression(PrecedenceLowest)
 
 	if !parser.expectPeek(token.ClosingParenthesis) {
 
@@ -378,7 +477,7 @@ func parseIfExpression(parser *Parser) ast.Expression {
 
 	parser.nextToken()
 
-	expression.Condition = parser.parseExpression(PrecedenceLowest)
+	expression.Condition = parser.parseRequiredExpression(PrecedenceLowest)
 
 	if !parser.expectPeek(token.ClosingParenthesis) {
 		parser.untrace("parseIfExpression")
@@ -419,7 +518,7 @@ func parseWhileExpression(parser *Parser) ast.Expression {
 
 	parser.nextToken()
 
-	expression.Condition = parser.parseExpression(PrecedenceLowest)
+	expression.Condition = parser.parseRequiredExpression(PrecedenceLowest)
 
 	if !parser.expectPeek(token.ClosingParenthesis) {
 		parser.untrace("parseWhileExpression")
@@ -446,7 +545,11 @@ func parsePrefixOperatorExpression(parser *Parser) ast.Expression {
 	}
 
 	parser.nextToken()
-	expression.Right = parser.parseExpression(PrecedencePrefix)
+	expression.Right = parser.parseRequiredExpression(PrecedencePrefix)
+
+	if isUpdateOperator(expression.Token.Type) && expression.Right != nil {
+		parser.expectAssignable(expression.Token, expression.Right)
+	}
 
 	parser.untrace("parsePrefixOperatorExpression")
 	return expression
@@ -462,25 +565,49 @@ func parseInfixOperatorExpression(parser *Parser, left ast.Expression) ast.Expre
 	}
 
 	precedences := parser.currentPrecedence()
+
+	// ** is right associative, 2 ** 3 ** 2 is 2 ** (3 ** 2).
+	if parser.currentTokenIs(token.Power) {
+		precedences--
+	}
+
 	parser.nextToken()
-	expression.Right = parser.parseExpression(precedences)
+	expression.Right = parser.parseRequiredExpression(precedences)
 
 	parser.untrace("parseInfixOperatorExpression")
 	return expression
 }
 
 func parsePostfixOperatorExpression(parser *Parser, left ast.Expression) ast.Expression {
+	parser.trace("parsePostfixOperatorExpression")
+
 	expression := &ast.PostfixOperatorExpression{
 		Token:    parser.currentToken,
 		Operator: parser.currentToken.Literal,
 		Left:     left,
 	}
 
-	parser.nextToken()
+	parser.expectAssignable(expression.Token, expression.Left)
 
+	parser.untrace("parsePostfixOperatorExpression")
 	return expression
 }
 
+func isUpdateOperator(t token.TokenType) bool {
+	return t == token.Increment || t == token.Decrement
+}
+
+// expectAssignable reports an error when target can't be the operand of an
+// update operator such as ++ or --, only identifiers can be assigned to.
+func (parser *Parser) expectAssignable(operator token.Token, target ast.Expression) bool {
+	if _, ok := target.(*ast.IdentifierLiteral); ok {
+		return true
+	}
+
+	parser.errorf(operator, "invalid operand for %s, expected an identifier", operator.Literal)
+	return false
+}
+
 func unexpectedInfixToken(parser *Parser, left ast.Expression) ast.Expression {
 	parser.errorf(parser.currentToken, "unexpected infix token %s", parser.currentToken.Type)
 	return nil
@@ -493,12 +620,148 @@ func parseIdentifierLiteral(parser *Parser) ast.Expression {
 }
 
 func parseIntergerLiteral(parser *Parser) ast.Expression {
-	value, ok := strconv.ParseInt(parser.currentToken.Literal, 0, 64)
-	if ok == nil {
+	if IsLegacyOctal(parser.currentToken.Literal) {
+		parser.errorf(parser.currentToken, "leading zero in integer literal %s, octal integers start with 0o", parser.currentToken.Literal)
+		return nil
+	}
+
+	value, err := strconv.ParseInt(parser.currentToken.Literal, 0, 64)
+	if err == nil {
 		return &ast.IntegerLiteral{Token: parser.currentToken, Value: value}
+	}
+
+	if numError, ok := err.(*strconv.NumError); ok && numError.Err == strconv.ErrRange {
+		if value, ok := new(big.Int).SetString(parser.currentToken.Literal, 0); ok {
+			return &ast.IntegerLiteral{Token: parser.currentToken, Big: value}
+		}
+	}
+
+	parser.numberLiteralError(err, "integer")
+	return nil
+}
+
+// IsLegacyOctal reports whether the integer literal starts with a zero
+// followed by digits, which Go and C read as octal. The language spells
+// octal integers 0o17 and leaves 017 out rather than read it as 17.
+func IsLegacyOctal(literal string) bool {
+	return len(literal) > 1 && literal[0] == '0' && ('0' <= literal[1] && literal[1] <= '9' || literal[1] == '_')
+}
+
+func parseFloatLiteral(parser *Parser) ast.Expression {
+	value, err := strconv.ParseFloat(parser.currentToken.Literal, 64)
+	if err == nil {
+		return &ast.FloatLiteral{Token: parser.currentToken, Value: value}
+	}
+
+	parser.numberLiteralError(err, "float")
+	return nil
+}
+
+func (parser *Parser) numberLiteralError(err error, kind string) {
+	if numError, ok := err.(*strconv.NumError); ok && numError.Err == strconv.ErrRange {
+		parser.errorf(parser.currentToken, "%s literal %s is out of range", kind, parser.currentToken.Literal)
 	} else {
+		parser.errorf(parser.currentToken, "malformed %s literal %s", kind, parser.currentToken.Literal)
+	}
+}
+
+func parseStringLiteral(parser *Parser) ast.Expression {
+	value, err := Unquote(parser.currentToken.Literal)
+
+	if err != nil {
+		parser.errorf(parser.currentToken, "%s", err)
 		return nil
 	}
+
+	return &ast.StringLiteral{Token: parser.currentToken, Value: value}
+}
+
+// Unquote decodes the literal of a String token, the tokenizer leaves it as
+// it is in the source, without checking its quotes and escapes.
+func Unquote(literal string) (string, error) {
+	backslashes := 0
+
+	for i := len(literal) - 2; i > 0 && literal[i] == '\\'; i-- {
+		backslashes++
+	}
+
+	// The closing quote is missing, or escaped.
+	if len(literal) < 2 || literal[len(literal)-1] != '"' || backslashes%2 == 1 {
+		return "", fmt.Errorf("unterminated string literal")
+	}
+
+	value, err := strconv.Unquote(literal)
+
+	if err != nil {
+		return "", fmt.Errorf("malformed string literal %s", literal)
+	}
+
+	return value, nil
+}
+
+func parseArrayLiteral(parser *Parser) ast.Expression {
+	parser.trace("parseArrayLiteral")
+
+	array := &ast.ArrayLiteral{Token: parser.currentToken}
+	array.Elements = parser.parseExpressionList(token.ClosingBracket)
+
+	if array.Elements == nil {
+		parser.untrace("parseArrayLiteral")
+		return nil
+	}
+
+	parser.untrace("parseArrayLiteral")
+	return array
+}
+
+// parseHashLiteral parses {key: value, ...}, a brace starting a statement is
+// a block rather than a hash.
+func parseHashLiteral(parser *Parser) ast.Expression {
+	parser.trace("parseHashLiteral")
+
+	hash := &ast.HashLiteral{Token: parser.currentToken, Keys: []ast.Expression{}, Values: []ast.Expression{}}
+
+	if parser.peekTokenIs(token.ClosingBrace) {
+		parser.nextToken()
+
+		parser.untrace("parseHashLiteral")
+		return hash
+	}
+
+	for {
+		parser.nextToken()
+		key := parser.parseRequiredExpression(PrecedenceLowest)
+
+		if key == nil || !parser.expectPeek(token.Colon) {
+			parser.untrace("parseHashLiteral")
+			return nil
+		}
+
+		parser.nextToken()
+		value := parser.parseRequiredExpression(PrecedenceLowest)
+
+		if value == nil {
+			parser.untrace("parseHashLiteral")
+			return nil
+		}
+
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !parser.peekTokenIs(token.Comma) {
+			break
+		}
+
+		parser.nextToken()
+	}
+
+	if !parser.expectPeek(token.ClosingBrace) {
+		parser.untrace("parseHashLiteral")
+		return nil
+	}
+
+	parser.untrace("parseHashLiteral")
+	return hash
 }
 
 func parseBoolLiteral(parser *Parser) ast.Expression {
@@ -519,11 +782,9 @@ func parseFunctionLiteral(parser *Parser) ast.Expression {
 		return nil
 	}
 
-	parser.nextToken()
-
 	function.Parameters = parser.parseFunctionParameters()
 
-	if !parser.expectPeek(token.ClosingParenthesis) {
+	if function.Parameters == nil {
 		parser.untrace("parseFunctionLiteral")
 		return nil
 	}
@@ -544,21 +805,29 @@ func (parser *Parser) parseFunctionParameters() []*ast.IdentifierLiteral {
 
 	identifiers := []*ast.IdentifierLiteral{}
 
-	for !parser.peekTokenIs(token.ClosingParenthesis) && parser.expectCurrent(token.Identifier) {
-		identifiers = append(identifiers, &ast.IdentifierLiteral{Token: parser.currentToken, Value: parser.currentToken.Literal})
-
+	if parser.peekTokenIs(token.ClosingParenthesis) {
 		parser.nextToken()
 
-		if parser.currentTokenIs(token.Comma) {
-			parser.nextToken()
-		}
+		parser.untrace("parseFunctionParameters")
+		return identifiers
 	}
 
-	if parser.currentTokenIs(token.Identifier) {
+	for {
+		if !parser.expectPeek(token.Identifier) {
+			parser.untrace("parseFunctionParameters")
+			return nil
+		}
+
 		identifiers = append(identifiers, &ast.IdentifierLiteral{Token: parser.currentToken, Value: parser.currentToken.Literal})
+
+		if !parser.peekTokenIs(token.Comma) {
+			break
+		}
+
+		parser.nextToken()
 	}
 
-	if parser.currentTokenIs(token.ClosingParenthesis) {
+	if !parser.expectPeek(token.ClosingParenthesis) {
 		parser.untrace("parseFunctionParameters")
 		return nil
 	}
@@ -566,3 +835,77 @@ func (parser *Parser) parseFunctionParameters() []*ast.IdentifierLiteral {
 	parser.untrace("parseFunctionParameters")
 	return identifiers
 }
+
+func parseCallExpression(parser *Parser, function ast.Expression) ast.Expression {
+	parser.trace("parseCallExpression")
+
+	call := &ast.CallExpression{Token: parser.currentToken, Function: function}
+	call.Arguments = parser.parseExpressionList(token.ClosingParenthesis)
+
+	if call.Arguments == nil {
+		parser.untrace("parseCallExpression")
+		return nil
+	}
+
+	parser.untrace("parseCallExpression")
+	return call
+}
+
+func parseIndexExpression(parser *Parser, left ast.Expression) ast.Expression {
+	parser.trace("parseIndexExpression")
+
+	expression := &ast.IndexExpression{Token: parser.currentToken, Left: left}
+
+	parser.nextToken()
+	expression.Index = parser.parseRequiredExpression(PrecedenceLowest)
+
+	if expression.Index == nil || !parser.expectPeek(token.ClosingBracket) {
+		parser.untrace("parseIndexExpression")
+		return nil
+	}
+
+	parser.untrace("parseIndexExpression")
+	return expression
+}
+
+// parseExpressionList parses expressions separated by commas up to the end
+// token, the arguments of a call or the elements of an array.
+func (parser *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	parser.trace("parseExpressionList")
+
+	list := []ast.Expression{}
+
+	if parser.peekTokenIs(end) {
+		parser.nextToken()
+
+		parser.untrace("parseExpressionList")
+		return list
+	}
+
+	for {
+		parser.nextToken()
+
+		element := parser.parseRequiredExpression(PrecedenceLowest)
+
+		if element == nil {
+			parser.untrace("parseExpressionList")
+			return nil
+		}
+
+		list = append(list, element)
+
+		if !parser.peekTokenIs(token.Comma) {
+			break
+		}
+
+		parser.nextToken()
+	}
+
+	if !parser.expectPeek(end) {
+		parser.untrace("parseExpressionList")
+		return nil
+	}
+
+	parser.untrace("parseExpressionList")
+	return list
+}
@@ -1,29 +1,218 @@
 package parser
 
 import (
+	"flag"
 	"monkey/ast"
+	"monkey/token"
 	"monkey/tokenizer"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
 func TestParser(t *testing.T) {
 	testParseExpect(t, "a + b;", "(a + b);", 1)
 	testParseExpect(t, "a + b + c;", "((a + b) + c);", 1)
 	testParseExpect(t, "a + b * c;", "(a + (b * c));", 1)
 	testParseExpect(t, "(a + b) * c;", "((a + b) * c);", 1)
+	testParseExpect(t, "a or b;", "(a or b);", 1)
 
 	testParseExpect(t, "let a = 10;", "let a = 10;", 1)
+	testParseExpect(t, ";;", ";;", 2)
+	testParseExpect(t, "a;;b;", "a;;b;", 3)
 
 	testParseExpect(t, "function (){ doStuff; };", "function(){doStuff;};", 1)
 	testParseExpect(t, "function (a){ return a; };", "function(a){return a;};", 1)
 	testParseExpect(t, "function (a, b){ return a + b; };", "function(a,b){return (a + b);};", 1)
 
+	testParseExpect(t, "f();", "f();", 1)
+	testParseExpect(t, "add(1, 2 * 3);", "add(1, (2 * 3));", 1)
+	testParseExpect(t, "-f(a) * g(b)(c);", "((- f(a)) * g(b)(c));", 1)
+	testParseExpect(t, "function(a) { return a; }(1);", "function(a){return a;}(1);", 1)
+
 	testParseExpectError(t, "(a+b)e;")
+	testParseExpectError(t, "f(a b);")
+	testParseExpectError(t, "f(a,);")
+	testParseExpectError(t, "f(;")
+	testParseExpectError(t, "f(1,;")
+	testParseExpectError(t, "1 + ;")
+	testParseExpectError(t, "-;")
+	testParseExpectError(t, "(;")
+	testParseExpectError(t, "let a = ;")
+}
+
+func TestParserUpdateOperators(t *testing.T) {
+	testParseExpect(t, "a++;", "(a ++);", 1)
+	testParseExpect(t, "a--;", "(a --);", 1)
+	testParseExpect(t, "++a;", "(++ a);", 1)
+	testParseExpect(t, "--a;", "(-- a);", 1)
+	testParseExpect(t, "-a++;", "(- (a ++));", 1)
+	testParseExpect(t, "a++ + ++b;", "((a ++) + (++ b));", 1)
+	testParseExpect(t, "a - -b;", "(a - (- b));", 1)
+
+	testParseExpectError(t, "a--b;")
+	testParseExpectError(t, "5++;")
+	testParseExpectError(t, "++5;")
+	testParseExpectError(t, "(a + b)--;")
+	testParseExpectError(t, "++a++;")
+}
+
+func TestParserNumberLiterals(t *testing.T) {
+	testParseExpect(t, "0xFF;", "255;", 1)
+	testParseExpect(t, "0b1010;", "10;", 1)
+	testParseExpect(t, "0o17;", "15;", 1)
+	testParseExpect(t, "1_000_000;", "1000000;", 1)
+	testParseExpect(t, "0;", "0;", 1)
+	testParseExpect(t, "010.5;", "10.5;", 1)
+	testParseExpect(t, "1.5e-3;", "0.0015;", 1)
+	testParseExpect(t, "1.5 * 2;", "(1.5 * 2);", 1)
+
+	testParseExpect(t, "9223372036854775808;", "9223372036854775808;", 1)
+	testParseExpect(t, "0x1_0000_0000_0000_0000;", "18446744073709551616;", 1)
+	testParseExpect(t, "2 ** 3 ** 2;", "(2 ** (3 ** 2));", 1)
+	testParseExpect(t, "-2 ** 2;", "(- (2 ** 2));", 1)
+	testParseExpect(t, "2 * 3 ** 2;", "(2 * (3 ** 2));", 1)
+
+	testParseExpectError(t, "1e400;")
+	testParseExpectError(t, "0b12;")
+	testParseExpectError(t, "1__0;")
+	testParseExpectError(t, "010;")
+	testParseExpectError(t, "0_7;")
+	testParseExpectError(t, "00;")
+}
+
+func TestParserStringsAndArrays(t *testing.T) {
+	testParseExpect(t, `"hello";`, `"hello";`, 1)
+	testParseExpect(t, `"a\tb\"c\u00e9";`, `"a\tb\"cé";`, 1)
+	testParseExpect(t, `[];`, `[];`, 1)
+	testParseExpect(t, `[1, "a", f(x)];`, `[1, "a", f(x)];`, 1)
+	testParseExpect(t, `a[1 + 2];`, `a[(1 + 2)];`, 1)
+	testParseExpect(t, `-a[0] * f(x)[1][2];`, `((- a[0]) * f(x)[1][2]);`, 1)
+
+	testParseExpectError(t, `"abc;`)
+	testParseExpectError(t, `"abc\";`)
+	testParseExpectError(t, `"\q";`)
+	testParseExpectError(t, `[1, 2;`)
+	testParseExpectError(t, `a[];`)
+	testParseExpectError(t, `let x = [;`)
+	testParseExpectError(t, `a[;`)
+
+	testParseExpect(t, `let h = {};`, `let h = {};`, 1)
+	testParseExpect(t, `f({"a": 1, b + 1: [2]});`, `f({"a": 1, (b + 1): [2]});`, 1)
+	testParseExpect(t, `({"a": {"b": 1}})["a"];`, `{"a": {"b": 1}}["a"];`, 1)
+
+	// A { opening a statement starts a hash when a colon or } follows.
+	testParseExpect(t, `{"a": 1};`, `{"a": 1};`, 1)
+	testParseExpect(t, `{};`, `{};`, 1)
+	testParseExpect(t, `{x + 1: [2], f(y): {}};`, `{(x + 1): [2], f(y): {}};`, 1)
+	testParseExpect(t, `{"a": input["a"]}["a"];`, `{"a": input["a"]}["a"];`, 1)
+
+	testParseExpectError(t, `let h = {"a" 1};`)
+	testParseExpectError(t, `let h = {"a": 1,};`)
+	testParseExpectError(t, `let h = {"a": };`)
+	testParseExpectError(t, `let h = {"a": ;`)
+	testParseExpectError(t, `let h = {;`)
+}
+
+func TestParserUnicode(t *testing.T) {
+	testParseExpect(t, "let café = 数量 + 1;", "let café = (数量 + 1);", 1)
+	testParseExpect(t, "let cafe\u0301 = 1;", "let caf\u00e9 = 1;", 1)
+
+	testParseExpectError(t, "let a = 1 + \xff;")
+}
+
+func TestParserLookahead(t *testing.T) {
+	p := New(tokenizer.New("let a = b + c;"))
+
+	expected := []token.TokenType{token.Let, token.Identifier, token.Assign, token.Identifier, token.Plus, token.Identifier, token.Semicolon, token.EOF}
+
+	if p.currentToken.Type != expected[0] {
+		t.Fatalf("currentToken - expected %s, got %s", expected[0], p.currentToken.Type)
+	}
+
+	for n := 1; n < len(expected); n++ {
+		if tok := p.peekTokenAt(n); tok.Type != expected[n] {
+			t.Errorf("peekTokenAt(%d) - expected %s, got %s", n, expected[n], tok.Type)
+		}
+	}
+
+	p.nextToken()
+
+	if tok := p.peekTokenAt(2); tok.Type != token.Identifier {
+		t.Errorf("peekTokenAt(2) after nextToken - expected %s, got %s", token.Identifier, tok.Type)
+	}
+}
+
+func TestStartsHashLiteral(t *testing.T) {
+	tests := map[string]bool{
+		`{}`:                 true,
+		`{"a": 1}`:           true,
+		`{x + f(y, z): 2}`:   true,
+		`{[1, 2][0]: {}}`:    true,
+		`{ a; b; }`:          false,
+		`{ f({"a": 1}); }`:   false,
+		`{ if (a) { b; }; }`: false,
+		`{ a`:                false,
+	}
+
+	for input, expected := range tests {
+		p := New(tokenizer.New(input))
+
+		if got := StartsHashLiteral(p.peekTokenAt); got != expected {
+			t.Errorf("StartsHashLiteral(%q) - expected %t, got %t", input, expected, got)
+		}
+	}
+}
+
+// TestParserGolden parses each testdata/*.monkey file and compares its tree,
+// as an S-expression, to the matching .sexpr file. Run the tests with
+// -update to regenerate them.
+func TestParserGolden(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "*.monkey"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		input, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		p := New(tokenizer.New(string(input)))
+		program := p.Parse()
+
+		checkParserErrors(t, p)
+
+		actual := ast.SExpr(program) + "\n"
+		golden := strings.TrimSuffix(path, ".monkey") + ".sexpr"
+
+		if *update {
+			if err := os.WriteFile(golden, []byte(actual), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		expected, err := os.ReadFile(golden)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != string(expected) {
+			t.Errorf("%s doesn't match %s, expected:\n%s\ngot:\n%s", path, golden, expected, actual)
+		}
+	}
 }
 
 func testParseProgram(t *testing.T, input string, expectedStatements int) *ast.Program {
 	tok := tokenizer.New(input)
-	p := NewWithTest(tok, t)
+	p := NewWithTracer(tok, NewWriterTracer(testLogWriter{t}))
 
 	program := p.Parse()
 
@@ -41,7 +230,7 @@ func testParseProgram(t *testing.T, input string, expectedStatements int) *ast.P
 }
 
 func testParseExpect(t *testing.T, input string, output string, expectedStatements int) {
-	t.Logf("--- testParseExpect '" + input + "' expect '" + output + "' ---")
+	t.Logf("--- testParseExpect '%s' expect '%s' ---", input, output)
 
 	program := testParseProgram(t, input, expectedStatements)
 
@@ -51,10 +240,10 @@ func testParseExpect(t *testing.T, input string, output string, expectedStatemen
 }
 
 func testParseExpectError(t *testing.T, input string) {
-	t.Logf("--- testParseExpectError '" + input + "' ---")
+	t.Logf("--- testParseExpectError '%s' ---", input)
 
 	tok := tokenizer.New(input)
-	p := NewWithTest(tok, t)
+	p := NewWithTracer(tok, NewWriterTracer(testLogWriter{t}))
 
 	program := p.Parse()
 
@@ -63,6 +252,16 @@ func testParseExpectError(t *testing.T, input string) {
 	}
 }
 
+// testLogWriter sends the parser trace to the test log.
+type testLogWriter struct {
+	t *testing.T
+}
+
+func (writer testLogWriter) Write(p []byte) (int, error) {
+	writer.t.Log(strings.TrimRight(string(p), "\n"))
+	return len(p), nil
+}
+
 func checkParserErrors(t *testing.T, parser *Parser) {
 	errors := parser.Errors
 	if len(errors) != 0 {
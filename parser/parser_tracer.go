@@ -1,32 +1,167 @@
 package parser
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
+	"monkey/token"
 	"strings"
 )
 
+// Tracer follows the parser through the grammar, Enter and Leave are called
+// around each parse function with the current and peek tokens at that time.
+type Tracer interface {
+	Enter(rule string, current token.Token, peek token.Token)
+	Leave(rule string, current token.Token, peek token.Token)
+}
+
+func (parser *Parser) trace(rule string) {
+	if parser.tracer != nil {
+		parser.tracer.Enter(rule, parser.currentToken, parser.peekToken)
+	}
+}
+
+func (parser *Parser) untrace(rule string) {
+	if parser.tracer != nil {
+		parser.tracer.Leave(rule, parser.currentToken, parser.peekToken)
+	}
+}
+
+/* --- Writer Tracer -------------------------------------------------------- */
+
 const traceIdentPlaceholder string = "\t"
 
-func (parser *Parser) identLevel() string {
-	return strings.Repeat(traceIdentPlaceholder, parser.depth-1)
+// WriterTracer writes an indented BEGIN/END line per rule to a writer.
+type WriterTracer struct {
+	writer io.Writer
+	depth  int
+}
+
+func NewWriterTracer(writer io.Writer) *WriterTracer {
+	return &WriterTracer{writer: writer}
+}
+
+func (tracer *WriterTracer) identLevel() string {
+	return strings.Repeat(traceIdentPlaceholder, tracer.depth)
+}
+
+func (tracer *WriterTracer) Enter(rule string, current token.Token, peek token.Token) {
+	fmt.Fprintf(tracer.writer, "%sBEGIN %s(%s %s)\n", tracer.identLevel(), rule, current.Type, peek.Type)
+	tracer.depth++
+}
+
+func (tracer *WriterTracer) Leave(rule string, current token.Token, peek token.Token) {
+	tracer.depth--
+	fmt.Fprintf(tracer.writer, "%sEND %s(%s %s)\n", tracer.identLevel(), rule, current.Type, peek.Type)
+}
+
+/* --- Tree Tracer ---------------------------------------------------------- */
+
+// TraceNode is a rule the parser went through, Begin and End are the current
+// tokens when the rule was entered and left.
+type TraceNode struct {
+	Rule     string
+	Begin    token.Token
+	End      token.Token
+	Children []*TraceNode
+}
+
+func (node *TraceNode) String() string {
+	var out strings.Builder
+
+	node.write(&out, 0)
+
+	return out.String()
+}
+
+func (node *TraceNode) write(out *strings.Builder, depth int) {
+	fmt.Fprintf(out, "%s%s [%d:%d-%d:%d]\n", strings.Repeat(traceIdentPlaceholder, depth), node.Rule, node.Begin.Line, node.Begin.Column, node.End.Line, node.End.Column)
+
+	for _, child := range node.Children {
+		child.write(out, depth+1)
+	}
+}
+
+// TreeTracer collects the rules in memory, Roots holds one node per
+// top-level call into the parser.
+type TreeTracer struct {
+	Roots []*TraceNode
+	stack []*TraceNode
+}
+
+func NewTreeTracer() *TreeTracer {
+	return &TreeTracer{}
 }
 
-func (parser *Parser) tracePrint(fs string) {
-	if parser.t != nil {
-		parser.t.Logf("%s%s\n", parser.identLevel(), fs)
+func (tracer *TreeTracer) Enter(rule string, current token.Token, peek token.Token) {
+	node := &TraceNode{Rule: rule, Begin: current}
+
+	if len(tracer.stack) == 0 {
+		tracer.Roots = append(tracer.Roots, node)
+	} else {
+		parent := tracer.stack[len(tracer.stack)-1]
+		parent.Children = append(parent.Children, node)
+	}
+
+	tracer.stack = append(tracer.stack, node)
+}
+
+func (tracer *TreeTracer) Leave(rule string, current token.Token, peek token.Token) {
+	if len(tracer.stack) == 0 {
+		return
 	}
+
+	tracer.stack[len(tracer.stack)-1].End = current
+	tracer.stack = tracer.stack[:len(tracer.stack)-1]
 }
 
-func (parser *Parser) incIdent() { parser.depth = parser.depth + 1 }
-func (parser *Parser) decIdent() { parser.depth = parser.depth - 1 }
+/* --- JSON Tracer ---------------------------------------------------------- */
+
+// JSONTracer writes one JSON object per line and per event.
+type JSONTracer struct {
+	encoder *json.Encoder
+	depth   int
+}
+
+type jsonTraceToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Line    int             `json:"line"`
+	Column  int             `json:"column"`
+}
+
+type jsonTraceEvent struct {
+	Event   string         `json:"event"`
+	Rule    string         `json:"rule"`
+	Depth   int            `json:"depth"`
+	Current jsonTraceToken `json:"current"`
+	Peek    jsonTraceToken `json:"peek"`
+}
+
+func NewJSONTracer(writer io.Writer) *JSONTracer {
+	return &JSONTracer{encoder: json.NewEncoder(writer)}
+}
+
+func newJSONTraceToken(tok token.Token) jsonTraceToken {
+	return jsonTraceToken{Type: tok.Type, Literal: tok.Literal, Line: tok.Line, Column: tok.Column}
+}
+
+func (tracer *JSONTracer) emit(event string, rule string, current token.Token, peek token.Token) {
+	tracer.encoder.Encode(jsonTraceEvent{
+		Event:   event,
+		Rule:    rule,
+		Depth:   tracer.depth,
+		Current: newJSONTraceToken(current),
+		Peek:    newJSONTraceToken(peek),
+	})
+}
 
-func (parser *Parser) trace(msg string) string {
-	parser.incIdent()
-	parser.tracePrint("BEGIN " + msg + fmt.Sprintf("(%s %s) ", parser.currentToken.Type, parser.peekToken.Type))
-	return msg
+func (tracer *JSONTracer) Enter(rule string, current token.Token, peek token.Token) {
+	tracer.emit("enter", rule, current, peek)
+	tracer.depth++
 }
 
-func (parser *Parser) untrace(msg string) {
-	parser.tracePrint("END " + msg + fmt.Sprintf("(%s %s) ", parser.currentToken.Type, parser.peekToken.Type))
-	parser.decIdent()
+func (tracer *JSONTracer) Leave(rule string, current token.Token, peek token.Token) {
+	tracer.depth--
+	tracer.emit("leave", rule, current, peek)
 }
@@ -0,0 +1,87 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"monkey/tokenizer"
+	"strings"
+	"testing"
+)
+
+func TestWriterTracer(t *testing.T) {
+	var out bytes.Buffer
+
+	NewWithTracer(tokenizer.New("a;"), NewWriterTracer(&out)).Parse()
+
+	expected := strings.Join([]string{
+		"BEGIN Parse(Identifier Semicolon)",
+		"\tBEGIN parseStatement(Identifier Semicolon)",
+		"\t\tBEGIN parseExpressionStatement(Identifier Semicolon)",
+		"\t\t\tBEGIN parseExpression(Identifier Semicolon)",
+		"\t\t\tEND parseExpression(Identifier Semicolon)",
+		"\t\tEND parseExpressionStatement(Semicolon EOF)",
+		"\tEND parseStatement(Semicolon EOF)",
+		"END Parse(EOF EOF)",
+		"",
+	}, "\n")
+
+	if out.String() != expected {
+		t.Errorf("unexpected trace, expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestTreeTracer(t *testing.T) {
+	tracer := NewTreeTracer()
+
+	NewWithTracer(tokenizer.New("let f = function(a, b) { if (a) { return b; }; }; while (x) { x--; };"), tracer).Parse()
+
+	if len(tracer.Roots) != 1 || tracer.Roots[0].Rule != "Parse" {
+		t.Fatalf("expected a single Parse root, got %v", tracer.Roots)
+	}
+
+	if len(tracer.stack) != 0 {
+		t.Fatalf("unbalanced trace, %d rules were never left", len(tracer.stack))
+	}
+
+	statements := tracer.Roots[0].Children
+	if len(statements) != 2 {
+		t.Fatalf("expected 2 statements, got %d:\n%s", len(statements), tracer.Roots[0])
+	}
+
+	if statements[1].Begin.Line != 1 || statements[1].Begin.Column != 51 {
+		t.Errorf("expected the second statement to begin at 1:51, got %d:%d", statements[1].Begin.Line, statements[1].Begin.Column)
+	}
+}
+
+func TestJSONTracer(t *testing.T) {
+	var out bytes.Buffer
+
+	NewWithTracer(tokenizer.New("1 + 2;"), NewJSONTracer(&out)).Parse()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	depth := 0
+
+	for i, line := range lines {
+		var event jsonTraceEvent
+
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("line %d isn't valid JSON: %s", i, err)
+		}
+
+		if event.Event == "leave" {
+			depth--
+		}
+
+		if event.Depth != depth {
+			t.Errorf("line %d - expected depth %d, got %d", i, depth, event.Depth)
+		}
+
+		if event.Event == "enter" {
+			depth++
+		}
+	}
+
+	if depth != 0 {
+		t.Errorf("unbalanced trace, ended at depth %d", depth)
+	}
+}
@@ -0,0 +1,249 @@
+// Package scope resolves the identifiers of a program to the let statement or
+// function parameter binding them.
+//
+// Blocks share the scope they are in and function literals open a new one,
+// as environments do in the evaluator. A let binding is visible from the end
+// of its statement, except in the functions of its own initializer so that
+// they can call themselves. Function bodies run once they are called, they
+// also see the lets that come after them in the enclosing scopes.
+package scope
+
+import (
+	"monkey/ast"
+	"sort"
+)
+
+type BindingKind string
+
+const (
+	Let       BindingKind = "let"
+	Parameter BindingKind = "parameter"
+)
+
+// Binding is a name introduced by a let statement or a function parameter.
+type Binding struct {
+	Name string
+	Kind BindingKind
+
+	// Declaration is the identifier being bound, Node the let statement or
+	// the function literal it belongs to.
+	Declaration *ast.IdentifierLiteral
+	Node        ast.Node
+	Scope       *Scope
+
+	// References are the identifiers using the binding in source order,
+	// the declaration left out.
+	References []*ast.IdentifierLiteral
+}
+
+// Scope is the program or the body of a function literal.
+type Scope struct {
+	Parent   *Scope
+	Function *ast.FunctionLiteral
+	Bindings []*Binding
+
+	names   map[string]*Binding
+	pending []*Binding
+}
+
+// Info is the result of resolving a program.
+type Info struct {
+	Global *Scope
+	Scopes map[*ast.FunctionLiteral]*Scope
+
+	// Uses maps every identifier, declarations included, to its binding.
+	Uses       map[*ast.IdentifierLiteral]*Binding
+	Unresolved []*ast.IdentifierLiteral
+
+	identifiers map[[2]int]*ast.IdentifierLiteral
+}
+
+func newScope(parent *Scope, function *ast.FunctionLiteral) *Scope {
+	return &Scope{Parent: parent, Function: function, names: map[string]*Binding{}}
+}
+
+// Resolve finds the binding of every identifier of program.
+func Resolve(program *ast.Program) *Info {
+	info := &Info{
+		Global:      newScope(nil, nil),
+		Scopes:      map[*ast.FunctionLiteral]*Scope{},
+		Uses:        map[*ast.IdentifierLiteral]*Binding{},
+		identifiers: map[[2]int]*ast.IdentifierLiteral{},
+	}
+
+	resolver := &resolver{info: info, scope: info.Global}
+	resolver.resolve(program)
+	resolver.resolveLater()
+
+	return info
+}
+
+// IdentifierAt returns the identifier starting at line and column, or nil.
+func (info *Info) IdentifierAt(line int, column int) *ast.IdentifierLiteral {
+	return info.identifiers[[2]int{line, column}]
+}
+
+// Visible returns the bindings in reach at line and column in scope, the
+// latest one of each name, innermost scope first.
+func (scope *Scope) Visible(line int, column int) []*Binding {
+	visible := []*Binding{}
+	seen := map[string]bool{}
+
+	for ; scope != nil; scope = scope.Parent {
+		for i := len(scope.Bindings) - 1; i >= 0; i-- {
+			binding := scope.Bindings[i]
+			tok := binding.Declaration.Token
+
+			if seen[binding.Name] || tok.Line > line || tok.Line == line && tok.Column >= column {
+				continue
+			}
+
+			seen[binding.Name] = true
+			visible = append(visible, binding)
+		}
+	}
+
+	return visible
+}
+
+func (scope *Scope) declare(binding *Binding) {
+	scope.Bindings = append(scope.Bindings, binding)
+	scope.names[binding.Name] = binding
+}
+
+type resolver struct {
+	info  *Info
+	scope *Scope
+
+	// later are the identifiers of function bodies not bound yet where they
+	// appear, with the scope they appear in.
+	later []later
+}
+
+type later struct {
+	identifier *ast.IdentifierLiteral
+	scope      *Scope
+}
+
+func (resolver *resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.LetStatement:
+		if node.Identifier == nil {
+			resolver.resolve(node.Expression)
+			return
+		}
+
+		binding := &Binding{Name: node.Identifier.Value, Kind: Let, Declaration: node.Identifier, Node: node, Scope: resolver.scope}
+
+		resolver.scope.pending = append(resolver.scope.pending, binding)
+
+		if node.Expression != nil {
+			resolver.resolve(node.Expression)
+		}
+
+		resolver.scope.pending = resolver.scope.pending[:len(resolver.scope.pending)-1]
+		resolver.scope.declare(binding)
+		resolver.record(node.Identifier, binding)
+
+	case *ast.FunctionLiteral:
+		scope := newScope(resolver.scope, node)
+		resolver.info.Scopes[node] = scope
+
+		for _, parameter := range node.Parameters {
+			binding := &Binding{Name: parameter.Value, Kind: Parameter, Declaration: parameter, Node: node, Scope: scope}
+
+			scope.declare(binding)
+			resolver.record(parameter, binding)
+		}
+
+		if node.Body != nil {
+			enclosing := resolver.scope
+			resolver.scope = scope
+			resolver.resolve(node.Body)
+			resolver.scope = enclosing
+		}
+
+	case *ast.IdentifierLiteral:
+		binding := resolver.lookup(node.Value)
+
+		if binding == nil && resolver.scope.Parent != nil {
+			resolver.later = append(resolver.later, later{node, resolver.scope})
+			return
+		}
+
+		if binding == nil {
+			resolver.info.Unresolved = append(resolver.info.Unresolved, node)
+		} else {
+			binding.References = append(binding.References, node)
+		}
+
+		resolver.record(node, binding)
+
+	case nil:
+
+	default:
+		for _, child := range node.Children() {
+			resolver.resolve(child)
+		}
+	}
+}
+
+// resolveLater binds the identifiers of function bodies to the lets of the
+// enclosing scopes as they are once the whole program is resolved. The lets
+// of the function's own scope that come later are not in reach yet.
+func (resolver *resolver) resolveLater() {
+	for _, use := range resolver.later {
+		var binding *Binding
+
+		for scope := use.scope.Parent; scope != nil && binding == nil; scope = scope.Parent {
+			binding = scope.names[use.identifier.Value]
+		}
+
+		if binding == nil {
+			resolver.info.Unresolved = append(resolver.info.Unresolved, use.identifier)
+		} else {
+			binding.References = append(binding.References, use.identifier)
+			sortBySource(binding.References)
+		}
+
+		resolver.record(use.identifier, binding)
+	}
+
+	sortBySource(resolver.info.Unresolved)
+}
+
+func sortBySource(identifiers []*ast.IdentifierLiteral) {
+	sort.SliceStable(identifiers, func(i, j int) bool {
+		a, b := identifiers[i].Token, identifiers[j].Token
+		return a.Line < b.Line || a.Line == b.Line && a.Column < b.Column
+	})
+}
+
+func (resolver *resolver) record(identifier *ast.IdentifierLiteral, binding *Binding) {
+	if binding != nil {
+		resolver.info.Uses[identifier] = binding
+	}
+
+	resolver.info.identifiers[[2]int{identifier.Token.Line, identifier.Token.Column}] = identifier
+}
+
+// lookup finds the binding of name as seen from the current scope. Lets
+// still being initialized in an enclosing scope are seen from the function
+// literals of their initializer.
+func (resolver *resolver) lookup(name string) *Binding {
+	for scope := resolver.scope; scope != nil; scope = scope.Parent {
+		if scope != resolver.scope {
+			for i := len(scope.pending) - 1; i >= 0; i-- {
+				if scope.pending[i].Name == name {
+					return scope.pending[i]
+				}
+			}
+		}
+
+		if binding, ok := scope.names[name]; ok {
+			return binding
+		}
+	}
+
+	return nil
+}
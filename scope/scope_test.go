@@ -0,0 +1,152 @@
+package scope
+
+import (
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"testing"
+)
+
+func resolve(t *testing.T, input string) (*ast.Program, *Info) {
+	parser := parser.New(tokenizer.New(input))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		t.Fatalf("%q - parser errors %v", input, parser.Errors)
+	}
+
+	return program, Resolve(program)
+}
+
+// uses lists the identifiers of program by position along with the line of
+// the declaration they resolve to, 0 when unresolved.
+func uses(program *ast.Program, info *Info) map[[2]int]int {
+	lines := map[[2]int]int{}
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if identifier, ok := node.(*ast.IdentifierLiteral); ok {
+			line := 0
+
+			if binding := info.Uses[identifier]; binding != nil {
+				line = binding.Declaration.Token.Line
+			}
+
+			lines[[2]int{identifier.Token.Line, identifier.Token.Column}] = line
+		}
+
+		return true
+	})
+
+	return lines
+}
+
+func TestResolve(t *testing.T) {
+	input := `let a = 1;
+let f = function(a, b) {
+	let c = a + b;
+	return f;
+};
+let a = a + 1;
+if (a) { let d = 1; };
+d;
+x;`
+
+	program, info := resolve(t, input)
+	actual := uses(program, info)
+
+	expected := map[[2]int]int{
+		{1, 5}:  1, // let a
+		{2, 5}:  2, // let f
+		{2, 18}: 2, // parameter a
+		{2, 21}: 2, // parameter b
+		{3, 6}:  3, // let c
+		{3, 10}: 2, // a is the parameter
+		{3, 14}: 2,
+		{4, 9}:  2, // f calls itself
+		{6, 5}:  6, // a is redeclared
+		{6, 9}:  1, // with the previous a
+		{7, 5}:  6,
+		{7, 14}: 7, // blocks share the scope they are in
+		{8, 1}:  7,
+		{9, 1}:  0,
+	}
+
+	for position, line := range expected {
+		if actual[position] != line {
+			t.Errorf("%v - expected the binding at line %d, got %d", position, line, actual[position])
+		}
+	}
+
+	if len(info.Unresolved) != 1 || info.Unresolved[0].Value != "x" {
+		t.Errorf("expected x to be unresolved, got %v", info.Unresolved)
+	}
+}
+
+func TestForwardReferences(t *testing.T) {
+	input := `let f = function() { return g(); };
+let g = function() { return h; let h = 1; };
+g;`
+
+	program, info := resolve(t, input)
+	actual := uses(program, info)
+
+	expected := map[[2]int]int{
+		{1, 29}: 2, // g is bound once f is called
+		{2, 29}: 0, // h is not bound yet in its own scope
+		{3, 1}:  2,
+	}
+
+	for position, line := range expected {
+		if actual[position] != line {
+			t.Errorf("%v - expected the binding at line %d, got %d", position, line, actual[position])
+		}
+	}
+
+	if len(info.Unresolved) != 1 || info.Unresolved[0].Value != "h" {
+		t.Errorf("expected h to be unresolved, got %v", info.Unresolved)
+	}
+
+	g := info.Uses[info.IdentifierAt(2, 5)]
+
+	if g == nil || len(g.References) != 2 || g.References[0].Token.Line != 1 {
+		t.Errorf("expected the references of g in source order, got %+v", g)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	_, info := resolve(t, "let n = 1;\nn + n;\nlet g = function() { n; };")
+
+	binding := info.Uses[info.IdentifierAt(1, 5)]
+
+	if binding == nil || binding.Kind != Let || len(binding.References) != 3 {
+		t.Fatalf("expected 3 references of n, got %+v", binding)
+	}
+
+	if binding.References[2].Token.Line != 3 {
+		t.Errorf("expected the last reference in the function, got line %d", binding.References[2].Token.Line)
+	}
+}
+
+func TestVisible(t *testing.T) {
+	program, info := resolve(t, "let a = 1;\nlet f = function(x) {\n\tlet y = 2;\n\ty;\n};\nlet z = 3;")
+
+	function := program.Statements[1].(*ast.LetStatement).Expression.(*ast.FunctionLiteral)
+	visible := info.Scopes[function].Visible(4, 2)
+
+	names := []string{}
+	for _, binding := range visible {
+		names = append(names, binding.Name)
+	}
+
+	expected := []string{"y", "x", "f", "a"}
+
+	if len(names) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+
+	for i := range expected {
+		if names[i] != expected[i] {
+			t.Errorf("expected %v, got %v", expected, names)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package template
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"path/filepath"
+	"strings"
+)
+
+// Error is an error in the template Name, Message tells where it is in the
+// template, as in Ln 3, Col 12: identifier not found: x.
+type Error struct {
+	Name    string
+	Message string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("%s: %s", err.Name, err.Message)
+}
+
+// Renderer renders templates, the output of puts goes with the text.
+type Renderer struct {
+	// HTML escapes the interpolated values for HTML.
+	HTML bool
+
+	// Load reads the included template name, which is relative to the
+	// directory of the including template. Without it includes fail.
+	Load func(name string) (string, error)
+
+	// Evaluator runs the code of the templates, NewRenderer sets it up to
+	// write to out.
+	Evaluator *evaluator.Evaluator
+
+	out       io.Writer
+	templates map[string]*Template
+	including []string
+}
+
+// NewRenderer creates a renderer writing to out.
+func NewRenderer(out io.Writer) *Renderer {
+	evaluator := evaluator.New()
+	evaluator.Output = out
+
+	return &Renderer{out: out, Evaluator: evaluator, templates: map[string]*Template{}}
+}
+
+// Render renders template with the values bound in env, the code of the
+// template can add to them.
+func (renderer *Renderer) Render(template *Template, env *object.Environment) error {
+	renderer.including = append(renderer.including, template.Name)
+	defer func() { renderer.including = renderer.including[:len(renderer.including)-1] }()
+
+	return renderer.render(template, template.nodes, env)
+}
+
+func (renderer *Renderer) eval(template *Template, code *ast.Program, env *object.Environment) (object.Object, error) {
+	result := renderer.Evaluator.Eval(code, env)
+
+	if err, ok := result.(*object.ErrorObject); ok {
+		return nil, &Error{Name: template.Name, Message: err.Message}
+	}
+
+	return result, nil
+}
+
+// test evaluates a condition, false and null being false.
+func (renderer *Renderer) test(template *Template, condition *ast.Program, env *object.Environment) (bool, error) {
+	value, err := renderer.eval(template, condition, env)
+
+	return err == nil && value != evaluator.Null && value != evaluator.False, err
+}
+
+func (renderer *Renderer) render(template *Template, nodes []node, env *object.Environment) error {
+	for _, node := range nodes {
+		var err error
+
+		switch node := node.(type) {
+		case *textNode:
+			_, err = io.WriteString(renderer.out, node.text)
+
+		case *outputNode:
+			err = renderer.renderOutput(template, node, env)
+
+		case *statementNode:
+			_, err = renderer.eval(template, node.code, env)
+
+		case *ifNode:
+			err = renderer.renderIf(template, node, env)
+
+		case *whileNode:
+			for {
+				var ok bool
+
+				if ok, err = renderer.test(template, node.condition, env); err != nil || !ok {
+					break
+				}
+
+				if err = renderer.render(template, node.body, env); err != nil {
+					break
+				}
+			}
+
+		case *forNode:
+			err = renderer.renderFor(template, node, env)
+
+		case *includeNode:
+			err = renderer.renderInclude(template, node, env)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderOutput writes a value, strings without their quotes and null as
+// nothing.
+func (renderer *Renderer) renderOutput(template *Template, node *outputNode, env *object.Environment) error {
+	value, err := renderer.eval(template, node.code, env)
+
+	if err != nil {
+		return err
+	}
+
+	text := ""
+
+	switch value := value.(type) {
+	case *object.StringObject:
+		text = value.Value
+	case *object.NullObject:
+	default:
+		text = value.Inspect()
+	}
+
+	if renderer.HTML {
+		text = html.EscapeString(text)
+	}
+
+	_, err = io.WriteString(renderer.out, text)
+	return err
+}
+
+func (renderer *Renderer) renderIf(template *Template, node *ifNode, env *object.Environment) error {
+	for _, branch := range node.branches {
+		ok, err := renderer.test(template, branch.condition, env)
+
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return renderer.render(template, branch.body, env)
+		}
+	}
+
+	return renderer.render(template, node.otherwise, env)
+}
+
+func (renderer *Renderer) renderFor(template *Template, node *forNode, env *object.Environment) error {
+	iterable, err := renderer.eval(template, node.iterable, env)
+
+	if err != nil {
+		return err
+	}
+
+	var indexes, elements []object.Object
+
+	switch iterable := iterable.(type) {
+	case *object.ArrayObject:
+		elements = iterable.Elements
+	case *object.HashObject:
+		for _, pair := range iterable.Sorted() {
+			indexes = append(indexes, pair.Key)
+			elements = append(elements, pair.Value)
+		}
+	case *object.StringObject:
+		for _, char := range iterable.Value {
+			elements = append(elements, &object.StringObject{Value: string(char)})
+		}
+	default:
+		return &Error{Name: template.Name, Message: node.at.errorf("can't iterate over %s", iterable.Type())}
+	}
+
+	for i, element := range elements {
+		var index object.Object = &object.IntegerObject{Value: int64(i)}
+
+		if indexes != nil {
+			index = indexes[i]
+		}
+
+		switch {
+		case len(node.names) == 2:
+			env.Set(node.names[0], index)
+			env.Set(node.names[1], element)
+		case indexes != nil:
+			env.Set(node.names[0], index)
+		default:
+			env.Set(node.names[0], element)
+		}
+
+		if err := renderer.render(template, node.body, env); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (renderer *Renderer) renderInclude(template *Template, node *includeNode, env *object.Environment) error {
+	value, err := renderer.eval(template, node.name, env)
+
+	if err != nil {
+		return err
+	}
+
+	fail := func(format string, args ...interface{}) error {
+		return &Error{Name: template.Name, Message: node.at.errorf(format, args...)}
+	}
+
+	str, ok := value.(*object.StringObject)
+
+	if !ok {
+		return fail("include expects a String, got %s", value.Type())
+	}
+
+	name := str.Value
+
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(filepath.Dir(template.Name), name)
+	}
+
+	for i, including := range renderer.including {
+		if including == name {
+			cycle := append(append([]string{}, renderer.including[i:]...), name)
+			return fail("include cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	included, ok := renderer.templates[name]
+
+	if !ok {
+		if renderer.Load == nil {
+			return fail("can't include %s", name)
+		}
+
+		source, err := renderer.Load(name)
+
+		if err != nil {
+			return fail("%s", err)
+		}
+
+		var errors []string
+
+		if included, errors = Parse(name, source); len(errors) != 0 {
+			return &Error{Name: name, Message: errors[0]}
+		}
+
+		renderer.templates[name] = included
+	}
+
+	return renderer.Render(included, env)
+}
@@ -0,0 +1,439 @@
+// Package template renders text with embedded monkey code.
+//
+// Values are interpolated with {{ expression }}, and tags control the
+// rendering:
+//
+//	{% if condition %} ... {% elif condition %} ... {% else %} ... {% end %}
+//	{% while condition %} ... {% end %}
+//	{% for name in expression %} ... {% end %}
+//	{% for key, value in expression %} ... {% end %}
+//	{% include expression %}
+//	{% statement %}
+//
+// for goes over the elements of arrays, the keys of hashes in the order they
+// print and the characters of strings, with two names the first one is bound
+// to the index, or to the key for hashes. include renders the template it
+// names, relative to the one including it, in the same environment. Other
+// tags run their code, as in {% let total = 0 %}.
+//
+// A tag alone on its line doesn't leave a blank line in the output.
+package template
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/parser"
+	"monkey/tokenizer"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Template is a parsed template.
+type Template struct {
+	Name  string
+	nodes []node
+}
+
+type node interface{}
+
+type textNode struct {
+	text string
+}
+
+type outputNode struct {
+	code *ast.Program
+}
+
+type statementNode struct {
+	code *ast.Program
+}
+
+type branch struct {
+	condition *ast.Program
+	body      []node
+}
+
+type ifNode struct {
+	branches  []branch
+	otherwise []node
+}
+
+type whileNode struct {
+	condition *ast.Program
+	body      []node
+}
+
+type forNode struct {
+	names    []string
+	iterable *ast.Program
+	at       position
+	body     []node
+}
+
+type includeNode struct {
+	name *ast.Program
+	at   position
+}
+
+type position struct {
+	line   int
+	column int
+}
+
+func (at position) errorf(format string, args ...interface{}) string {
+	return fmt.Sprintf("Ln %d, Col %d: %s", at.line, at.column, fmt.Sprintf(format, args...))
+}
+
+/* --- Lexer ---------------------------------------------------------------- */
+
+type pieceKind int
+
+const (
+	textPiece pieceKind = iota
+	outputPiece
+	tagPiece
+)
+
+// piece is text, or the code between the delimiters of an interpolation or
+// a tag, at is where it starts.
+type piece struct {
+	kind pieceKind
+	text string
+	at   position
+}
+
+func isBlank(char rune) bool {
+	return char == ' ' || char == '\t' || char == '\r'
+}
+
+// lex splits source into pieces.
+func lex(source string) ([]piece, []string) {
+	runes := []rune(source)
+	pieces := []piece{}
+	at := position{1, 1}
+	i := 0
+
+	// advance moves i to end, keeping at where i is.
+	advance := func(end int) {
+		for ; i < end; i++ {
+			if runes[i] == '\n' {
+				at.line++
+				at.column = 1
+			} else {
+				at.column++
+			}
+		}
+	}
+
+	textStart, textAt := 0, at
+
+	for i < len(runes) {
+		if runes[i] != '{' || i+1 == len(runes) || runes[i+1] != '{' && runes[i+1] != '%' {
+			advance(i + 1)
+			continue
+		}
+
+		kind, closing := outputPiece, '}'
+
+		if runes[i+1] == '%' {
+			kind, closing = tagPiece, '%'
+		}
+
+		// first is the first closing delimiter, whatever the braces before it.
+		length, depth, first := -1, 0, -1
+
+		// The braces of hashes and blocks in the code are matched so that
+		// {{ {"a": {"b": 1}} }} ends at the last }}, strings are skipped.
+		for j := i + 2; j < len(runes) && length == -1; j++ {
+			closes := j+1 < len(runes) && runes[j] == closing && runes[j+1] == '}'
+
+			if closes && first == -1 {
+				first = j
+			}
+
+			switch {
+			case runes[j] == '"':
+				for j++; j < len(runes) && runes[j] != '"' && runes[j] != '\n'; j++ {
+					if runes[j] == '\\' {
+						j++
+					}
+				}
+			case closes && depth == 0:
+				length = j - i - 2
+			case runes[j] == '{':
+				depth++
+			case runes[j] == '}' && depth > 0:
+				depth--
+			}
+		}
+
+		if length == -1 && first != -1 {
+			return pieces, []string{at.errorf("unbalanced braces in %s", string(runes[i:i+2]))}
+		}
+
+		if length == -1 {
+			return pieces, []string{at.errorf("unclosed %s", string(runes[i:i+2]))}
+		}
+
+		textEnd, next := i, i+2+length+2
+
+		// A tag alone on its line is removed with the line.
+		if kind == tagPiece {
+			start := i
+
+			for start > textStart && isBlank(runes[start-1]) {
+				start--
+			}
+
+			end := next
+
+			for end < len(runes) && isBlank(runes[end]) {
+				end++
+			}
+
+			if (start == 0 || runes[start-1] == '\n') && (end == len(runes) || runes[end] == '\n') {
+				textEnd = start
+				next = end
+
+				if end < len(runes) {
+					next++
+				}
+			}
+		}
+
+		if textEnd > textStart {
+			pieces = append(pieces, piece{textPiece, string(runes[textStart:textEnd]), textAt})
+		}
+
+		advance(i + 2)
+		pieces = append(pieces, piece{kind, string(runes[i : i+length]), at})
+		advance(next)
+
+		textStart, textAt = i, at
+	}
+
+	if textStart < len(runes) {
+		pieces = append(pieces, piece{textPiece, string(runes[textStart:]), textAt})
+	}
+
+	return pieces, nil
+}
+
+/* --- Parser --------------------------------------------------------------- */
+
+// tag is a parsed tag piece, keyword is empty for statements.
+type tag struct {
+	keyword string
+	at      position
+	code    string
+	codeAt  position
+	names   []string
+}
+
+var forHead = regexp.MustCompile(`^\s+([A-Za-z_][A-Za-z0-9_]*)(?:\s*,\s*([A-Za-z_][A-Za-z0-9_]*))?\s+in\b`)
+
+// builder builds the nodes of a template from its pieces.
+type builder struct {
+	pieces   []piece
+	position int
+	errors   []string
+}
+
+// Parse parses the template source, it returns the syntax errors of the
+// template and of its code.
+func Parse(name string, source string) (*Template, []string) {
+	pieces, errors := lex(source)
+	builder := &builder{pieces: pieces, errors: errors}
+	nodes, end := builder.parseNodes()
+
+	if end != nil {
+		builder.errors = append(builder.errors, end.at.errorf("unexpected {%% %s %%}", end.keyword))
+	}
+
+	return &Template{Name: name, nodes: nodes}, builder.errors
+}
+
+// skip returns where text is after its first count runes, text starting at
+// at.
+func skip(text []rune, count int, at position) position {
+	for _, char := range text[:count] {
+		if char == '\n' {
+			at.line++
+			at.column = 1
+		} else {
+			at.column++
+		}
+	}
+
+	return at
+}
+
+func (builder *builder) parseTag(piece piece) *tag {
+	text := []rune(piece.text)
+	start := 0
+
+	for start < len(text) && unicode.IsSpace(text[start]) {
+		start++
+	}
+
+	end := start
+
+	for end < len(text) && (unicode.IsLetter(text[end]) || text[end] == '_' || unicode.IsDigit(text[end])) {
+		end++
+	}
+
+	tag := &tag{keyword: string(text[start:end]), at: skip(text, start, piece.at)}
+
+	switch tag.keyword {
+	case "if", "elif", "while", "include":
+	case "else", "end":
+		if rest := strings.TrimSpace(string(text[end:])); rest != "" {
+			builder.errors = append(builder.errors, tag.at.errorf("unexpected %s after %s", rest, tag.keyword))
+		}
+
+		return tag
+	case "for":
+		match := forHead.FindStringSubmatch(string(text[end:]))
+
+		if match == nil {
+			builder.errors = append(builder.errors, tag.at.errorf("expected for name in expression or for key, value in expression"))
+			return nil
+		}
+
+		tag.names = []string{match[1]}
+
+		if match[2] != "" {
+			tag.names = append(tag.names, match[2])
+		}
+
+		end += len([]rune(match[0]))
+	default:
+		tag.keyword = ""
+		end = start
+	}
+
+	for end < len(text) && unicode.IsSpace(text[end]) {
+		end++
+	}
+
+	tag.code = string(text[end:])
+	tag.codeAt = skip(text, end, piece.at)
+
+	return tag
+}
+
+// compile parses code, at is where it is in the template.
+func (builder *builder) compile(code string, at position) *ast.Program {
+	if trimmed := strings.TrimSpace(code); trimmed != "" && !strings.HasSuffix(trimmed, ";") {
+		code += ";"
+	}
+
+	codeParser := parser.New(tokenizer.NewFromReaderAt(strings.NewReader(code), at.line, at.column))
+	program := codeParser.Parse()
+	builder.errors = append(builder.errors, codeParser.Errors...)
+
+	return program
+}
+
+// expression compiles the code of tag, which must not be empty.
+func (builder *builder) expression(tag *tag) *ast.Program {
+	if strings.TrimSpace(tag.code) == "" {
+		builder.errors = append(builder.errors, tag.at.errorf("expected an expression after %s", tag.keyword))
+	}
+
+	return builder.compile(tag.code, tag.codeAt)
+}
+
+// body parses the nodes of the block opened by open until one of ends, it
+// returns nil when the block isn't closed.
+func (builder *builder) body(open *tag, ends ...string) ([]node, *tag) {
+	nodes, end := builder.parseNodes(ends...)
+
+	if end == nil {
+		builder.errors = append(builder.errors, open.at.errorf("unclosed {%% %s %%}", open.keyword))
+	}
+
+	return nodes, end
+}
+
+// parseNodes parses nodes until a tag whose keyword is in ends, it returns
+// that tag, or nil at the end of the template.
+func (builder *builder) parseNodes(ends ...string) ([]node, *tag) {
+	nodes := []node{}
+
+	for builder.position < len(builder.pieces) {
+		piece := builder.pieces[builder.position]
+		builder.position++
+
+		switch piece.kind {
+		case textPiece:
+			nodes = append(nodes, &textNode{text: piece.text})
+			continue
+		case outputPiece:
+			nodes = append(nodes, &outputNode{code: builder.compile(piece.text, piece.at)})
+			continue
+		}
+
+		tag := builder.parseTag(piece)
+
+		if tag == nil {
+			continue
+		}
+
+		for _, end := range ends {
+			if tag.keyword == end {
+				return nodes, tag
+			}
+		}
+
+		switch tag.keyword {
+		case "":
+			nodes = append(nodes, &statementNode{code: builder.compile(tag.code, tag.codeAt)})
+
+		case "if":
+			node := &ifNode{}
+			condition, open := builder.expression(tag), tag
+
+			for {
+				body, end := builder.body(open, "elif", "else", "end")
+				node.branches = append(node.branches, branch{condition: condition, body: body})
+
+				if end == nil {
+					break
+				}
+
+				if end.keyword == "else" {
+					node.otherwise, _ = builder.body(open, "end")
+					break
+				}
+
+				if end.keyword == "end" {
+					break
+				}
+
+				condition = builder.expression(end)
+			}
+
+			nodes = append(nodes, node)
+
+		case "while":
+			node := &whileNode{condition: builder.expression(tag)}
+			node.body, _ = builder.body(tag, "end")
+			nodes = append(nodes, node)
+
+		case "for":
+			node := &forNode{names: tag.names, iterable: builder.expression(tag), at: tag.codeAt}
+			node.body, _ = builder.body(tag, "end")
+			nodes = append(nodes, node)
+
+		case "include":
+			nodes = append(nodes, &includeNode{name: builder.expression(tag), at: tag.codeAt})
+
+		default:
+			builder.errors = append(builder.errors, tag.at.errorf("unexpected {%% %s %%}", tag.keyword))
+		}
+	}
+
+	return nodes, nil
+}
@@ -0,0 +1,132 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/object"
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, source string, html bool, files map[string]string) (string, error) {
+	template, errors := Parse("page", source)
+
+	if len(errors) != 0 {
+		t.Fatalf("%q - unexpected errors %v", source, errors)
+	}
+
+	var out bytes.Buffer
+	renderer := NewRenderer(&out)
+	renderer.HTML = html
+	renderer.Load = func(name string) (string, error) {
+		if source, ok := files[name]; ok {
+			return source, nil
+		}
+
+		return "", fmt.Errorf("no template %s", name)
+	}
+
+	env := object.NewEnvironment()
+	env.Set("name", &object.StringObject{Value: "<b>Ann</b>"})
+
+	err := renderer.Render(template, env)
+
+	return out.String(), err
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"Hello {{ name }}, {{ 1 + 2 }}{{ if (false) { 1; } }}!", "Hello <b>Ann</b>, 3!"},
+		{"{% if len(name) > 20 %}long{% elif len(name) > 5 %}medium{% else %}short{% end %}", "medium"},
+		{"{% if false %}a{% end %}b", "b"},
+		{"{% let i = 0 %}{% while i < 3 %}{{ i }}{% i++ %}{% end %}", "012"},
+		{"{% for x in [1, 2] %}[{{ x }}]{% end %}", "[1][2]"},
+		{"{% for i, x in [\"a\", \"b\"] %}{{ i }}={{ x }} {% end %}", "0=a 1=b "},
+		{"{% let h = {\"b\": 2, \"a\": 1} %}{% for k in h %}{{ k }}{% end %} {% for k, v in h %}{{ k }}{{ v }}{% end %}", "ab a1b2"},
+		{"{% for c in \"hé\" %}<{{ c }}>{% end %}", "<h><é>"},
+		{"<ul>\n  {% for x in [1, 2] %}\n  <li>{{ x }}</li>\n  {% end %}\n</ul>\n", "<ul>\n  <li>1</li>\n  <li>2</li>\n</ul>\n"},
+		{"a {% let x = 1 %}\n{{ x }}", "a \n1"},
+		{"{{ puts(\"out\") }}text", "out\ntext"},
+		{"{{ {\"a\": {\"b\": 1}}[\"a\"][\"b\"] }} {{ \"}}\" }}", "1 }}"},
+		{"{% include \"header\" %}|{% include \"dir/\" + \"item\" %}", "<h1><b>Ann</b></h1>|item sub"},
+	}
+
+	files := map[string]string{
+		"header":   "<h1>{{ name }}</h1>",
+		"dir/item": "item {% include \"sub\" %}",
+		"dir/sub":  "sub",
+	}
+
+	for _, test := range tests {
+		actual, err := render(t, test.source, false, files)
+
+		if err != nil || actual != test.expected {
+			t.Errorf("%q - expected %q, got %q %v", test.source, test.expected, actual, err)
+		}
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	actual, err := render(t, "<p>{{ name }}</p>{{ 1 < 2 }}", true, nil)
+
+	if err != nil || actual != "<p>&lt;b&gt;Ann&lt;/b&gt;</p>true" {
+		t.Errorf("expected the values to be escaped, got %q %v", actual, err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"line\n  {{ (1 + 2 }}", "Ln 2, Col 13: expected token"},
+		{"{{ 1 + }}", "Ln 1, Col 8: expected an expression"},
+		{"{{ name", "Ln 1, Col 1: unclosed {{"},
+		{"a {{ {\"a\": 1 }}", "Ln 1, Col 3: unbalanced braces in {{"},
+		{"a {{ {\"a\": } }}", "Ln 1, Col 12: no prefix parse function"},
+		{"a\n{% if true %}\nb", "Ln 2, Col 4: unclosed {% if %}"},
+		{"{% end %}", "Ln 1, Col 4: unexpected {% end %}"},
+		{"{% else if x %}", "Ln 1, Col 4: unexpected if x after else"},
+		{"{% for x %}{% end %}", "Ln 1, Col 4: expected for name in expression"},
+		{"{% while %}{% end %}", "Ln 1, Col 4: expected an expression after while"},
+	}
+
+	for _, test := range tests {
+		_, errors := Parse("page", test.source)
+
+		if len(errors) == 0 || !strings.HasPrefix(errors[0], test.expected) {
+			t.Errorf("%q - expected an error starting with %q, got %v", test.source, test.expected, errors)
+		}
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		source   string
+		expected string
+	}{
+		{"a\nb {{ missing }}", "page: Ln 2, Col 6: identifier not found: missing"},
+		{"{% for x in 12 %}{% end %}", "page: Ln 1, Col 13: can't iterate over Integer"},
+		{"{% include \"a\" %}", "b: Ln 1, Col 12: include cycle: a -> b -> a"},
+		{"{% include \"bad\" %}", "bad: Ln 1, Col 4: identifier not found: oops"},
+		{"\n  {% include 1 %}", "page: Ln 2, Col 14: include expects a String, got Integer"},
+		{"{% include \"none\" %}", "page: Ln 1, Col 12: no template none"},
+	}
+
+	files := map[string]string{
+		"a":   "{% include \"b\" %}",
+		"b":   "{% include \"a\" %}",
+		"bad": "{{ oops }}",
+	}
+
+	for _, test := range tests {
+		_, err := render(t, test.source, false, files)
+
+		if err == nil || err.Error() != test.expected {
+			t.Errorf("%q - expected the error %q, got %v", test.source, test.expected, err)
+		}
+	}
+}
@@ -7,9 +7,15 @@ const (
 	Illegal = "Illegal"
 	EOF     = "EOF"
 
+	// Trivia, only produced by Tokenizer.Scan
+	Whitespace = "Whitespace"
+	Comment    = "Comment"
+
 	// Identifier and literals
 	Identifier = "Identifier"
 	Integer    = "Integer"
+	Float      = "Float"
+	String     = "String"
 
 	// Operators
 	Assign     = "Assign"
@@ -17,14 +23,18 @@ const (
 	Minus      = "Minus"
 	Bang       = "Bang"
 	Asterisk   = "Asterisk"
+	Power      = "Power"
 	Slash      = "Slash"
 	LessThan   = "LessThan"
 	BiggerThan = "BiggerThan"
 	Equal      = "Equal"
 	NotEqual   = "NotEqual"
+	Increment  = "Increment"
+	Decrement  = "Decrement"
 
 	// Delemiters
 	Comma              = "Comma"
+	Colon              = "Colon"
 	Semicolon          = "Semicolon"
 	OpeningParenthesis = "OpeningParenthesis"
 	ClosingParenthesis = "ClosingParenthesis"
@@ -67,11 +77,14 @@ var Operators = map[string]TokenType{
 	"-":  Minus,
 	"!":  Bang,
 	"*":  Asterisk,
+	"**": Power,
 	"/":  Slash,
 	"<":  LessThan,
 	">":  BiggerThan,
 	"==": Equal,
 	"!=": NotEqual,
+	"++": Increment,
+	"--": Decrement,
 }
 
 type Token struct {
@@ -0,0 +1,39 @@
+package tokenizer
+
+import "monkey/token"
+
+// Buffer gives arbitrary lookahead over the tokens of a Tokenizer, tokens
+// are only read from the source as far as they have been peeked at.
+type Buffer struct {
+	source *Tokenizer
+	tokens []token.Token
+}
+
+func NewBuffer(source *Tokenizer) *Buffer {
+	return &Buffer{source: source}
+}
+
+func (buffer *Buffer) fill(count int) {
+	for len(buffer.tokens) < count {
+		buffer.tokens = append(buffer.tokens, buffer.source.NextToken())
+	}
+}
+
+// Peek returns the token n positions ahead without consuming anything,
+// Peek(0) is the token the next call to Next will return. Once the input is
+// exhausted every position holds an EOF token.
+func (buffer *Buffer) Peek(n int) token.Token {
+	buffer.fill(n + 1)
+
+	return buffer.tokens[n]
+}
+
+// Next consumes and returns the next token.
+func (buffer *Buffer) Next() token.Token {
+	buffer.fill(1)
+
+	tok := buffer.tokens[0]
+	buffer.tokens = buffer.tokens[1:]
+
+	return tok
+}
@@ -1,22 +1,51 @@
 package tokenizer
 
-import "monkey/token"
-
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"monkey/token"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Tokenizer splits UTF-8 encoded source code into tokens, columns are counted
+// in runes. Invalid encodings produce an Illegal token and an entry in Errors.
+//
+// The source is read through a buffered reader, only the token being read is
+// kept in memory.
 type Tokenizer struct {
-	input        string
-	position     int
-	readPosition int
-	currentChar  byte
+	reader      *bufio.Reader
+	readFailed  bool
+	currentChar rune
+	currentRaw  []byte
+	lexeme      []byte
 
 	currentLine   int
 	currentColumn int
+
+	Errors []string
 }
 
 func New(input string) *Tokenizer {
+	return NewFromReader(strings.NewReader(input))
+}
+
+func NewFromReader(reader io.Reader) *Tokenizer {
+	return NewFromReaderAt(reader, 1, 1)
+}
+
+// NewFromReaderAt reads source code starting at the given line and column,
+// for tokenizing a part of a larger source.
+func NewFromReaderAt(reader io.Reader, line int, column int) *Tokenizer {
 	state := &Tokenizer{
-		input:       input,
-		currentLine: 1,
-		currentChar: 1,
+		reader:        bufio.NewReader(reader),
+		currentLine:   line,
+		currentColumn: column - 1,
+		currentChar:   1,
 	}
 
 	state.readChar()
@@ -32,7 +61,7 @@ func (state *Tokenizer) newToken(tokenType token.TokenType) token.Token {
 	}
 }
 
-func (state *Tokenizer) newTokenChar(tokenType token.TokenType, char byte) token.Token {
+func (state *Tokenizer) newTokenChar(tokenType token.TokenType, char rune) token.Token {
 	token := state.newToken(tokenType)
 	token.Literal = string(char)
 
@@ -46,13 +75,41 @@ func (state *Tokenizer) newTokenString(tokenType token.TokenType, literal string
 	return token
 }
 
+func (state *Tokenizer) errorf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	state.Errors = append(state.Errors, fmt.Sprintf("Ln %d, Col %d: %s", state.currentLine, state.currentColumn, msg))
+}
+
+// beginLexeme starts recording the source text of a token at the current
+// character, readChar appends every character it moves past to it.
+func (state *Tokenizer) beginLexeme() {
+	state.lexeme = state.lexeme[:0]
+}
+
 func (state *Tokenizer) readChar() {
-	if state.readPosition >= len(state.input) {
+	state.lexeme = append(state.lexeme, state.currentRaw...)
+
+	buffer, err := state.reader.Peek(utf8.UTFMax)
+
+	if len(buffer) == 0 {
+		if err != nil && err != io.EOF && !state.readFailed {
+			state.readFailed = true
+			state.errorf("read error: %s", err)
+		}
+
 		state.currentChar = 0
-	} else {
-		state.currentChar = state.input[state.readPosition]
+		state.currentRaw = state.currentRaw[:0]
+		state.currentColumn++
+
+		return
 	}
 
+	char, size := utf8.DecodeRune(buffer)
+
+	state.currentChar = char
+	state.currentRaw = append(state.currentRaw[:0], buffer[:size]...)
+	state.reader.Discard(size)
+
 	if state.currentChar == '\n' {
 		state.currentLine++
 		state.currentColumn = 0
@@ -60,38 +117,93 @@ func (state *Tokenizer) readChar() {
 		state.currentColumn++
 	}
 
-	state.position = state.readPosition
-	state.readPosition++
+	if char == utf8.RuneError && size == 1 {
+		state.errorf("invalid UTF-8 encoding, unexpected byte 0x%02x", state.currentRaw[0])
+	}
 }
 
-func (state *Tokenizer) peekChar() byte {
-	if state.readPosition >= len(state.input) {
+func (state *Tokenizer) peekChar() rune {
+	buffer, _ := state.reader.Peek(utf8.UTFMax)
+
+	if len(buffer) == 0 {
 		return 0
 	}
 
-	return state.input[state.readPosition]
+	char, _ := utf8.DecodeRune(buffer)
+
+	return char
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z'
+func isLetter(char rune) bool {
+	return 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z'
 }
 
-func isIdentifier(char byte) bool {
-	return isLetter(char) || char == '_'
+// isIdentifierStart follows the UAX #31 default identifier syntax, ID_Start,
+// extended with the underscore.
+func isIdentifierStart(char rune) bool {
+	if char < utf8.RuneSelf {
+		return isLetter(char) || char == '_'
+	}
+
+	return unicode.In(char, unicode.L, unicode.Nl, unicode.Other_ID_Start) &&
+		!unicode.In(char, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
 
-func isWitespace(char byte) bool {
-	return char == ' ' || char == '\t' || char == '\n' || char == '\r'
+// isIdentifier follows the UAX #31 default identifier syntax, ID_Continue.
+func isIdentifier(char rune) bool {
+	if char < utf8.RuneSelf {
+		return isLetter(char) || isNumber(char) || char == '_'
+	}
+
+	return isIdentifierStart(char) ||
+		unicode.In(char, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc, unicode.Other_ID_Continue) &&
+			!unicode.In(char, unicode.Pattern_Syntax, unicode.Pattern_White_Space)
 }
 
-func isNumber(char byte) bool {
+func isWitespace(char rune) bool {
+	if char < utf8.RuneSelf {
+		return char == ' ' || char == '\t' || char == '\n' || char == '\r' || char == '\v' || char == '\f'
+	}
+
+	return unicode.Is(unicode.Pattern_White_Space, char)
+}
+
+func isNumber(char rune) bool {
 	return '0' <= char && char <= '9'
 }
 
-func (state *Tokenizer) eatWhitespace() {
+func (state *Tokenizer) readWhitespace() token.Token {
+	tok := state.newToken(token.Whitespace)
+
+	state.beginLexeme()
+
 	for isWitespace(state.currentChar) {
 		state.readChar()
 	}
+
+	tok.Literal = string(state.lexeme)
+
+	return tok
+}
+
+// readComment reads a line comment up to, but not including, the newline.
+func (state *Tokenizer) readComment() token.Token {
+	tok := state.newToken(token.Comment)
+
+	state.beginLexeme()
+
+	for state.currentChar != '\n' && !state.atEOF() {
+		state.readChar()
+	}
+
+	tok.Literal = string(state.lexeme)
+
+	return tok
+}
+
+// atEOF tells the end of the input apart from a NUL character in it.
+func (state *Tokenizer) atEOF() bool {
+	return state.currentChar == 0 && len(state.currentRaw) == 0
 }
 
 func lookupIdentifier(identifier string) token.TokenType {
@@ -105,61 +217,190 @@ func lookupIdentifier(identifier string) token.TokenType {
 func (state *Tokenizer) readIdentifier() token.Token {
 	token := state.newTokenString(token.Identifier, "")
 
-	position := state.position
+	state.beginLexeme()
 
 	for isIdentifier(state.currentChar) {
 		state.readChar()
 	}
 
-	token.Literal = state.input[position:state.position]
-	token.Type = lookupIdentifier(token.Literal)
+	// The literal keeps the source spelling, NextToken normalizes it.
+	token.Literal = string(state.lexeme)
+	token.Type = lookupIdentifier(norm.NFC.String(token.Literal))
 
 	return token
 }
 
+func isHexNumber(char rune) bool {
+	return isNumber(char) || 'a' <= char && char <= 'f' || 'A' <= char && char <= 'F'
+}
+
+func (state *Tokenizer) readDigits(isDigit func(rune) bool) {
+	for isDigit(state.currentChar) || state.currentChar == '_' {
+		state.readChar()
+	}
+}
+
+// readNumber reads integer literals in decimal, hexadecimal (0x), binary (0b)
+// and octal (0o) notations, and decimal floating-point literals with an
+// optional exponent. Digits may be separated by underscores, validating the
+// literal is left to the parser.
 func (state *Tokenizer) readNumber() token.Token {
-	token := state.newToken(token.Integer)
+	tok := state.newToken(token.Integer)
 
-	position := state.position
+	state.beginLexeme()
 
-	for isNumber(state.currentChar) {
+	if state.currentChar == '0' && isLetter(state.peekChar()) && state.peekChar() != 'e' && state.peekChar() != 'E' {
 		state.readChar()
+
+		switch state.currentChar {
+		case 'x', 'X':
+			state.readChar()
+			state.readDigits(isHexNumber)
+		case 'b', 'B', 'o', 'O':
+			state.readChar()
+			state.readDigits(isNumber)
+		}
+	} else {
+		state.readDigits(isNumber)
+
+		if state.currentChar == '.' && isNumber(state.peekChar()) {
+			tok.Type = token.Float
+			state.readChar()
+			state.readDigits(isNumber)
+		}
+
+		if state.currentChar == 'e' || state.currentChar == 'E' {
+			if next := state.peekChar(); isNumber(next) || next == '+' || next == '-' {
+				tok.Type = token.Float
+				state.readChar()
+				state.readChar()
+				state.readDigits(isNumber)
+			}
+		}
 	}
 
-	token.Literal = state.input[position:state.position]
+	// Swallow anything glued to the literal so "0b12" or "12abc" are
+	// reported as one malformed number instead of a confusing token soup.
+	for isIdentifier(state.currentChar) || isNumber(state.currentChar) {
+		state.readChar()
+	}
 
-	return token
+	tok.Literal = string(state.lexeme)
+
+	return tok
+}
+
+// readString reads a string literal up to its closing quote, or the end of
+// the line when it is missing. Escapes are skipped over, decoding and
+// validating the literal is left to the parser.
+func (state *Tokenizer) readString() token.Token {
+	tok := state.newToken(token.String)
+
+	state.beginLexeme()
+	state.readChar()
+
+	for state.currentChar != '"' && state.currentChar != '\n' && !state.atEOF() {
+		if state.currentChar == '\\' && state.peekChar() != '\n' {
+			state.readChar()
+		}
+
+		state.readChar()
+	}
+
+	if state.currentChar == '"' {
+		state.readChar()
+	}
+
+	tok.Literal = string(state.lexeme)
+
+	return tok
 }
 
-// NextToken get the next token a currentPosition in the input string.
+// NextToken get the next token at the current position in the input,
+// skipping whitespace and comments.
 func (state *Tokenizer) NextToken() token.Token {
-	state.eatWhitespace()
+	for {
+		tok := state.Scan()
+
+		switch tok.Type {
+		case token.Whitespace, token.Comment:
+			continue
+		case token.Identifier:
+			// Canonically equivalent spellings of an identifier are the same name.
+			tok.Literal = norm.NFC.String(tok.Literal)
+		}
+
+		return tok
+	}
+}
+
+// Scan gets the next token like NextToken but also returns whitespace and
+// comments as tokens. Literals are the exact source text, so concatenating
+// the literals of every scanned token gives back the input.
+func (state *Tokenizer) Scan() token.Token {
+	if state.atEOF() {
+		tok := state.newTokenString(token.EOF, "")
+		state.readChar()
+
+		return tok
+	}
+
+	if isWitespace(state.currentChar) {
+		return state.readWhitespace()
+	}
+
+	if state.currentChar == '/' && state.peekChar() == '/' {
+		return state.readComment()
+	}
 
 	tok := state.newTokenChar(token.Illegal, state.currentChar)
 
 	switch state.currentChar {
 	case '=':
 		if state.peekChar() == '=' {
+			tok = state.newTokenString(token.Equal, "")
 			char := state.currentChar
 			state.readChar()
-			tok = state.newTokenString(token.Equal, string(char)+string(state.currentChar))
+			tok.Literal = string(char) + string(state.currentChar)
 		} else {
 			tok = state.newTokenChar(token.Assign, state.currentChar)
 		}
 	case '+':
-		tok = state.newTokenChar(token.Plus, state.currentChar)
+		if state.peekChar() == '+' {
+			tok = state.newTokenString(token.Increment, "")
+			char := state.currentChar
+			state.readChar()
+			tok.Literal = string(char) + string(state.currentChar)
+		} else {
+			tok = state.newTokenChar(token.Plus, state.currentChar)
+		}
 	case '-':
-		tok = state.newTokenChar(token.Minus, state.currentChar)
+		if state.peekChar() == '-' {
+			tok = state.newTokenString(token.Decrement, "")
+			char := state.currentChar
+			state.readChar()
+			tok.Literal = string(char) + string(state.currentChar)
+		} else {
+			tok = state.newTokenChar(token.Minus, state.currentChar)
+		}
 	case '!':
 		if state.peekChar() == '=' {
+			tok = state.newTokenString(token.NotEqual, "")
 			char := state.currentChar
 			state.readChar()
-			tok = state.newTokenString(token.NotEqual, string(char)+string(state.currentChar))
+			tok.Literal = string(char) + string(state.currentChar)
 		} else {
 			tok = state.newTokenChar(token.Bang, state.currentChar)
 		}
 	case '*':
-		tok = state.newTokenChar(token.Asterisk, state.currentChar)
+		if state.peekChar() == '*' {
+			tok = state.newTokenString(token.Power, "")
+			char := state.currentChar
+			state.readChar()
+			tok.Literal = string(char) + string(state.currentChar)
+		} else {
+			tok = state.newTokenChar(token.Asterisk, state.currentChar)
+		}
 	case '/':
 		tok = state.newTokenChar(token.Slash, state.currentChar)
 	case '<':
@@ -170,6 +411,8 @@ func (state *Tokenizer) NextToken() token.Token {
 		tok = state.newTokenChar(token.Comma, state.currentChar)
 	case ';':
 		tok = state.newTokenChar(token.Semicolon, state.currentChar)
+	case ':':
+		tok = state.newTokenChar(token.Colon, state.currentChar)
 	case '(':
 		tok = state.newTokenChar(token.OpeningParenthesis, state.currentChar)
 	case ')':
@@ -182,15 +425,16 @@ func (state *Tokenizer) NextToken() token.Token {
 		tok = state.newTokenChar(token.OpeningBracket, state.currentChar)
 	case ']':
 		tok = state.newTokenChar(token.ClosingBracket, state.currentChar)
-	case 0:
-		tok = state.newTokenString(token.EOF, "")
-
+	case '"':
+		return state.readString()
 	default:
-		if isIdentifier(state.currentChar) {
+		if isIdentifierStart(state.currentChar) {
 			return state.readIdentifier()
 		} else if isNumber(state.currentChar) {
 			return state.readNumber()
 		}
+
+		tok.Literal = string(state.currentRaw)
 	}
 
 	state.readChar()
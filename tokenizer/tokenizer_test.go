@@ -1,15 +1,19 @@
 package tokenizer
 
 import (
+	"errors"
+	"io"
 	"monkey/token"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestNextToken(t *testing.T) {
 	input := `let five = 5;
 	let ten = 10;
 	
-	let add = fn(x, y) {
+	let add = function(x, y) {
 		x + y;
 	};
 	
@@ -40,11 +44,11 @@ func TestNextToken(t *testing.T) {
 		{token.Integer, "10"},
 		{token.Semicolon, ";"},
 
-		// let add = fn(x, y) { x + y; };
+		// let add = function(x, y) { x + y; };
 		{token.Let, "let"},
 		{token.Identifier, "add"},
 		{token.Assign, "="},
-		{token.Function, "fn"},
+		{token.Function, "function"},
 		{token.OpeningParenthesis, "("},
 		{token.Identifier, "x"},
 		{token.Comma, ","},
@@ -128,3 +132,322 @@ func TestNextToken(t *testing.T) {
 		}
 	}
 }
+
+func TestIncrementDecrement(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []token.TokenType
+	}{
+		{"a - -b", []token.TokenType{token.Identifier, token.Minus, token.Minus, token.Identifier, token.EOF}},
+		{"a--b", []token.TokenType{token.Identifier, token.Decrement, token.Identifier, token.EOF}},
+		{"a + +b", []token.TokenType{token.Identifier, token.Plus, token.Plus, token.Identifier, token.EOF}},
+		{"a++b", []token.TokenType{token.Identifier, token.Increment, token.Identifier, token.EOF}},
+		{"a---b", []token.TokenType{token.Identifier, token.Decrement, token.Minus, token.Identifier, token.EOF}},
+		{"++a--", []token.TokenType{token.Increment, token.Identifier, token.Decrement, token.EOF}},
+	}
+
+	for _, test := range tests {
+		state := New(test.input)
+
+		for i, expected := range test.expected {
+			tok := state.NextToken()
+
+			if tok.Type != expected {
+				t.Fatalf("%q token[%d] - TokenType wrong. expected=%q, got=%q", test.input, i, expected, tok.Type)
+			}
+		}
+	}
+}
+
+func TestNumberLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{"42", token.Integer, "42"},
+		{"0xFF", token.Integer, "0xFF"},
+		{"0b1010", token.Integer, "0b1010"},
+		{"0o755", token.Integer, "0o755"},
+		{"1_000_000", token.Integer, "1_000_000"},
+		{"3.14", token.Float, "3.14"},
+		{"1.5e-3", token.Float, "1.5e-3"},
+		{"2E10", token.Float, "2E10"},
+		{"1_000.000_1", token.Float, "1_000.000_1"},
+		{"0b12", token.Integer, "0b12"},
+		{"12abc", token.Integer, "12abc"},
+	}
+
+	for _, test := range tests {
+		state := New(test.input)
+		tok := state.NextToken()
+
+		if tok.Type != test.expectedType {
+			t.Errorf("%q - TokenType wrong. expected=%q, got=%q", test.input, test.expectedType, tok.Type)
+		}
+
+		if tok.Literal != test.expectedLiteral {
+			t.Errorf("%q - Literal wrong. expected=%q, got=%q", test.input, test.expectedLiteral, tok.Literal)
+		}
+
+		if next := state.NextToken(); next.Type != token.EOF {
+			t.Errorf("%q - expected a single token, got %q after it", test.input, next.Literal)
+		}
+	}
+}
+
+func TestStringLiterals(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		rest            token.TokenType
+	}{
+		{`"hello"`, `"hello"`, token.EOF},
+		{`""`, `""`, token.EOF},
+		{`"a \"quoted\" word"`, `"a \"quoted\" word"`, token.EOF},
+		{`"tab\t\\";`, `"tab\t\\"`, token.Semicolon},
+		{`"héllo, 世界"`, `"héllo, 世界"`, token.EOF},
+		{`"key": 1`, `"key"`, token.Colon},
+		{"\"unterminated\nx", `"unterminated`, token.Identifier},
+		{`"unterminated\`, `"unterminated\`, token.EOF},
+	}
+
+	for _, test := range tests {
+		state := New(test.input)
+		tok := state.NextToken()
+
+		if tok.Type != token.String || tok.Literal != test.expectedLiteral {
+			t.Errorf("%q - expected a string %q, got %+v", test.input, test.expectedLiteral, tok)
+		}
+
+		if next := state.NextToken(); next.Type != test.rest {
+			t.Errorf("%q - expected %s after the string, got %+v", test.input, test.rest, next)
+		}
+	}
+}
+
+func TestUnicodeIdentifiers(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{"café", "café", 1},
+		{"数量", "数量", 1},
+		{"x1", "x1", 1},
+		{"_tmp", "_tmp", 1},
+		{"cafe\u0301", "caf\u00e9", 1},
+		{"Ωmega_٣", "Ωmega_٣", 1},
+		{"    ünïcode", "ünïcode", 5},
+	}
+
+	for _, test := range tests {
+		tok := New(test.input).NextToken()
+
+		if tok.Type != token.Identifier {
+			t.Errorf("%q - TokenType wrong. expected=%q, got=%q", test.input, token.Identifier, tok.Type)
+		}
+
+		if tok.Literal != test.expectedLiteral {
+			t.Errorf("%q - Literal wrong. expected=%q, got=%q", test.input, test.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Column != test.expectedColumn {
+			t.Errorf("%q - Column wrong. expected=%d, got=%d", test.input, test.expectedColumn, tok.Column)
+		}
+	}
+}
+
+func TestUnicodeColumns(t *testing.T) {
+	state := New("let 数量 = «;")
+
+	expected := []struct {
+		tokenType token.TokenType
+		column    int
+	}{
+		{token.Let, 1},
+		{token.Identifier, 5},
+		{token.Assign, 8},
+		{token.Illegal, 10},
+		{token.Semicolon, 11},
+	}
+
+	for i, test := range expected {
+		tok := state.NextToken()
+
+		if tok.Type != test.tokenType || tok.Column != test.column {
+			t.Errorf("token[%d] - expected %s at col %d, got %s at col %d", i, test.tokenType, test.column, tok.Type, tok.Column)
+		}
+	}
+}
+
+func TestInvalidUTF8(t *testing.T) {
+	state := New("let é = 1;\nlet a\xff = 2;")
+
+	for tok := state.NextToken(); tok.Type != token.EOF; tok = state.NextToken() {
+	}
+
+	if len(state.Errors) != 1 {
+		t.Fatalf("expected 1 error, got %d: %v", len(state.Errors), state.Errors)
+	}
+
+	if expected := "Ln 2, Col 6: invalid UTF-8 encoding, unexpected byte 0xff"; state.Errors[0] != expected {
+		t.Errorf("expected error %q, got %q", expected, state.Errors[0])
+	}
+}
+
+func TestReaderMatchesString(t *testing.T) {
+	input := "let café = 0xFF + 1.5e3;\nlet 数量 = a++ - --b;\n\xff if (x != 10) { return x ** 2; }"
+
+	expected := New(input)
+	readers := []io.Reader{
+		iotest.OneByteReader(strings.NewReader(input)),
+		iotest.HalfReader(strings.NewReader(input)),
+		iotest.DataErrReader(strings.NewReader(input)),
+	}
+
+	var tokens []token.Token
+	for tok := expected.NextToken(); tok.Type != token.EOF; tok = expected.NextToken() {
+		tokens = append(tokens, tok)
+	}
+
+	for i, reader := range readers {
+		state := NewFromReader(reader)
+
+		for j, want := range tokens {
+			if got := state.NextToken(); got != want {
+				t.Fatalf("reader[%d] token[%d] - expected %+v, got %+v", i, j, want, got)
+			}
+		}
+
+		if tok := state.NextToken(); tok.Type != token.EOF {
+			t.Fatalf("reader[%d] - expected EOF, got %+v", i, tok)
+		}
+
+		if len(state.Errors) != len(expected.Errors) {
+			t.Fatalf("reader[%d] - expected errors %v, got %v", i, expected.Errors, state.Errors)
+		}
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	state := NewFromReader(io.MultiReader(strings.NewReader("let a"), iotest.ErrReader(errors.New("disk on fire"))))
+
+	expected := []token.TokenType{token.Let, token.Identifier, token.EOF, token.EOF}
+
+	for i, tokenType := range expected {
+		if tok := state.NextToken(); tok.Type != tokenType {
+			t.Fatalf("token[%d] - expected %s, got %s", i, tokenType, tok.Type)
+		}
+	}
+
+	if len(state.Errors) != 1 {
+		t.Fatalf("expected the read error to be reported once, got %v", state.Errors)
+	}
+}
+
+func TestBuffer(t *testing.T) {
+	buffer := NewBuffer(New("a + b;"))
+
+	if tok := buffer.Peek(3); tok.Type != token.Semicolon {
+		t.Fatalf("Peek(3) - expected %s, got %s", token.Semicolon, tok.Type)
+	}
+
+	if tok := buffer.Peek(10); tok.Type != token.EOF {
+		t.Fatalf("Peek(10) - expected %s, got %s", token.EOF, tok.Type)
+	}
+
+	expected := []token.TokenType{token.Identifier, token.Plus, token.Identifier, token.Semicolon, token.EOF, token.EOF}
+
+	for i, tokenType := range expected {
+		if tok := buffer.Peek(0); tok.Type != tokenType {
+			t.Fatalf("token[%d] - Peek(0) expected %s, got %s", i, tokenType, tok.Type)
+		}
+
+		if tok := buffer.Next(); tok.Type != tokenType {
+			t.Fatalf("token[%d] - Next() expected %s, got %s", i, tokenType, tok.Type)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := "let a = 1; // one\n// nothing / here\na / 2 //"
+
+	expected := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Let, "let"},
+		{token.Identifier, "a"},
+		{token.Assign, "="},
+		{token.Integer, "1"},
+		{token.Semicolon, ";"},
+		{token.Identifier, "a"},
+		{token.Slash, "/"},
+		{token.Integer, "2"},
+		{token.EOF, ""},
+	}
+
+	state := New(input)
+
+	for i, want := range expected {
+		tok := state.NextToken()
+
+		if tok.Type != want.expectedType || tok.Literal != want.expectedLiteral {
+			t.Fatalf("tests[%d] - expected %s %q, got %s %q", i, want.expectedType, want.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestScanIsLossless(t *testing.T) {
+	inputs := []string{
+		"",
+		"let a = 1;",
+		"  let\ta=1 ;  // trailing comment",
+		"// only a comment\n\n",
+		"let café = 0x_FF + 1.5e3;\r\n\xff # \x00 end",
+		"if (a != b) {\n\treturn a ** 2; // power\n} else { b-- };\n",
+	}
+
+	for _, input := range inputs {
+		state := New(input)
+		var builder strings.Builder
+
+		for tok := state.Scan(); tok.Type != token.EOF; tok = state.Scan() {
+			builder.WriteString(tok.Literal)
+		}
+
+		if builder.String() != input {
+			t.Errorf("%q - scanned text is %q", input, builder.String())
+		}
+	}
+}
+
+func TestScanTrivia(t *testing.T) {
+	state := New("a  // note\n\tb")
+
+	expected := []token.Token{
+		{Type: token.Identifier, Literal: "a", Line: 1, Column: 1},
+		{Type: token.Whitespace, Literal: "  ", Line: 1, Column: 2},
+		{Type: token.Comment, Literal: "// note", Line: 1, Column: 4},
+		{Type: token.Whitespace, Literal: "\n\t", Line: 2, Column: 0},
+		{Type: token.Identifier, Literal: "b", Line: 2, Column: 2},
+		{Type: token.EOF, Literal: "", Line: 2, Column: 3},
+	}
+
+	for i, want := range expected {
+		if got := state.Scan(); got != want {
+			t.Fatalf("tests[%d] - expected %+v, got %+v", i, want, got)
+		}
+	}
+}
+
+func TestOperatorColumns(t *testing.T) {
+	state := New("a == b ** c++")
+
+	for _, want := range []int{1, 3, 6, 8, 11, 12, 14} {
+		if tok := state.NextToken(); tok.Column != want {
+			t.Errorf("%q - expected column %d, got %d", tok.Literal, want, tok.Column)
+		}
+	}
+}
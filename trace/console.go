@@ -0,0 +1,219 @@
+package trace
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const ConsolePrompt = "(replay) "
+
+const consoleHelp = `forward [count]   move forward, one entry by default
+back [count]      move backward, one entry by default
+start             move before the first entry
+end               move to the end of the program
+last name         move back to the last change of name
+history name      show every value name took
+print name        show the value of name at the current entry
+locals            show the variables of the current frame
+bt                show the calls active at the current entry
+quit              leave the replay
+`
+
+// Console is a command line front-end replaying a trace, it reads commands
+// from a stream and writes the state of the program to another.
+type Console struct {
+	replay *Replay
+	lines  []string
+	out    io.Writer
+}
+
+// NewConsole creates a console replaying trace, source is the program it
+// was recorded from, empty if it isn't available anymore.
+func NewConsole(trace *Trace, source string, out io.Writer) *Console {
+	console := &Console{replay: NewReplay(trace), out: out}
+
+	if source != "" {
+		console.lines = strings.Split(source, "\n")
+	}
+
+	return console
+}
+
+// Run reads and runs commands until the input ends or the user quits, an
+// empty line repeats the previous command.
+func (console *Console) Run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+	previous := ""
+
+	console.printf("%d entries recorded from %s.\n", len(console.replay.Trace.Entries), console.replay.Trace.Path)
+
+	for {
+		fmt.Fprint(console.out, ConsolePrompt)
+
+		if !scanner.Scan() {
+			fmt.Fprintln(console.out)
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			line = previous
+		}
+
+		previous = line
+
+		if line == "" {
+			continue
+		}
+
+		if !console.execute(line) {
+			return
+		}
+	}
+}
+
+// execute runs a command line, it returns false once the user quits.
+func (console *Console) execute(line string) bool {
+	command, argument, _ := strings.Cut(line, " ")
+	argument = strings.TrimSpace(argument)
+
+	switch command {
+	case "forward", "f", "next", "n":
+		console.move(argument, console.replay.Forward)
+
+	case "back", "b", "reverse-next", "rn":
+		console.move(argument, console.replay.Backward)
+
+	case "start":
+		console.replay.Seek(-1)
+		console.printf("Before the first entry.\n")
+
+	case "end":
+		console.replay.Seek(len(console.replay.Trace.Entries) - 1)
+		console.show()
+
+	case "last":
+		if position, ok := console.replay.LastChange(argument); ok {
+			console.replay.Seek(position)
+			console.show()
+		} else {
+			console.printf("%s didn't change before this entry.\n", argument)
+		}
+
+	case "history":
+		console.history(argument)
+
+	case "print", "p":
+		if entry, ok := console.replay.Value(argument); ok {
+			console.printf("%s = %s\n", argument, entry.Value)
+		} else {
+			console.printf("No variable %s at this entry.\n", argument)
+		}
+
+	case "locals":
+		locals := console.replay.Locals()
+
+		if len(locals) == 0 {
+			console.printf("No locals.\n")
+		}
+
+		for _, entry := range locals {
+			console.printf("%s = %s\n", entry.Name, entry.Value)
+		}
+
+	case "bt", "backtrace", "where":
+		for i, call := range console.replay.Stack() {
+			console.printf("#%d  %s(%s) at %s\n", i, call.Name, call.Value, console.location(call))
+		}
+
+		console.printf("#%d  main\n", len(console.replay.Stack()))
+
+	case "help", "h":
+		console.printf("%s", consoleHelp)
+
+	case "quit", "q":
+		return false
+
+	default:
+		console.printf("Undefined command: %q. Try \"help\".\n", command)
+	}
+
+	return true
+}
+
+func (console *Console) printf(format string, a ...interface{}) {
+	fmt.Fprintf(console.out, format, a...)
+}
+
+func (console *Console) move(argument string, step func() bool) {
+	count := 1
+
+	if argument != "" {
+		parsed, err := strconv.Atoi(argument)
+
+		if err != nil || parsed < 1 {
+			console.printf("Expected a count, got %q.\n", argument)
+			return
+		}
+
+		count = parsed
+	}
+
+	for i := 0; i < count; i++ {
+		if !step() {
+			if i == 0 {
+				console.printf("No more entries.\n")
+				return
+			}
+
+			break
+		}
+	}
+
+	if console.replay.Position() == -1 {
+		console.printf("Before the first entry.\n")
+		return
+	}
+
+	console.show()
+}
+
+func (console *Console) history(name string) {
+	history := console.replay.History(name)
+
+	if len(history) == 0 {
+		console.printf("%s never changed.\n", name)
+		return
+	}
+
+	for _, position := range history {
+		entry := console.replay.Trace.Entries[position]
+		console.printf("#%d  %s = %s at %s\n", position, name, entry.Value, console.location(entry))
+	}
+}
+
+// show prints the current entry and its source line.
+func (console *Console) show() {
+	entry, _ := console.replay.Current()
+	console.printf("#%d  %s", console.replay.Position(), entry)
+
+	if entry.Kind == KindExit {
+		console.printf("\n")
+		return
+	}
+
+	console.printf(" at %s\n", console.location(entry))
+
+	if entry.Line >= 1 && entry.Line <= len(console.lines) {
+		console.printf("%d\t%s\n", entry.Line, console.lines[entry.Line-1])
+	}
+}
+
+func (console *Console) location(entry Entry) string {
+	return fmt.Sprintf("%s:%d:%d", filepath.Base(console.replay.Trace.Path), entry.Line, entry.Column)
+}
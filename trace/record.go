@@ -0,0 +1,76 @@
+package trace
+
+import (
+	"monkey/ast"
+	"monkey/evaluator"
+	"monkey/object"
+	"strings"
+)
+
+// Recorder is an evaluator hook building the trace of the program it
+// observes. It only looks at the program, so recording the same program
+// twice gives the same trace.
+type Recorder struct {
+	Trace *Trace
+}
+
+// Record runs program, read from path, and returns its trace and result.
+func Record(path string, program *ast.Program) (*Trace, object.Object) {
+	recorder := &Recorder{Trace: &Trace{Path: path}}
+
+	evaluator := evaluator.New()
+	evaluator.Hook = recorder
+
+	result := evaluator.Eval(program, object.NewEnvironment())
+	recorder.Exit(result)
+
+	return recorder.Trace, result
+}
+
+func (recorder *Recorder) add(entry Entry) {
+	recorder.Trace.Entries = append(recorder.Trace.Entries, entry)
+}
+
+func (recorder *Recorder) BeforeStatement(statement ast.Statement, env *object.Environment) error {
+	return nil
+}
+
+func (recorder *Recorder) EnterFunction(call *ast.CallExpression, function *object.FunctionObject, env *object.Environment) {
+	arguments := []string{}
+
+	for _, parameter := range function.Parameters {
+		value, _ := env.Get(parameter.Value)
+		arguments = append(arguments, value.Inspect())
+	}
+
+	recorder.add(Entry{Kind: KindCall, Line: call.Token.Line, Column: call.Token.Column, Name: functionName(call), Value: strings.Join(arguments, ", ")})
+}
+
+func (recorder *Recorder) LeaveFunction(call *ast.CallExpression, result object.Object) {
+	recorder.add(Entry{Kind: KindReturn, Line: call.Token.Line, Column: call.Token.Column, Name: functionName(call), Value: inspect(result)})
+}
+
+func (recorder *Recorder) Bind(identifier *ast.IdentifierLiteral, value object.Object, env *object.Environment) {
+	recorder.add(Entry{Kind: KindBind, Line: identifier.Token.Line, Column: identifier.Token.Column, Name: identifier.Value, Value: inspect(value)})
+}
+
+// Exit records the result of the program.
+func (recorder *Recorder) Exit(result object.Object) {
+	recorder.add(Entry{Kind: KindExit, Value: inspect(result)})
+}
+
+func functionName(call *ast.CallExpression) string {
+	if identifier, ok := call.Function.(*ast.IdentifierLiteral); ok {
+		return identifier.Value
+	}
+
+	return "function"
+}
+
+func inspect(value object.Object) string {
+	if value == nil {
+		return evaluator.Null.Inspect()
+	}
+
+	return value.Inspect()
+}
@@ -0,0 +1,157 @@
+package trace
+
+import "sort"
+
+// Replay moves through a trace in both directions, rebuilding the state of
+// the program at each entry from the entries before it.
+type Replay struct {
+	Trace *Trace
+
+	// position is the index of the current entry, -1 before the first.
+	position int
+
+	// frames maps each entry to the index of the call entry of the frame
+	// it happened in, -1 for the main program.
+	frames []int
+}
+
+// NewReplay creates a replay positioned before the first entry of trace.
+func NewReplay(trace *Trace) *Replay {
+	replay := &Replay{Trace: trace, position: -1, frames: make([]int, len(trace.Entries))}
+	stack := []int{-1}
+
+	for i, entry := range trace.Entries {
+		replay.frames[i] = stack[len(stack)-1]
+
+		switch entry.Kind {
+		case KindCall:
+			stack = append(stack, i)
+		case KindReturn:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	return replay
+}
+
+// Position returns the index of the current entry, -1 before the first.
+func (replay *Replay) Position() int {
+	return replay.position
+}
+
+// Current returns the current entry, if there's one.
+func (replay *Replay) Current() (Entry, bool) {
+	if replay.position < 0 {
+		return Entry{}, false
+	}
+
+	return replay.Trace.Entries[replay.position], true
+}
+
+// Seek moves to the entry at position, -1 being before the first one.
+func (replay *Replay) Seek(position int) bool {
+	if position < -1 || position >= len(replay.Trace.Entries) {
+		return false
+	}
+
+	replay.position = position
+
+	return true
+}
+
+// Forward moves to the next entry.
+func (replay *Replay) Forward() bool {
+	return replay.Seek(replay.position + 1)
+}
+
+// Backward moves to the previous entry.
+func (replay *Replay) Backward() bool {
+	return replay.Seek(replay.position - 1)
+}
+
+// frame returns the call entry of the frame at position, -1 for the main
+// program.
+func (replay *Replay) frame(position int) int {
+	if position < 0 {
+		return -1
+	}
+
+	return replay.frames[position]
+}
+
+// Stack returns the calls active at the current entry, innermost first.
+func (replay *Replay) Stack() []Entry {
+	calls := []Entry{}
+
+	for call := replay.frame(replay.position); call != -1; call = replay.frames[call] {
+		calls = append(calls, replay.Trace.Entries[call])
+	}
+
+	return calls
+}
+
+// Value returns the entry that last bound name up to the current entry. The
+// name is looked up in the active frames, innermost first.
+func (replay *Replay) Value(name string) (Entry, bool) {
+	for frame := replay.frame(replay.position); ; frame = replay.frames[frame] {
+		for i := replay.position; i > frame; i-- {
+			entry := replay.Trace.Entries[i]
+
+			if entry.Kind == KindBind && entry.Name == name && replay.frames[i] == frame {
+				return entry, true
+			}
+		}
+
+		if frame == -1 {
+			return Entry{}, false
+		}
+	}
+}
+
+// Locals returns the last binding of each name in the frame of the current
+// entry up to it, sorted by name.
+func (replay *Replay) Locals() []Entry {
+	frame := replay.frame(replay.position)
+	seen := map[string]bool{}
+	locals := []Entry{}
+
+	for i := replay.position; i > frame; i-- {
+		entry := replay.Trace.Entries[i]
+
+		if entry.Kind == KindBind && replay.frames[i] == frame && !seen[entry.Name] {
+			seen[entry.Name] = true
+			locals = append(locals, entry)
+		}
+	}
+
+	sort.Slice(locals, func(i, j int) bool { return locals[i].Name < locals[j].Name })
+
+	return locals
+}
+
+// LastChange returns the position of the last change of name before the
+// current entry, in any frame.
+func (replay *Replay) LastChange(name string) (int, bool) {
+	for i := replay.position - 1; i >= 0; i-- {
+		if entry := replay.Trace.Entries[i]; entry.Kind == KindBind && entry.Name == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+// History returns the positions of all the changes of name.
+func (replay *Replay) History(name string) []int {
+	history := []int{}
+
+	for i, entry := range replay.Trace.Entries {
+		if entry.Kind == KindBind && entry.Name == name {
+			history = append(history, i)
+		}
+	}
+
+	return history
+}
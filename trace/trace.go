@@ -0,0 +1,162 @@
+// Package trace records the execution of a program, every binding change,
+// call and return with its source position, and replays the recording
+// without running the program again.
+//
+// A trace is a text file with a header line followed by one entry per line:
+//
+//	monkey-trace 1 "main.monkey"
+//	b 1:5 add "function(a, b) {...}"
+//	c 5:12 add "1, 2"
+//	r 5:12 add "3"
+//	x 0:0 - "6"
+//
+// Each entry starts with its kind, bind, call, return or exit, then its
+// position, a name and a quoted value. The value of a call is its
+// arguments and the name of an exit is unused.
+package trace
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const version = 1
+
+// Kind is the kind of a trace entry.
+type Kind byte
+
+const (
+	KindBind   Kind = 'b'
+	KindCall   Kind = 'c'
+	KindReturn Kind = 'r'
+	KindExit   Kind = 'x'
+)
+
+// Entry is something that happened while the program ran.
+type Entry struct {
+	Kind   Kind
+	Line   int
+	Column int
+	Name   string
+	Value  string
+}
+
+func (entry Entry) String() string {
+	switch entry.Kind {
+	case KindBind:
+		return fmt.Sprintf("%s = %s", entry.Name, entry.Value)
+	case KindCall:
+		return fmt.Sprintf("call %s(%s)", entry.Name, entry.Value)
+	case KindReturn:
+		return fmt.Sprintf("%s returned %s", entry.Name, entry.Value)
+	default:
+		return fmt.Sprintf("program ended with %s", entry.Value)
+	}
+}
+
+// Trace is the recording of a run of the program read from Path.
+type Trace struct {
+	Path    string
+	Entries []Entry
+}
+
+/* --- Encoding ------------------------------------------------------------- */
+
+// Write writes trace to writer in the trace file format.
+func Write(writer io.Writer, trace *Trace) error {
+	buffered := bufio.NewWriter(writer)
+
+	fmt.Fprintf(buffered, "monkey-trace %d %s\n", version, strconv.Quote(trace.Path))
+
+	for _, entry := range trace.Entries {
+		name := entry.Name
+
+		if name == "" {
+			name = "-"
+		}
+
+		fmt.Fprintf(buffered, "%c %d:%d %s %s\n", entry.Kind, entry.Line, entry.Column, name, strconv.Quote(entry.Value))
+	}
+
+	return buffered.Flush()
+}
+
+// Read reads a trace written by Write.
+func Read(reader io.Reader) (*Trace, error) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 1<<24)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+
+		return nil, errors.New("empty trace")
+	}
+
+	trace := &Trace{}
+
+	var fileVersion int
+	var path string
+
+	if _, err := fmt.Sscanf(scanner.Text(), "monkey-trace %d %q", &fileVersion, &path); err != nil {
+		return nil, fmt.Errorf("not a trace: %s", err)
+	}
+
+	if fileVersion != version {
+		return nil, fmt.Errorf("unsupported trace version %d", fileVersion)
+	}
+
+	trace.Path = path
+
+	for line := 2; scanner.Scan(); line++ {
+		entry, err := readEntry(scanner.Text())
+
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", line, err)
+		}
+
+		trace.Entries = append(trace.Entries, entry)
+	}
+
+	return trace, scanner.Err()
+}
+
+func readEntry(text string) (Entry, error) {
+	entry := Entry{}
+	fields := strings.SplitN(text, " ", 4)
+
+	if len(fields) != 4 || len(fields[0]) != 1 {
+		return entry, fmt.Errorf("invalid entry %q", text)
+	}
+
+	entry.Kind = Kind(fields[0][0])
+
+	switch entry.Kind {
+	case KindBind, KindCall, KindReturn, KindExit:
+	default:
+		return entry, fmt.Errorf("unknown entry kind %q", fields[0])
+	}
+
+	if _, err := fmt.Sscanf(fields[1], "%d:%d", &entry.Line, &entry.Column); err != nil {
+		return entry, fmt.Errorf("invalid position %q", fields[1])
+	}
+
+	if fields[2] != "-" {
+		entry.Name = fields[2]
+	}
+
+	value, err := strconv.Unquote(fields[3])
+
+	if err != nil {
+		return entry, fmt.Errorf("invalid value %s", fields[3])
+	}
+
+	entry.Value = value
+
+	return entry, nil
+}
@@ -0,0 +1,175 @@
+package trace
+
+import (
+	"monkey/parser"
+	"monkey/tokenizer"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const source = `let add = function(a, b) {
+	let sum = a + b;
+	return sum;
+};
+let x = add(1, 2);
+x++;
+let y = add(x, 3);
+y;
+`
+
+func record(t *testing.T, source string) *Trace {
+	parser := parser.New(tokenizer.New(source))
+	program := parser.Parse()
+
+	if len(parser.Errors) != 0 {
+		t.Fatalf("parser errors %v", parser.Errors)
+	}
+
+	trace, _ := Record("main.monkey", program)
+
+	return trace
+}
+
+func TestRecord(t *testing.T) {
+	trace := record(t, source)
+
+	expected := []string{
+		"add = function(a, b) {let sum = (a + b);return sum;}",
+		"call add(1, 2)", "a = 1", "b = 2", "sum = 3", "add returned 3",
+		"x = 3", "x = 4",
+		"call add(4, 3)", "a = 4", "b = 3", "sum = 7", "add returned 7",
+		"y = 7",
+		"program ended with 7",
+	}
+
+	entries := []string{}
+
+	for _, entry := range trace.Entries {
+		entries = append(entries, entry.String())
+	}
+
+	if !reflect.DeepEqual(entries, expected) {
+		t.Fatalf("expected %q, got %q", expected, entries)
+	}
+
+	if entry := trace.Entries[7]; entry.Line != 6 || entry.Column != 1 {
+		t.Errorf("expected x++ to be recorded at 6:1, got %d:%d", entry.Line, entry.Column)
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	var first, second strings.Builder
+
+	if err := Write(&first, record(t, source)); err != nil {
+		t.Fatal(err)
+	}
+
+	Write(&second, record(t, source))
+
+	if first.String() != second.String() {
+		t.Fatalf("expected recordings to be deterministic, got\n%s\nand\n%s", first.String(), second.String())
+	}
+
+	trace, err := Read(strings.NewReader(first.String()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(trace, record(t, source)) {
+		t.Errorf("expected the trace to survive a round trip, got %+v", trace)
+	}
+
+	invalid := []string{
+		"",
+		"monkey-trace 2 \"main.monkey\"\n",
+		"monkey-trace 1 \"main.monkey\"\nz 1:1 x \"1\"\n",
+		"monkey-trace 1 \"main.monkey\"\nb 1 x \"1\"\n",
+		"monkey-trace 1 \"main.monkey\"\nb 1:1 x 1\n",
+	}
+
+	for _, input := range invalid {
+		if _, err := Read(strings.NewReader(input)); err == nil {
+			t.Errorf("expected %q to be rejected", input)
+		}
+	}
+}
+
+func TestReplay(t *testing.T) {
+	replay := NewReplay(record(t, source))
+
+	if _, ok := replay.Value("x"); ok || replay.Backward() {
+		t.Fatalf("expected nothing before the first entry")
+	}
+
+	replay.Seek(10)
+
+	if stack := replay.Stack(); len(stack) != 1 || stack[0].Value != "4, 3" {
+		t.Errorf("expected to be in the second call, got %+v", stack)
+	}
+
+	values := map[string]string{"a": "4", "b": "3", "x": "4"}
+
+	for name, expected := range values {
+		if entry, ok := replay.Value(name); !ok || entry.Value != expected {
+			t.Errorf("expected %s to be %s, got %+v", name, expected, entry)
+		}
+	}
+
+	if _, ok := replay.Value("sum"); ok {
+		t.Errorf("expected sum to be unbound before its let")
+	}
+
+	if locals := replay.Locals(); len(locals) != 2 || locals[0].Name != "a" || locals[1].Name != "b" {
+		t.Errorf("unexpected locals %+v", locals)
+	}
+
+	if position, ok := replay.LastChange("x"); !ok || position != 7 {
+		t.Errorf("expected x to last change at 7, got %d", position)
+	}
+
+	replay.Seek(7)
+
+	if position, ok := replay.LastChange("x"); !ok || position != 6 {
+		t.Errorf("expected x to change at 6 before 7, got %d", position)
+	}
+
+	if history := replay.History("sum"); !reflect.DeepEqual(history, []int{4, 11}) {
+		t.Errorf("unexpected history of sum %v", history)
+	}
+
+	replay.Seek(len(replay.Trace.Entries) - 1)
+
+	if replay.Forward() || len(replay.Stack()) != 0 {
+		t.Errorf("expected to be at the end of main")
+	}
+}
+
+func TestConsole(t *testing.T) {
+	var out strings.Builder
+
+	commands := []string{"end", "last x", "", "history x", "forward 3", "bt", "print a", "back 100", "jump"}
+	NewConsole(record(t, source), source, &out).Run(strings.NewReader(strings.Join(commands, "\n") + "\n"))
+
+	expected := []string{
+		"15 entries recorded from main.monkey.",
+		"#14  program ended with 7",
+		"#7  x = 4 at main.monkey:6:1\n6\tx++;",
+		"#6  x = 3 at main.monkey:5:5\n5\tlet x = add(1, 2);",
+		"#6  x = 3 at main.monkey:5:5\n#7  x = 4 at main.monkey:6:1",
+		"#9  a = 4 at main.monkey:1:20",
+		"#0  add(4, 3) at main.monkey:7:12\n#1  main",
+		"a = 4",
+		"Before the first entry.",
+		`Undefined command: "jump".`,
+	}
+
+	output := out.String()
+
+	for _, text := range expected {
+		if !strings.Contains(output, text) {
+			t.Errorf("expected the output to contain %q, got:\n%s", text, output)
+		}
+	}
+}